@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"rssgram/internal"
+)
+
+// checkConfig проверяет конфиг и печатает все найденные проблемы в виде path:line: ...
+// Путь можно передать аргументом или флагом --config.
+func (c *cli) checkConfig(ctx context.Context, args []string) error {
+	fs := c.flagSet("check-config")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		c.configPath = fs.Arg(0)
+	}
+
+	path := c.configPath
+
+	cnf, err := c.source().Load()
+	if err != nil {
+		return fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+
+	err = cnf.Validate()
+
+	var verrs internal.ValidationErrors
+	if errors.As(err, &verrs) {
+		for _, e := range verrs {
+			fmt.Fprintf(c.stdout, "%s:%d: %s: %s\n", path, e.Line, e.Path, e.Message)
+		}
+		return fmt.Errorf("config %s has %d problem(s)", path, len(verrs))
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.stdout, "%s: ok\n", path)
+	return nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestRunCheckConfig(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		expectError bool
+		expectOut   []string
+	}{
+		{
+			name: "valid config",
+			content: `telegram:
+  channel_name: "@test_channel"
+  bot_token: "test_token"
+feeds:
+  - url: https://example.com/rss
+`,
+			expectOut: []string{"config.yaml: ok"},
+		},
+		{
+			name: "reports every problem with line",
+			content: `telegram:
+  channel_name: "@test_channel"
+feeds:
+  - url: https://example.com/rss
+  - url: https://example.com/rss
+    description_type: full
+`,
+			expectError: true,
+			expectOut: []string{
+				"config.yaml:1: telegram.bot_token: is required",
+				"config.yaml:5: feeds[1].url: duplicate of feeds[0].url",
+				"config.yaml:6: feeds[1].description_type: unknown description type",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0o644))
+
+			var out bytes.Buffer
+			c := newCLI(noEnv, &out, io.Discard, zap.NewNop())
+			err := c.checkConfig(context.Background(), []string{path})
+			if tt.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			for _, s := range tt.expectOut {
+				assert.Contains(t, out.String(), s)
+			}
+		})
+	}
+}
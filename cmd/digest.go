@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"rssgram/internal"
+	"rssgram/internal/feed"
+	"rssgram/internal/metrics"
+	"rssgram/internal/outputs/telegram"
+	"rssgram/internal/storage"
+	"rssgram/internal/storage/backend"
+
+	"go.uber.org/zap"
+)
+
+// digestBatch - новости одного дайджеста, собранные за проход отправителя.
+type digestBatch struct {
+	key    string
+	title  string
+	output string
+	conf   telegram.DigestConfig
+	items  []*feed.FeedItem
+	// новости, у которых кроме этого дайджеста остались другие выходы
+	partial map[string]bool
+}
+
+// digests раскладывает новости по дайджестам: digest фида важнее digest выхода.
+type digests struct {
+	feeds   map[string]internal.FeedConfig
+	outputs map[string]telegram.DigestConfig
+	batches map[string]*digestBatch
+	// выходы в тихих часах с defer: их дайджесты ждут конца окна
+	hold map[string]bool
+	// ключи в порядке появления, чтобы дайджесты уходили в предсказуемом порядке
+	keys []string
+}
+
+func newDigests(cnf *internal.Config) *digests {
+	d := &digests{
+		feeds:   make(map[string]internal.FeedConfig, len(cnf.Feeds)),
+		outputs: make(map[string]telegram.DigestConfig, len(cnf.Outputs)+1),
+		batches: make(map[string]*digestBatch),
+		hold:    make(map[string]bool),
+	}
+
+	for _, f := range cnf.Feeds {
+		d.feeds[f.URL] = f
+	}
+	d.outputs[feed.DefaultOutput] = cnf.Telegram.Digest
+	for _, o := range cnf.Outputs {
+		d.outputs[o.Name] = o.Digest
+	}
+
+	return d
+}
+
+// batch возвращает дайджест, в который новость уходит через output, или nil, если её надо отправить сразу.
+func (d *digests) batch(item *feed.FeedItem, output string) *digestBatch {
+	if f, ok := d.feeds[item.FeedURL]; ok && f.Digest.Enabled() {
+		return d.get("feed:"+output+":"+item.FeedURL, "Digest: "+d.feedName(item), output, f.Digest)
+	}
+	if c := d.outputs[output]; c.Enabled() {
+		return d.get("output:"+output, "Digest", output, c)
+	}
+	return nil
+}
+
+// overflow возвращает дайджест для новостей фида сверх его квоты.
+func (d *digests) overflow(item *feed.FeedItem, output string, conf telegram.DigestConfig) *digestBatch {
+	return d.get("overflow:"+output+":"+item.FeedURL, "Digest: "+d.feedName(item), output, conf)
+}
+
+// quiet возвращает дайджест новостей, накопленных выходом за тихие часы.
+func (d *digests) quiet(output string) *digestBatch {
+	return d.get("quiet:"+output, "Digest", output, telegram.DigestConfig{EveryItems: 1})
+}
+
+func (d *digests) feedName(item *feed.FeedItem) string {
+	if f, ok := d.feeds[item.FeedURL]; ok && f.Name != "" {
+		return f.Name
+	}
+	return item.FeedTitle
+}
+
+func (d *digests) get(key, title, output string, conf telegram.DigestConfig) *digestBatch {
+	if conf.Title != "" {
+		title = conf.Title
+	}
+
+	b, ok := d.batches[key]
+	if !ok {
+		b = &digestBatch{key: key, title: title, output: output, conf: conf, partial: make(map[string]bool)}
+		d.batches[key] = b
+		d.keys = append(d.keys, key)
+	}
+	return b
+}
+
+func (b *digestBatch) add(item *feed.FeedItem, partial bool) {
+	b.items = append(b.items, item)
+	if partial {
+		b.partial[item.ID] = true
+	}
+}
+
+// sendDigests отправляет дайджесты, которым пора по расписанию, и одной транзакцией
+// отмечает их новости. Расписание нового дайджеста начинается с первой попавшей в него новости.
+func sendDigests(ctx context.Context, d *digests, outputs map[string]*telegram.TelegramChannelOutput, store backend.Storage, status storage.DeliveryStatus, logger *zap.Logger) {
+	now := time.Now()
+
+	for _, key := range d.keys {
+		if ctx.Err() != nil {
+			return
+		}
+		b := d.batches[key]
+		ctxLogger := logger.With(zap.String("digest", b.key))
+		if d.hold[b.output] {
+			ctxLogger.Debug("digest is held until quiet hours end", zap.Int("items", len(b.items)))
+			continue
+		}
+
+		last, err := store.GetDigestSentAt(ctx, b.key)
+		if err != nil {
+			ctxLogger.Error("failed to get digest state", zap.Error(err))
+			continue
+		}
+		if last.IsZero() {
+			if err = store.SetDigestSent(ctx, storage.SentDigest{Key: b.key, SentAt: now}); err != nil {
+				ctxLogger.Error("failed to start digest schedule", zap.Error(err))
+				continue
+			}
+			last = now
+		}
+
+		due, err := b.conf.Due(last, now, len(b.items))
+		if err != nil {
+			ctxLogger.Error("invalid digest schedule", zap.Error(err))
+			continue
+		}
+		if !due {
+			ctxLogger.Debug("digest is not due yet", zap.Int("items", len(b.items)))
+			continue
+		}
+
+		// как и отдельную новость, начатый дайджест не прерываем при остановке
+		digestCtx := context.WithoutCancel(ctx)
+
+		if err = outputs[b.output].PushDigest(digestCtx, b.title, b.items); err != nil {
+			ctxLogger.Error("failed to send digest", zap.Error(err))
+			for _, item := range b.items {
+				metrics.ItemsSentErrorCount.WithLabelValues(item.FeedTitle).Inc()
+				if err = store.IncrementItemFailedCounter(digestCtx, item.ID); err != nil {
+					ctxLogger.Error("failed to increment item failed", zap.Error(err))
+				}
+			}
+			continue
+		}
+
+		sent := storage.SentDigest{Key: b.key, SentAt: now, Status: status, Output: b.output}
+		for _, item := range b.items {
+			if b.partial[item.ID] {
+				sent.PartialItemIDs = append(sent.PartialItemIDs, item.ID)
+			} else {
+				sent.ItemIDs = append(sent.ItemIDs, item.ID)
+			}
+		}
+		if err = store.SetDigestSent(digestCtx, sent); err != nil {
+			ctxLogger.Error("failed to mark digest items as sent", zap.Error(err))
+			continue
+		}
+
+		for _, item := range b.items {
+			if !b.partial[item.ID] {
+				metrics.ItemsSentSuccessCount.WithLabelValues(item.FeedTitle).Inc()
+			}
+		}
+		ctxLogger.Info("digest sent", zap.Int("items", len(b.items)))
+	}
+}
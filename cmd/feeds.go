@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"rssgram/internal"
+	"rssgram/internal/storage"
+)
+
+type feedListEntry struct {
+	Name     string `json:"name"`
+	InConfig bool   `json:"in_config"`
+	storage.FeedStats
+}
+
+// feedList объединяет фиды из конфига и из базы: сначала фиды конфига в его порядке,
+// затем оставшиеся в базе после удаления из конфига.
+func feedList(cnf *internal.Config, stats []storage.FeedStats) []feedListEntry {
+	byURL := make(map[string]storage.FeedStats, len(stats))
+	for _, st := range stats {
+		byURL[st.URL] = st
+	}
+
+	entries := make([]feedListEntry, 0, len(stats))
+	for _, f := range cnf.Feeds {
+		st, ok := byURL[f.URL]
+		if !ok {
+			st = storage.FeedStats{URL: f.URL}
+		}
+		delete(byURL, f.URL)
+
+		entries = append(entries, feedListEntry{Name: f.Name, InConfig: true, FeedStats: st})
+	}
+
+	for _, st := range stats {
+		if _, ok := byURL[st.URL]; ok {
+			entries = append(entries, feedListEntry{FeedStats: st})
+		}
+	}
+
+	return entries
+}
+
+func (c *cli) feeds(ctx context.Context, args []string) error {
+	if len(args) == 0 || args[0] != "list" {
+		return fmt.Errorf("usage: rssgram feeds list [-json]")
+	}
+
+	fs := c.flagSet("feeds list")
+	asJSON := fs.Bool("json", false, "print feeds as JSON")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	store, cnf, err := c.openStorage(ctx)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	stats, err := store.ListFeedStats(ctx)
+	if err != nil {
+		return err
+	}
+
+	entries := feedList(cnf, stats)
+
+	if *asJSON {
+		return writeJSON(c.stdout, entries)
+	}
+
+	w := tabwriter.NewWriter(c.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tURL\tPENDING\tFAILED\tSENT\tDRY RUN\tLAST CHECKED")
+	for _, e := range entries {
+		name := e.Name
+		if !e.InConfig {
+			name = "(not in config)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%d\t%s\n", name, e.URL, e.Pending, e.Failed, e.Sent, e.DryRun, formatListTime(e.LastChecked))
+	}
+	return w.Flush()
+}
+
+func formatListTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format(time.DateTime)
+}
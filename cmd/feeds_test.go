@@ -0,0 +1,31 @@
+package main
+
+import (
+	"testing"
+
+	"rssgram/internal"
+	"rssgram/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFeedList(t *testing.T) {
+	cnf := &internal.Config{
+		Feeds: []internal.FeedConfig{
+			{Name: "Second", URL: "https://b.example.com/rss"},
+			{Name: "New", URL: "https://new.example.com/rss"},
+		},
+	}
+	stats := []storage.FeedStats{
+		{URL: "https://a.example.com/rss", Sent: 3},
+		{URL: "https://b.example.com/rss", Pending: 2},
+	}
+
+	entries := feedList(cnf, stats)
+
+	assert.Equal(t, []feedListEntry{
+		{Name: "Second", InConfig: true, FeedStats: storage.FeedStats{URL: "https://b.example.com/rss", Pending: 2}},
+		{Name: "New", InConfig: true, FeedStats: storage.FeedStats{URL: "https://new.example.com/rss"}},
+		{FeedStats: storage.FeedStats{URL: "https://a.example.com/rss", Sent: 3}},
+	}, entries)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"text/tabwriter"
+
+	"rssgram/internal/storage"
+	"rssgram/internal/utils"
+)
+
+func (c *cli) items(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: rssgram items list|requeue [flags]")
+	}
+
+	action, args := args[0], args[1:]
+
+	fs := c.flagSet("items " + action)
+	feedName := fs.String("feed", "", "feed URL or name")
+	status := fs.String("status", "", "pending, failed, sent or dry_run")
+
+	switch action {
+	case "list":
+		limit := fs.Int("limit", 50, "max items")
+		asJSON := fs.Bool("json", false, "print items as JSON")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+
+		filter := storage.ItemFilter{Feed: *feedName, Status: storage.ItemStatus(*status), Limit: *limit}
+		return c.listItems(ctx, filter, *asJSON)
+
+	case "requeue":
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+
+		filter := storage.ItemFilter{IDs: fs.Args(), Feed: *feedName, Status: storage.ItemStatus(*status)}
+		// без фильтра в канал ушли бы заново все новости из базы
+		if len(filter.IDs) == 0 && filter.Feed == "" && filter.Status == "" {
+			return fmt.Errorf("usage: rssgram items requeue [-feed url] [-status failed|sent|dry_run] [id...]: at least one filter is required")
+		}
+		return c.requeueItems(ctx, filter)
+
+	default:
+		return fmt.Errorf("unknown items action %q, expected list or requeue", action)
+	}
+}
+
+func (c *cli) listItems(ctx context.Context, filter storage.ItemFilter, asJSON bool) error {
+	store, _, err := c.openStorage(ctx)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	items, err := store.ListItems(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	if asJSON {
+		if items == nil {
+			items = []storage.StoredItem{}
+		}
+		return writeJSON(c.stdout, items)
+	}
+
+	w := tabwriter.NewWriter(c.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tPUBLISHED\tSTATUS\tFEED\tTITLE")
+	for _, item := range items {
+		status := string(item.Status)
+		if item.FailedCount > 0 {
+			status += fmt.Sprintf(" (%d)", item.FailedCount)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", item.ID, formatListTime(item.PublishedAt), status, item.FeedTitle, utils.EllipsisString(item.Title, 60))
+	}
+	return w.Flush()
+}
+
+func (c *cli) requeueItems(ctx context.Context, filter storage.ItemFilter) error {
+	store, _, err := c.openStorage(ctx)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	count, err := store.RequeueItems(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.stdout, "requeued %d item(s)\n", count)
+	return nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"rssgram/internal/feed"
+	"rssgram/internal/storage"
+	"rssgram/internal/storage/backend"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestCLI_Items(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	dsn := "file:" + filepath.Join(dir, "data.db")
+
+	store, err := backend.Open(ctx, storage.Config{DSN: dsn})
+	require.NoError(t, err)
+	require.NoError(t, store.Migrate(ctx))
+
+	published := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	for _, id := range []string{"pending", "failed"} {
+		require.NoError(t, store.InsertItem(ctx, &feed.FeedItem{
+			ID:          id,
+			FeedURL:     "https://example.com/rss",
+			FeedTitle:   "Test Feed",
+			Title:       "Title " + id,
+			PublishedAt: &published,
+		}))
+	}
+	require.NoError(t, store.IncrementItemFailedCounter(ctx, "failed"))
+	require.NoError(t, store.Close())
+
+	run := func(args ...string) (string, error) {
+		var out bytes.Buffer
+		c := newCLI(noEnv, &out, io.Discard, zap.NewNop())
+		err := c.Run(ctx, append([]string{"--config", filepath.Join(dir, "config.yaml"), "--db", dsn}, args...))
+		return out.String(), err
+	}
+
+	out, err := run("items", "list", "-status", "failed", "-json")
+	require.NoError(t, err)
+
+	var items []storage.StoredItem
+	require.NoError(t, json.Unmarshal([]byte(out), &items))
+	require.Len(t, items, 1)
+	assert.Equal(t, "failed", items[0].ID)
+	assert.Equal(t, 1, items[0].FailedCount)
+
+	out, err = run("items", "list")
+	require.NoError(t, err)
+	assert.Contains(t, out, "failed (1)")
+	assert.Contains(t, out, "Title pending")
+
+	// без фильтра requeue отклоняется
+	_, err = run("items", "requeue")
+	assert.Error(t, err)
+
+	out, err = run("items", "requeue", "-status", "failed")
+	require.NoError(t, err)
+	assert.Equal(t, "requeued 1 item(s)\n", out)
+
+	out, err = run("items", "list", "-status", "failed", "-json")
+	require.NoError(t, err)
+	assert.JSONEq(t, "[]", out)
+
+	out, err = run("feeds", "list")
+	require.NoError(t, err)
+	assert.Contains(t, out, "(not in config)")
+	assert.Contains(t, out, "https://example.com/rss")
+}
@@ -2,231 +2,215 @@ package main
 
 import (
 	"context"
-	"database/sql"
-	"embed"
 	"errors"
+	"flag"
 	"fmt"
-	"log"
-	"log/syslog"
-	"net/http"
+	"io"
 	"os"
-	"os/signal"
-	"syscall"
-	"time"
 
 	"rssgram/internal"
-	"rssgram/internal/feed"
-	"rssgram/internal/metrics"
-	"rssgram/internal/outputs/telegram"
-	"rssgram/internal/storage/sqlite"
-
-	"github.com/golang-migrate/migrate/v4"
-	"github.com/golang-migrate/migrate/v4/database/sqlite3"
-	"github.com/golang-migrate/migrate/v4/source/iofs"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
-	"go.uber.org/zap"
+	"rssgram/internal/storage/backend"
 
-	_ "modernc.org/sqlite"
+	"go.uber.org/zap"
 )
 
-//go:embed migrations/*.sql
-var migrations embed.FS
+func main() {
 
-func runMigrate() error {
+	// уровень и формат из конфига применяются командой run, до этого - info и json
+	level := zap.NewAtomicLevel()
+	logger, _ := internal.NewLogger(internal.LogConfig{}, level)
+	defer logger.Sync()
 
-	w, _ := syslog.New(syslog.LOG_SYSLOG|syslog.LOG_INFO, "rssgram")
-	log.SetOutput(w)
+	c := newCLI(os.LookupEnv, os.Stdout, os.Stderr, logger)
+	c.logLevel = &level
 
-	db, err := sql.Open("sqlite", "file:data.db?cache=shared")
-	if err != nil {
-		return fmt.Errorf("failed to open database: %w", err)
+	err := c.Run(context.Background(), os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
 	}
-	defer db.Close()
-
-	instance, err := sqlite3.WithInstance(db, &sqlite3.Config{})
 	if err != nil {
-		return fmt.Errorf("failed create sqlite3 instance: %w", err)
+		fmt.Fprintln(os.Stderr, err)
+		logger.Sync()
+		os.Exit(1)
 	}
+}
 
-	d, err := iofs.New(migrations, "migrations")
-	if err != nil {
-		return fmt.Errorf("failed to init iofs: %w", err)
-	}
+// cli - общие флаги и потоки вывода команд.
+type cli struct {
+	configPath string
+	dsn        string
+	dryRun     bool
+	dryRunFile string
+
+	lookupEnv func(string) (string, bool)
+	stdout    io.Writer
+	stderr    io.Writer
+	logger    *zap.Logger
+	// logLevel задан, если логгер собран по конфигу; в тестах логгер передаётся готовым
+	logLevel *zap.AtomicLevel
+}
 
-	m, err := migrate.NewWithInstance("iofs", d, "sqlite", instance)
-	if err != nil {
-		return fmt.Errorf("failed to init migrate: %w", err)
+type command struct {
+	name  string
+	usage string
+	run   func(c *cli, ctx context.Context, args []string) error
+}
+
+func newCLI(lookupEnv func(string) (string, bool), stdout, stderr io.Writer, logger *zap.Logger) *cli {
+	c := &cli{
+		configPath: internal.DefaultConfigPath,
+		lookupEnv:  lookupEnv,
+		stdout:     stdout,
+		stderr:     stderr,
+		logger:     logger,
 	}
 
-	err = m.Up()
-	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
-		return fmt.Errorf("failed to migrate: %w", err)
+	if path, ok := lookupEnv(internal.EnvPrefix + "CONFIG"); ok {
+		c.configPath = path
 	}
-	return nil
+
+	return c
 }
 
-func main() {
+func (c *cli) commands() []command {
+	return []command{
+		{"run", "run the service (default)", (*cli).runService},
+		{"migrate", "migrate up|down|version - manage the database schema", (*cli).migrate},
+		{"check-config", "validate the config and print every problem", (*cli).checkConfig},
+		{"feeds", "feeds list - configured and stored feeds with item counters", (*cli).feeds},
+		{"items", "items list|requeue - inspect stored items and queue them again", (*cli).items},
+		{"search", "search [flags] <query> - full-text search over stored items", (*cli).search},
+		{"import-opml", "import-opml [flags] <file.opml> - add feeds from an OPML export to the config", (*cli).importOPML},
+		{"export-opml", "export-opml [-o file] - write configured feeds as OPML", (*cli).exportOPML},
+		{"preview", "preview [flags] <url> - render messages of a feed without storing or sending them", (*cli).preview},
+		{"send-test", "send a test message to the configured channel", (*cli).sendTest},
+		{"version", "print version", (*cli).version},
+	}
+}
 
-	cfg := zap.NewProductionConfig()
-	cfg.Level = zap.NewAtomicLevelAt(zap.DebugLevel)
-	logger, _ := cfg.Build()
-	defer logger.Sync()
+// Run разбирает общие флаги и запускает команду; без команды запускается сервис.
+func (c *cli) Run(ctx context.Context, args []string) error {
+	fs := c.flagSet("rssgram")
+	fs.Usage = func() { c.usage(fs) }
 
-	err := runMigrate()
-	if err != nil {
-		logger.Fatal("migrate failed", zap.Error(err))
+	if err := fs.Parse(args); err != nil {
+		return err
 	}
+	args = fs.Args()
 
-	cnf, err := internal.ParseConfig()
-	if err != nil {
-		logger.Fatal(err.Error())
+	name := "run"
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
 	}
 
-	storage, err := sqlite.NewStorage()
-	if err != nil {
-		log.Fatal(err)
+	if name == "help" {
+		c.usage(fs)
+		return nil
 	}
 
-	ctx := context.Background()
-
-	go feedGetter(ctx, cnf, storage, logger.With(zap.String("module", "feed_manager")))
-	go itemSender(ctx, cnf, storage, logger.With(zap.String("module", "sender")))
-	go metricHandler(ctx, cnf, logger.With(zap.String("module", "metric_handler")))
-
-	sig := make(chan os.Signal)
-	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
-	s := <-sig
-	logger.Info(fmt.Sprintf("received signal %s", s.String()))
-
-}
-
-func feedGetter(ctx context.Context, cnf *internal.Config, storage *sqlite.Storage, logger *zap.Logger) {
-	ticker := time.NewTicker(1 * time.Millisecond)
-	for {
-		select {
-		case <-ctx.Done():
-			return
-
-		case <-ticker.C:
-			ticker.Stop()
-			_feedGetter(ctx, cnf, storage, logger)
-			ticker.Reset(10 * time.Second)
+	for _, cmd := range c.commands() {
+		if cmd.name == name {
+			return cmd.run(c, ctx, args)
 		}
-
 	}
-}
-
-func _feedGetter(ctx context.Context, cnf *internal.Config, storage *sqlite.Storage, logger *zap.Logger) {
-	m := feed.NewManager(storage)
-
-	metrics.FeedsCount.Set(float64(len(cnf.Feeds)))
 
-	for _, f := range cnf.Feeds {
-		// временный перегон из старого ConfigFeed.
-		fc := feed.FeedConfig{
-			Name:            f.Name,
-			URL:             f.URL,
-			Key:             f.Key,
-			DescriptionType: f.DescriptionType,
-		}
+	c.usage(fs)
+	return fmt.Errorf("unknown command %q", name)
+}
 
-		err := m.ProcessFeed(context.Background(), fc, logger)
-		if err != nil {
-			logger.Error("failed to process feed", zap.String("url", f.URL), zap.Error(err))
-			continue
-		}
-	}
+func (c *cli) usage(fs *flag.FlagSet) {
+	fmt.Fprintf(c.stderr, "Usage: rssgram [--config path] [--db dsn] <command> [args]\n\nCommands:\n")
+	for _, cmd := range c.commands() {
+		fmt.Fprintf(c.stderr, "  %-13s %s\n", cmd.name, cmd.usage)
+	}
+	fmt.Fprintf(c.stderr, "\nFlags:\n")
+	fs.PrintDefaults()
+	fmt.Fprintf(c.stderr, "\nEnvironment overrides:\n  RSSGRAM_CONFIG, RSSGRAM_DB, RSSGRAM_DB_DRIVER, RSSGRAM_TELEGRAM_CHANNEL, RSSGRAM_TELEGRAM_BOT_TOKEN,\n"+
+		"  RSSGRAM_TELEGRAM_BOT_TOKEN_FILE, RSSGRAM_DB_FILE,\n"+
+		"  RSSGRAM_METRICS_ENABLED, RSSGRAM_METRICS_PORT, RSSGRAM_DRY_RUN, RSSGRAM_DRY_RUN_FILE, RSSGRAM_SHUTDOWN_TIMEOUT,\n"+
+		"  RSSGRAM_LOG_LEVEL, RSSGRAM_LOG_FORMAT\n")
 }
 
-func itemSender(ctx context.Context, cnf *internal.Config, storage *sqlite.Storage, logger *zap.Logger) {
-	ticker := time.NewTicker(1 * time.Millisecond)
-	for {
-		select {
-		case <-ctx.Done():
-			return
-
-		case <-ticker.C:
-			ticker.Stop()
-			_itemSender(ctx, cnf, storage, logger)
-			ticker.Reset(10 * time.Second)
-		}
+// flagSet создаёт флаги команды вместе с общими --config и --db,
+// поэтому их можно указать и до, и после имени команды.
+func (c *cli) flagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.StringVar(&c.configPath, "config", c.configPath, "path to the config file (env "+internal.EnvPrefix+"CONFIG)")
+	fs.StringVar(&c.dsn, "db", c.dsn, "database DSN, overrides database.dsn (env "+internal.EnvPrefix+"DB)")
+	return fs
+}
 
+func (c *cli) source() internal.ConfigSource {
+	return internal.ConfigSource{
+		Path:       c.configPath,
+		DSN:        c.dsn,
+		DryRun:     c.dryRun,
+		DryRunFile: c.dryRunFile,
+		LookupEnv:  c.lookupEnv,
 	}
 }
 
-func _itemSender(ctx context.Context, cnf *internal.Config, storage *sqlite.Storage, logger *zap.Logger) {
-	tgOutput := telegram.NewTelegramChannelOutput(
-		cnf.Telegram,
-		logger,
-		cnf.EnableTags,
-	)
-
-	itemsToSend, err := storage.GetItemsReadyToSend(ctx, 0)
+// loadConfig читает конфиг с переопределениями и проверяет его.
+func (c *cli) loadConfig() (*internal.Config, error) {
+	cnf, err := c.source().Load()
 	if err != nil {
-		logger.Error("failed to get items to send", zap.Error(err))
+		return nil, fmt.Errorf("failed to parse config %s: %w", c.configPath, err)
 	}
 
-	metrics.ItemsReadyToSendCount.Set(float64(len(itemsToSend)))
-
-	failedItems, err := storage.GetCountItemsSendFailed(ctx)
-	if err != nil {
-		logger.Error("failed to get failed items", zap.Error(err))
+	if err = cnf.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config %s, run `rssgram check-config` for details:\n%w", c.configPath, err)
 	}
 
-	metrics.ItemsSentFailedCount.Set(float64(failedItems))
+	return cnf, nil
+}
 
-	logger.Debug(fmt.Sprintf("got %d items to send", len(itemsToSend)))
+// setupLogger пересобирает логгер по секции log конфига.
+func (c *cli) setupLogger(conf internal.LogConfig) error {
+	if c.logLevel == nil {
+		return nil
+	}
 
-	for i := range itemsToSend {
-		time.Sleep(1 * time.Second)
-		logger.Debug(fmt.Sprintf("sending %s ...", itemsToSend[i].ID))
+	logger, err := internal.NewLogger(conf, *c.logLevel)
+	if err != nil {
+		return err
+	}
 
-		pushCtx := context.WithValue(ctx, "item_id", itemsToSend[i].ID)
-		isSuccess, err := tgOutput.Push(pushCtx, &itemsToSend[i])
-		if err != nil {
-			logger.Error("failed to send item", zap.Error(err))
-			metrics.ItemsSentErrorCount.WithLabelValues(itemsToSend[i].FeedTitle).Inc()
-			err = storage.IncrementItemFailedCounter(ctx, itemsToSend[i].ID)
-			if err != nil {
-				logger.Error("failed to increment item failed", zap.Error(err))
-			}
-			continue
-		}
-		if !isSuccess {
-			logger.Error("failed to send item")
-			metrics.ItemsSentErrorCount.WithLabelValues(itemsToSend[i].FeedTitle).Inc()
-			err = storage.IncrementItemFailedCounter(ctx, itemsToSend[i].ID)
-			if err != nil {
-				logger.Error("failed to increment item failed", zap.Error(err))
-			}
-			continue
-		}
+	c.logger.Sync()
+	c.logger = logger
+	return nil
+}
 
-		err = storage.SetItemIsSent(ctx, itemsToSend[i].ID)
-		if err != nil {
-			logger.Error("failed to set is_sent for item", zap.Error(err))
-			continue
-		}
-		logger.Debug("sent")
+// openDatabase открывает базу без миграций. Конфиг для служебных команд необязателен:
+// базу можно задать только через --db или RSSGRAM_DB.
+func (c *cli) openDatabase(ctx context.Context) (backend.Storage, *internal.Config, error) {
+	source := c.source()
+	source.Optional = true
 
-		metrics.ItemsSentSuccessCount.WithLabelValues(itemsToSend[i].FeedTitle).Inc()
+	cnf, err := source.Load()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse config %s: %w", c.configPath, err)
+	}
 
+	store, err := backend.Open(ctx, cnf.Database)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
+	return store, cnf, nil
 }
 
-func metricHandler(ctx context.Context, cnf *internal.Config, logger *zap.Logger) {
-	if !cnf.Metrics.Enabled {
-		logger.Info("metrics disabled")
-		return
+// openStorage открывает базу и применяет миграции.
+func (c *cli) openStorage(ctx context.Context) (backend.Storage, *internal.Config, error) {
+	store, cnf, err := c.openDatabase(ctx)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	if cnf.Metrics.Port == 0 {
-		logger.Warn("metrics port must be greater than 0")
-		return
+	if err = store.Migrate(ctx); err != nil {
+		store.Close()
+		return nil, nil, fmt.Errorf("migrate failed: %w", err)
 	}
 
-	http.Handle("/metrics", promhttp.Handler())
-	logger.Info(fmt.Sprintf("start to serve /metrics on %d port", cnf.Metrics.Port))
-	http.ListenAndServe(fmt.Sprintf(":%d", cnf.Metrics.Port), nil)
+	return store, cnf, nil
 }
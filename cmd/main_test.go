@@ -2,14 +2,25 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"rssgram/internal"
 	"rssgram/internal/outputs/telegram"
+	"rssgram/internal/storage"
+	"rssgram/internal/storage/backend"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
 )
 
 func TestMain_ParseConfig(t *testing.T) {
@@ -120,13 +131,28 @@ func TestMain_ConfigValidation(t *testing.T) {
 		},
 	}
 
-	// Check that the config contains an empty token
+	// Check that a complete config passes validation
 	assert.NotNil(t, config)
 	assert.Len(t, config.Feeds, 1)
 	assert.Equal(t, "Test Feed", config.Feeds[0].Name)
 	assert.Equal(t, "https://example.com/rss", config.Feeds[0].URL)
 	assert.Equal(t, "@test_channel", config.Telegram.ChannelName)
 	assert.Equal(t, "test_token", config.Telegram.BotToken)
+	assert.NoError(t, config.Validate())
+}
+
+// assertValidationErrors checks that validation failed for exactly the given paths
+func assertValidationErrors(t *testing.T, err error, paths ...string) {
+	t.Helper()
+
+	var verrs internal.ValidationErrors
+	require.ErrorAs(t, err, &verrs)
+
+	var got []string
+	for _, e := range verrs {
+		got = append(got, e.Path)
+	}
+	assert.Equal(t, paths, got)
 }
 
 func TestMain_ConfigValidation_EmptyFeeds(t *testing.T) {
@@ -141,9 +167,10 @@ func TestMain_ConfigValidation_EmptyFeeds(t *testing.T) {
 		},
 	}
 
-	// Check that the config is empty
+	// Check that a config without feeds is still valid
 	assert.NotNil(t, config)
 	assert.Len(t, config.Feeds, 0)
+	assert.NoError(t, config.Validate())
 }
 
 func TestMain_ConfigValidation_EmptyFeedName(t *testing.T) {
@@ -163,10 +190,11 @@ func TestMain_ConfigValidation_EmptyFeedName(t *testing.T) {
 		},
 	}
 
-	// Check that the config contains an empty name
+	// Check that the feed name is optional
 	assert.NotNil(t, config)
 	assert.Len(t, config.Feeds, 1)
 	assert.Empty(t, config.Feeds[0].Name)
+	assert.NoError(t, config.Validate())
 }
 
 func TestMain_ConfigValidation_EmptyChannelName(t *testing.T) {
@@ -186,9 +214,10 @@ func TestMain_ConfigValidation_EmptyChannelName(t *testing.T) {
 		},
 	}
 
-	// Check that the config contains an empty channel name
+	// Check that an empty channel name is rejected
 	assert.NotNil(t, config)
 	assert.Empty(t, config.Telegram.ChannelName)
+	assertValidationErrors(t, config.Validate(), "telegram.channel_name")
 }
 
 func TestMain_ConfigValidation_EmptyBotToken(t *testing.T) {
@@ -208,9 +237,10 @@ func TestMain_ConfigValidation_EmptyBotToken(t *testing.T) {
 		},
 	}
 
-	// Check that the config contains an empty token
+	// Check that an empty token is rejected
 	assert.NotNil(t, config)
 	assert.Empty(t, config.Telegram.BotToken)
+	assertValidationErrors(t, config.Validate(), "telegram.bot_token")
 }
 
 func TestMain_ConfigValidation_InvalidURL(t *testing.T) {
@@ -230,20 +260,124 @@ func TestMain_ConfigValidation_InvalidURL(t *testing.T) {
 		},
 	}
 
-	// Check that the config contains an invalid URL
+	// Check that an invalid URL is rejected
 	assert.NotNil(t, config)
 	assert.Len(t, config.Feeds, 1)
 	assert.Equal(t, "invalid-url", config.Feeds[0].URL)
+	assertValidationErrors(t, config.Validate(), "feeds[0].url")
 }
 
 func TestMain_RunMigrate(t *testing.T) {
+	tmpFile, err := os.CreateTemp("", "test_*.db")
+	require.NoError(t, err)
+	defer os.Remove(tmpFile.Name())
+	tmpFile.Close()
+
+	store, err := backend.Open(context.Background(), storage.Config{DSN: "file:" + tmpFile.Name()})
+	require.NoError(t, err)
+	defer store.Close()
+
 	// Test running migrations
-	err := runMigrate()
+	err = store.Migrate(context.Background())
+	assert.NoError(t, err)
+
+	// Repeated run must be a no-op
+	err = store.Migrate(context.Background())
+	assert.NoError(t, err)
+}
+
+func TestMain_MetricHandler_Shutdown(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+
+	cnf := &internal.Config{
+		Metrics:         internal.MetricsConfig{Enabled: true, Port: port},
+		ShutdownTimeout: time.Second,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		metricHandler(ctx, cnf, nil, zap.NewNop())
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool {
+		resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/metrics", port))
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+		return resp.StatusCode == http.StatusOK
+	}, 2*time.Second, 10*time.Millisecond)
+
+	cancel()
 
-	// Expect either success or an error, but not a panic
-	// Migrations may already be applied, so "no change" error is acceptable
-	if err != nil {
-		// Check that this is not a critical error
-		assert.Contains(t, err.Error(), "no change")
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("metric handler did not stop after context cancellation")
 	}
 }
+
+func noEnv(string) (string, bool) { return "", false }
+
+func TestCLI_UnknownCommand(t *testing.T) {
+	var stderr bytes.Buffer
+	c := newCLI(noEnv, io.Discard, &stderr, zap.NewNop())
+
+	err := c.Run(context.Background(), []string{"unknown"})
+	assert.EqualError(t, err, `unknown command "unknown"`)
+	assert.Contains(t, stderr.String(), "check-config")
+}
+
+func TestCLI_ConfigOverrides(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "custom.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+telegram:
+  channel_name: "@test_channel"
+database:
+  dsn: "file:from-file.db"
+`), 0o644))
+
+	env := map[string]string{
+		"RSSGRAM_CONFIG":             path,
+		"RSSGRAM_TELEGRAM_BOT_TOKEN": "env_token",
+		"RSSGRAM_DB":                 "file:from-env.db",
+	}
+	lookupEnv := func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}
+
+	// файл из RSSGRAM_CONFIG, токен из окружения
+	var out bytes.Buffer
+	c := newCLI(lookupEnv, &out, io.Discard, zap.NewNop())
+	require.NoError(t, c.Run(context.Background(), []string{"check-config"}))
+	assert.Equal(t, path+": ok\n", out.String())
+
+	cnf, err := c.loadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "env_token", cnf.Telegram.BotToken)
+	assert.Equal(t, "file:from-env.db", cnf.Database.DSN)
+
+	// --db важнее RSSGRAM_DB, флаг можно указать после команды
+	c = newCLI(lookupEnv, io.Discard, io.Discard, zap.NewNop())
+	require.NoError(t, c.Run(context.Background(), []string{"check-config", "--db", "file:from-flag.db"}))
+	cnf, err = c.loadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "file:from-flag.db", cnf.Database.DSN)
+
+	// без файла конфига служебные команды работают только с --db
+	c = newCLI(noEnv, &out, io.Discard, zap.NewNop())
+	out.Reset()
+	err = c.Run(context.Background(), []string{"--config", filepath.Join(dir, "missing.yaml"), "--db", "file:" + filepath.Join(dir, "data.db"), "migrate", "version"})
+	require.NoError(t, err)
+	assert.Equal(t, "version 0\n", out.String())
+
+	err = c.Run(context.Background(), []string{"--config", filepath.Join(dir, "missing.yaml"), "check-config"})
+	assert.Error(t, err)
+}
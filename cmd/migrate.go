@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// migrate управляет схемой базы: up, down, version.
+func (c *cli) migrate(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: rssgram migrate up|down|version")
+	}
+
+	action, args := args[0], args[1:]
+
+	fs := c.flagSet("migrate " + action)
+	steps := 1
+	all := false
+	if action == "down" {
+		fs.IntVar(&steps, "steps", steps, "number of migrations to roll back")
+		fs.BoolVar(&all, "all", all, "roll back all migrations")
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	store, _, err := c.openDatabase(ctx)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	switch action {
+	case "up":
+		if err = store.Migrate(ctx); err != nil {
+			return err
+		}
+	case "down":
+		if all {
+			steps = 0
+		} else if steps <= 0 {
+			return fmt.Errorf("steps must be greater than 0, use -all to roll back everything")
+		}
+		if err = store.MigrateDown(ctx, steps); err != nil {
+			return err
+		}
+	case "version":
+	default:
+		return fmt.Errorf("unknown migrate action %q, expected up, down or version", action)
+	}
+
+	version, err := store.MigrationVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	if version.Dirty {
+		fmt.Fprintf(c.stdout, "version %d (dirty)\n", version.Version)
+	} else {
+		fmt.Fprintf(c.stdout, "version %d\n", version.Version)
+	}
+
+	return nil
+}
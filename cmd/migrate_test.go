@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestCLI_Migrate(t *testing.T) {
+	dir := t.TempDir()
+	dsn := "file:" + filepath.Join(dir, "data.db")
+
+	run := func(args ...string) (string, error) {
+		var out bytes.Buffer
+		c := newCLI(noEnv, &out, io.Discard, zap.NewNop())
+		err := c.Run(context.Background(), append([]string{"--config", filepath.Join(dir, "config.yaml"), "--db", dsn}, args...))
+		return out.String(), err
+	}
+
+	out, err := run("migrate", "up")
+	require.NoError(t, err)
+
+	var latest uint
+	_, err = fmt.Sscanf(out, "version %d\n", &latest)
+	require.NoError(t, err)
+	require.NotZero(t, latest)
+
+	out, err = run("migrate", "down")
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("version %d\n", latest-1), out)
+
+	out, err = run("migrate", "version")
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("version %d\n", latest-1), out)
+
+	out, err = run("migrate", "down", "-all")
+	require.NoError(t, err)
+	assert.Equal(t, "version 0\n", out)
+
+	out, err = run("migrate", "up")
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("version %d\n", latest), out)
+
+	_, err = run("migrate", "sideways")
+	assert.Error(t, err)
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"rssgram/internal"
+	"rssgram/internal/opml"
+)
+
+// importOPML дописывает подписки из OPML в feeds конфига. Уже настроенные адреса
+// пропускаются, новые проходят ту же проверку, что и весь конфиг. Запущенный сервис
+// подхватит изменённый файл и обработает фиды как новые: без отправки старых записей.
+func (c *cli) importOPML(ctx context.Context, args []string) error {
+	fs := c.flagSet("import-opml")
+	interval := fs.String("interval", "", "interval for the imported feeds")
+	descriptionType := fs.String("description-type", "", "description type for the imported feeds: item, link, fulltext or none")
+	extraTags := fs.String("tags", "", "comma separated tags added to every imported feed")
+	dryRun := fs.Bool("dry-run", false, "print the feeds that would be added without changing the config")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: rssgram import-opml [flags] <file.opml>")
+	}
+
+	doc, err := readOPML(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	source := c.source()
+	source.Optional = true
+	cnf, err := source.Load()
+	if err != nil {
+		return fmt.Errorf("failed to parse config %s: %w", c.configPath, err)
+	}
+
+	known := make(map[string]bool, len(cnf.Feeds))
+	for _, f := range cnf.Feeds {
+		known[f.URL] = true
+	}
+
+	var candidates []internal.FeedConfig
+	existing := 0
+	// одна подписка может лежать в OPML в нескольких папках: фид добавляется один раз
+	// с тегами всех папок
+	seen := make(map[string]int)
+	for _, f := range doc.Feeds() {
+		if known[f.URL] {
+			if _, ok := seen[f.URL]; !ok {
+				existing++
+			}
+			seen[f.URL] = -1
+			continue
+		}
+		if i, ok := seen[f.URL]; ok {
+			candidates[i].Tags = appendNewTags(candidates[i].Tags, f.Tags...)
+			continue
+		}
+		seen[f.URL] = len(candidates)
+
+		candidates = append(candidates, internal.FeedConfig{
+			Name:            opmlFeedName(f),
+			URL:             f.URL,
+			Interval:        *interval,
+			DescriptionType: *descriptionType,
+			Tags:            appendNewTags(nil, f.Tags...),
+		})
+	}
+
+	for i := range candidates {
+		candidates[i].Tags = appendNewTags(candidates[i].Tags, splitTags(*extraTags)...)
+	}
+
+	added, err := validateImportedFeeds(cnf, candidates, c.stdout)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range added {
+		fmt.Fprintf(c.stdout, "add %s %s\n", f.Name, f.URL)
+	}
+	fmt.Fprintf(c.stdout, "%d feed(s) to add, %d skipped, %d already in the config\n", len(added), len(candidates)-len(added), existing)
+
+	if *dryRun || len(added) == 0 {
+		return nil
+	}
+
+	if err = c.appendFeeds(added); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.stdout, "%s updated\n", c.configPath)
+	return nil
+}
+
+// splitTags разбирает значение флага -tags: теги через запятую, пробелы вокруг и пустые отбрасываются.
+func splitTags(s string) []string {
+	var tags []string
+	for _, tag := range strings.Split(s, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// appendNewTags добавляет к tags те из added, которых там ещё нет.
+func appendNewTags(tags []string, added ...string) []string {
+	for _, tag := range added {
+		if !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+func readOPML(path string) (*opml.Document, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return opml.Parse(f)
+}
+
+func opmlFeedName(f opml.Feed) string {
+	if f.Title != "" {
+		return f.Title
+	}
+	if u, err := url.Parse(f.URL); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return f.URL
+}
+
+// validateImportedFeeds проверяет конфиг вместе с новыми фидами. Фиды с ошибками
+// пропускаются с сообщением, ошибка в остальном конфиге прерывает импорт.
+func validateImportedFeeds(cnf *internal.Config, candidates []internal.FeedConfig, out io.Writer) ([]internal.FeedConfig, error) {
+	configured := len(cnf.Feeds)
+
+	merged := *cnf
+	merged.Feeds = append(cnf.Feeds[:configured:configured], candidates...)
+
+	err := merged.Validate()
+
+	var verrs internal.ValidationErrors
+	if err != nil && !errors.As(err, &verrs) {
+		return nil, err
+	}
+
+	skip := make(map[int]bool)
+	var other internal.ValidationErrors
+	for _, e := range verrs {
+		var i int
+		if _, scanErr := fmt.Sscanf(e.Path, "feeds[%d]", &i); scanErr == nil && i >= configured {
+			f := merged.Feeds[i]
+			fmt.Fprintf(out, "skip %s %s: %s\n", f.Name, f.URL, strings.TrimPrefix(e.Path, fmt.Sprintf("feeds[%d].", i))+": "+e.Message)
+			skip[i-configured] = true
+			continue
+		}
+		other = append(other, e)
+	}
+	if len(other) > 0 {
+		return nil, fmt.Errorf("invalid config, run `rssgram check-config` for details:\n%w", other)
+	}
+
+	var added []internal.FeedConfig
+	for i, f := range candidates {
+		if !skip[i] {
+			added = append(added, f)
+		}
+	}
+	return added, nil
+}
+
+// appendFeeds дописывает фиды в файл конфига. Новый файл сначала пишется рядом
+// и проверяется целиком, затем заменяет старый одним rename. Симлинк на конфиг
+// (ConfigMap, dotfiles) остаётся симлинком: заменяется файл, на который он ведёт.
+func (c *cli) appendFeeds(feeds []internal.FeedConfig) error {
+	path := c.configPath
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+
+	mode := fs.FileMode(0o644)
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	info, statErr := os.Stat(path)
+	if statErr == nil {
+		mode = info.Mode().Perm()
+	}
+
+	data, err = internal.AppendFeeds(data, feeds)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp config: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write temp config: %w", err)
+	}
+
+	source := c.source()
+	source.Path = tmp.Name()
+	cnf, err := source.Load()
+	if err != nil {
+		return fmt.Errorf("failed to parse updated config: %w", err)
+	}
+	if err = cnf.Validate(); err != nil {
+		return fmt.Errorf("updated config is invalid:\n%w", err)
+	}
+
+	if err = os.Chmod(tmp.Name(), mode); err != nil {
+		return fmt.Errorf("failed to set config mode: %w", err)
+	}
+	// владелец сохраняется, если на это хватает прав
+	if statErr == nil {
+		if st, ok := info.Sys().(*syscall.Stat_t); ok {
+			_ = os.Chown(tmp.Name(), int(st.Uid), int(st.Gid))
+		}
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace config: %w", err)
+	}
+
+	return nil
+}
+
+// exportOPML печатает фиды конфига в OPML; теги становятся папками и category.
+func (c *cli) exportOPML(ctx context.Context, args []string) error {
+	fs := c.flagSet("export-opml")
+	output := fs.String("o", "", "write to this file instead of stdout")
+	title := fs.String("title", "rssgram feeds", "document title")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cnf, err := c.source().Load()
+	if err != nil {
+		return fmt.Errorf("failed to parse config %s: %w", c.configPath, err)
+	}
+
+	feeds := make([]opml.Feed, 0, len(cnf.Feeds))
+	for _, f := range cnf.Feeds {
+		feeds = append(feeds, opml.Feed{Title: f.Name, URL: f.URL, Tags: f.Tags})
+	}
+	doc := opml.New(*title, feeds, time.Now())
+
+	if *output == "" {
+		return doc.Write(c.stdout)
+	}
+
+	f, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	if err = doc.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"rssgram/internal"
+	"rssgram/internal/opml"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const opmlTestConfig = `telegram:
+  channel_name: "@test_channel"
+  bot_token: "test_token"
+feeds:
+  # уже настроенный фид
+  - name: Go Blog
+    url: https://go.dev/blog/feed.atom
+`
+
+const opmlTestFile = `<?xml version="1.0"?>
+<opml version="2.0">
+  <body>
+    <outline text="Go Blog" xmlUrl="https://go.dev/blog/feed.atom"/>
+    <outline text="Tech">
+      <outline text="LWN" xmlUrl="https://lwn.net/headlines/rss"/>
+      <outline text="Broken" xmlUrl="ftp://example.com/rss"/>
+      <outline text="LWN again" xmlUrl="https://lwn.net/headlines/rss"/>
+    </outline>
+    <outline xmlUrl="https://example.com/rss"/>
+  </body>
+</opml>`
+
+func TestCLI_ImportOPML(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	opmlPath := filepath.Join(dir, "feeds.opml")
+	require.NoError(t, os.WriteFile(configPath, []byte(opmlTestConfig), 0o600))
+	require.NoError(t, os.WriteFile(opmlPath, []byte(opmlTestFile), 0o644))
+
+	run := func(args ...string) string {
+		var out bytes.Buffer
+		c := newCLI(noEnv, &out, io.Discard, zap.NewNop())
+		require.NoError(t, c.Run(context.Background(), append([]string{"--config", configPath, "import-opml"}, args...)))
+		return out.String()
+	}
+
+	out := run("-dry-run", opmlPath)
+	assert.Contains(t, out, "skip Broken ftp://example.com/rss: url: invalid url")
+	assert.Contains(t, out, "add LWN https://lwn.net/headlines/rss")
+	assert.Contains(t, out, "add example.com https://example.com/rss")
+	assert.Contains(t, out, "2 feed(s) to add, 1 skipped, 1 already in the config")
+
+	data, err := os.ReadFile(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, opmlTestConfig, string(data))
+
+	run("-interval", "1h", "-tags", "imported", opmlPath)
+
+	cnf, err := internal.ParseConfigFile(configPath)
+	require.NoError(t, err)
+	require.NoError(t, cnf.Validate())
+	assert.Equal(t, []internal.FeedConfig{
+		{Name: "Go Blog", URL: "https://go.dev/blog/feed.atom"},
+		{Name: "LWN", URL: "https://lwn.net/headlines/rss", Interval: "1h", Tags: []string{"Tech", "imported"}},
+		{Name: "example.com", URL: "https://example.com/rss", Interval: "1h", Tags: []string{"imported"}},
+	}, cnf.Feeds)
+
+	data, err = os.ReadFile(configPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "# уже настроенный фид")
+
+	info, err := os.Stat(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	// повторный импорт ничего не меняет
+	assert.Contains(t, run(opmlPath), "0 feed(s) to add, 1 skipped, 3 already in the config")
+}
+
+func TestCLI_ImportOPML_DuplicateFolders(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	opmlPath := filepath.Join(dir, "feeds.opml")
+	require.NoError(t, os.WriteFile(configPath, []byte(opmlTestConfig), 0o600))
+	require.NoError(t, os.WriteFile(opmlPath, []byte(`<?xml version="1.0"?>
+<opml version="2.0">
+  <body>
+    <outline text="Tech">
+      <outline text="LWN" xmlUrl="https://lwn.net/headlines/rss"/>
+    </outline>
+    <outline text="Linux">
+      <outline text="LWN.net" xmlUrl="https://lwn.net/headlines/rss"/>
+    </outline>
+    <outline text="imported">
+      <outline text="LWN" xmlUrl="https://lwn.net/headlines/rss"/>
+    </outline>
+  </body>
+</opml>`), 0o644))
+
+	var out bytes.Buffer
+	c := newCLI(noEnv, &out, io.Discard, zap.NewNop())
+	require.NoError(t, c.Run(context.Background(), []string{"--config", configPath, "import-opml", "-tags", "imported", opmlPath}))
+	assert.Contains(t, out.String(), "1 feed(s) to add, 0 skipped, 0 already in the config")
+
+	cnf, err := internal.ParseConfigFile(configPath)
+	require.NoError(t, err)
+	require.Len(t, cnf.Feeds, 2)
+	// теги всех папок, без повторов
+	assert.Equal(t, internal.FeedConfig{
+		Name: "LWN",
+		URL:  "https://lwn.net/headlines/rss",
+		Tags: []string{"Tech", "Linux", "imported"},
+	}, cnf.Feeds[1])
+}
+
+func TestCLI_ImportOPML_SymlinkedConfig(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "dotfiles", "config.yaml")
+	configPath := filepath.Join(dir, "config.yaml")
+	opmlPath := filepath.Join(dir, "feeds.opml")
+	require.NoError(t, os.Mkdir(filepath.Dir(target), 0o755))
+	require.NoError(t, os.WriteFile(target, []byte(opmlTestConfig), 0o600))
+	require.NoError(t, os.Symlink(target, configPath))
+	require.NoError(t, os.WriteFile(opmlPath, []byte(opmlTestFile), 0o644))
+
+	c := newCLI(noEnv, io.Discard, io.Discard, zap.NewNop())
+	require.NoError(t, c.Run(context.Background(), []string{"--config", configPath, "import-opml", opmlPath}))
+
+	// симлинк остался симлинком, изменился файл, на который он ведёт
+	info, err := os.Lstat(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, os.ModeSymlink, info.Mode().Type())
+
+	cnf, err := internal.ParseConfigFile(target)
+	require.NoError(t, err)
+	assert.Len(t, cnf.Feeds, 3)
+}
+
+func TestCLI_ImportOPML_InvalidConfig(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	opmlPath := filepath.Join(dir, "feeds.opml")
+	require.NoError(t, os.WriteFile(configPath, []byte("telegram:\n  channel_name: \"@test_channel\"\n"), 0o644))
+	require.NoError(t, os.WriteFile(opmlPath, []byte(opmlTestFile), 0o644))
+
+	c := newCLI(noEnv, io.Discard, io.Discard, zap.NewNop())
+	err := c.Run(context.Background(), []string{"--config", configPath, "import-opml", opmlPath})
+	assert.ErrorContains(t, err, "telegram.bot_token")
+}
+
+func TestCLI_ExportOPML(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte(opmlTestConfig+`  - name: LWN
+    url: https://lwn.net/headlines/rss
+    tags: [it]
+`), 0o644))
+
+	var out bytes.Buffer
+	c := newCLI(noEnv, &out, io.Discard, zap.NewNop())
+	require.NoError(t, c.Run(context.Background(), []string{"--config", configPath, "export-opml"}))
+
+	doc, err := opml.Parse(&out)
+	require.NoError(t, err)
+	assert.Equal(t, []opml.Feed{
+		{Title: "Go Blog", URL: "https://go.dev/blog/feed.atom"},
+		{Title: "LWN", URL: "https://lwn.net/headlines/rss", Tags: []string{"it"}},
+	}, doc.Feeds())
+}
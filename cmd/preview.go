@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"rssgram/internal"
+	"rssgram/internal/feed"
+	"rssgram/internal/outputs/telegram"
+)
+
+type previewMessage struct {
+	ItemID      string     `json:"item_id"`
+	Link        string     `json:"link"`
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+	// выходы и тихий режим, выбранные правилами маршрутизации
+	Outputs []string `json:"outputs,omitempty"`
+	Silent  *bool    `json:"silent,omitempty"`
+	telegram.RenderedMessage
+}
+
+type previewResult struct {
+	Feed        string           `json:"feed"`
+	URL         string           `json:"url"`
+	ResolvedURL string           `json:"resolved_url,omitempty"`
+	Messages    []previewMessage `json:"messages"`
+}
+
+// previewFeedConfig берёт фид из конфига по имени или URL, либо собирает новый по URL.
+// Флаги -name, -description-type и -tags переопределяют настройки фида.
+func previewFeedConfig(cnf *internal.Config, feedRef, url, name, descriptionType, tags string) (internal.FeedConfig, error) {
+	var fc internal.FeedConfig
+
+	switch {
+	case feedRef != "":
+		found := false
+		for _, f := range cnf.Feeds {
+			if f.Name == feedRef || f.URL == feedRef {
+				fc, found = f, true
+				break
+			}
+		}
+		if !found {
+			return fc, fmt.Errorf("feed %q not found in config", feedRef)
+		}
+	case url != "":
+		fc.URL = url
+	default:
+		return fc, fmt.Errorf("usage: rssgram preview [flags] <url> or rssgram preview -feed <name>")
+	}
+
+	if name != "" {
+		fc.Name = name
+	}
+	if descriptionType != "" {
+		fc.DescriptionType = descriptionType
+	}
+	if tags := splitTags(tags); len(tags) > 0 {
+		fc.Tags = tags
+	}
+
+	return fc, nil
+}
+
+// preview показывает сообщения, которые ушли бы в Telegram из фида, не трогая базу и Telegram.
+func (c *cli) preview(ctx context.Context, args []string) error {
+	fs := c.flagSet("preview")
+	feedRef := fs.String("feed", "", "name or URL of a feed from the config")
+	name := fs.String("name", "", "feed name shown in messages")
+	descriptionType := fs.String("description-type", "", "item, link, fulltext or none")
+	tags := fs.String("tags", "", "comma-separated tags that replace the feed categories")
+	limit := fs.Int("limit", 5, "number of latest items to render, 0 - all")
+	asJSON := fs.Bool("json", false, "print messages as JSON")
+	noFilters := fs.Bool("no-filters", false, "ignore filters and routing rules from the config")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	source := c.source()
+	source.Optional = true
+
+	cnf, err := source.Load()
+	if err != nil {
+		return fmt.Errorf("failed to parse config %s: %w", c.configPath, err)
+	}
+
+	fc, err := previewFeedConfig(cnf, *feedRef, fs.Arg(0), *name, *descriptionType, *tags)
+	if err != nil {
+		return err
+	}
+
+	feedConfig := newFeedConfig(fc, cnf)
+	if *noFilters {
+		feedConfig.Filters = feed.FiltersConfig{}
+		feedConfig.Rules = nil
+	}
+
+	f, err := feed.NewManager(nil).Preview(ctx, feedConfig, *limit)
+	if err != nil {
+		return err
+	}
+
+	result := previewResult{Feed: f.Title, URL: f.URL, ResolvedURL: f.ResolvedURL, Messages: []previewMessage{}}
+	for i := range f.Items {
+		item := &f.Items[i]
+		result.Messages = append(result.Messages, previewMessage{
+			ItemID:          item.ID,
+			Link:            item.Link,
+			PublishedAt:     item.PublishedAt,
+			Outputs:         item.Outputs,
+			Silent:          item.Silent,
+			RenderedMessage: telegram.RenderMessage(item, cnf.EnableTags),
+		})
+	}
+
+	if *asJSON {
+		return writeJSON(c.stdout, result)
+	}
+
+	fmt.Fprintf(c.stdout, "Feed: %s (%s), %d message(s)\n", result.Feed, result.URL, len(result.Messages))
+	if result.ResolvedURL != "" {
+		fmt.Fprintf(c.stdout, "Discovered feed: %s\n", result.ResolvedURL)
+	}
+	for i, m := range result.Messages {
+		published := "-"
+		if m.PublishedAt != nil {
+			published = formatListTime(*m.PublishedAt)
+		}
+
+		fmt.Fprintf(c.stdout, "\n--- %d/%d  %s  %s\n", i+1, len(result.Messages), published, m.Link)
+		if len(m.Outputs) > 0 {
+			fmt.Fprintf(c.stdout, "outputs: %s\n", strings.Join(m.Outputs, ", "))
+		}
+		if m.Silent != nil {
+			fmt.Fprintf(c.stdout, "silent: %t\n", *m.Silent)
+		}
+		if m.ImageURL != "" {
+			fmt.Fprintf(c.stdout, "image: %s\n", m.ImageURL)
+		}
+		fmt.Fprintln(c.stdout, m.Text)
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const previewTestRSS = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0">
+<channel>
+  <title>Test Feed</title>
+  <item>
+    <title>Second &lt;post&gt;</title>
+    <link>https://example.com/2</link>
+    <description>&lt;p&gt;Second description&lt;/p&gt;</description>
+    <category>go</category>
+    <pubDate>Wed, 01 May 2024 11:00:00 GMT</pubDate>
+  </item>
+  <item>
+    <title>First post</title>
+    <link>https://example.com/1</link>
+    <description>First description</description>
+    <pubDate>Wed, 01 May 2024 10:00:00 GMT</pubDate>
+  </item>
+</channel>
+</rss>`
+
+func TestCLI_Preview(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		io.WriteString(w, previewTestRSS)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	dbPath := filepath.Join(dir, "data.db")
+	require.NoError(t, os.WriteFile(configPath, []byte(`
+enable_tags: true
+feeds:
+  - name: Configured
+    url: `+srv.URL+`
+    tags: ["news"]
+`), 0o644))
+
+	run := func(args ...string) (string, error) {
+		var out bytes.Buffer
+		c := newCLI(noEnv, &out, io.Discard, zap.NewNop())
+		err := c.Run(context.Background(), append([]string{"--config", configPath, "--db", "file:" + dbPath, "preview"}, args...))
+		return out.String(), err
+	}
+
+	out, err := run("-json", "-limit", "1", srv.URL)
+	require.NoError(t, err)
+
+	var result previewResult
+	require.NoError(t, json.Unmarshal([]byte(out), &result))
+	assert.Equal(t, "Test Feed", result.Feed)
+	require.Len(t, result.Messages, 1)
+	assert.Equal(t, "https://example.com/2", result.Messages[0].Link)
+	assert.Contains(t, result.Messages[0].Text, "Second &lt;post&gt;")
+	assert.Contains(t, result.Messages[0].Text, "<blockquote>Second description</blockquote>")
+	assert.Contains(t, result.Messages[0].Text, "#go")
+
+	// фид из конфига: имя и теги берутся из его настроек, новости в порядке отправки
+	out, err = run("-feed", "Configured")
+	require.NoError(t, err)
+	assert.Contains(t, out, "Feed: Configured")
+	assert.Contains(t, out, "2 message(s)")
+	assert.Contains(t, out, "#news")
+	assert.Less(t, bytes.Index([]byte(out), []byte("First post")), bytes.Index([]byte(out), []byte("Second")))
+
+	// пробелы вокруг тегов и пустые теги отбрасываются
+	out, err = run("-feed", "Configured", "-tags", "go, linux,,")
+	require.NoError(t, err)
+	assert.Contains(t, out, "#go #linux")
+	assert.NotContains(t, out, "#news")
+
+	_, err = run("-feed", "Unknown")
+	assert.Error(t, err)
+
+	// база не создавалась
+	assert.NoFileExists(t, dbPath)
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"rssgram/internal"
+	"rssgram/internal/feed"
+	"rssgram/internal/outputs/telegram"
+	"rssgram/internal/storage/backend"
+)
+
+// senderState - то, что отправитель помнит между проходами. После рестарта
+// интервалы каналов отсчитываются заново, а накопленное за тихие часы уходит по очереди.
+type senderState struct {
+	// время последнего сообщения в каждый выход, для min_interval
+	lastPost map[string]time.Time
+	// выходы, чьи новости ждут конца тихих часов с release: digest
+	held map[string]bool
+}
+
+func newSenderState() *senderState {
+	return &senderState{lastPost: make(map[string]time.Time), held: make(map[string]bool)}
+}
+
+// quietHours решает для каждого выхода с silent_mode.mode: defer, держать ли его новости
+// на этом проходе (hold) или отправить накопленное дайджестом (release).
+func quietHours(cnf *internal.Config, state *senderState, now time.Time) (hold, release map[string]bool, err error) {
+	modes := map[string]telegram.SilentModeConfig{feed.DefaultOutput: cnf.Telegram.SilentMode}
+	for _, o := range cnf.Outputs {
+		modes[o.Name] = o.WithDefaults(cnf.Telegram.TelegramChannelClientConfig).SilentMode
+	}
+
+	hold = make(map[string]bool)
+	release = make(map[string]bool)
+	var errs []error
+	for name, mode := range modes {
+		if !mode.Defer() {
+			delete(state.held, name)
+			continue
+		}
+
+		active, err := mode.Active(now)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("output %s: %w", name, err))
+			continue
+		}
+
+		switch {
+		case active:
+			hold[name] = true
+			state.held[name] = mode.Release == telegram.SilentReleaseDigest
+		case state.held[name]:
+			release[name] = true
+			delete(state.held, name)
+		}
+	}
+
+	return hold, release, errors.Join(errs...)
+}
+
+// outputSpacing - min_interval выходов; выходы без интервала не попадают в map.
+func outputSpacing(cnf *internal.Config) map[string]time.Duration {
+	spacing := make(map[string]time.Duration)
+	if cnf.Telegram.MinInterval > 0 {
+		spacing[feed.DefaultOutput] = cnf.Telegram.MinInterval
+	}
+	for _, o := range cnf.Outputs {
+		if o.MinInterval > 0 {
+			spacing[o.Name] = o.MinInterval
+		}
+	}
+	return spacing
+}
+
+// quotas - квоты фидов и отправленные за скользящие час и сутки новости.
+type quotas struct {
+	feeds map[string]internal.QuotaConfig
+	hour  map[string]int
+	day   map[string]int
+}
+
+// loadQuotas считает отправленные новости, только если хотя бы у одного фида есть квота.
+func loadQuotas(ctx context.Context, cnf *internal.Config, store backend.Storage, now time.Time) (*quotas, error) {
+	q := &quotas{
+		feeds: make(map[string]internal.QuotaConfig, len(cnf.Feeds)),
+		hour:  map[string]int{},
+		day:   map[string]int{},
+	}
+
+	enabled := false
+	for _, f := range cnf.Feeds {
+		quota := cnf.Quota.Merge(f.Quota)
+		if quota.Enabled() {
+			q.feeds[f.URL] = quota
+			enabled = true
+		}
+	}
+	if !enabled {
+		return q, nil
+	}
+
+	var err error
+	if q.hour, err = store.CountSentItems(ctx, now.Add(-time.Hour)); err != nil {
+		return nil, fmt.Errorf("failed to count items sent in the last hour: %w", err)
+	}
+	if q.day, err = store.CountSentItems(ctx, now.Add(-24*time.Hour)); err != nil {
+		return nil, fmt.Errorf("failed to count items sent in the last day: %w", err)
+	}
+	return q, nil
+}
+
+func (q *quotas) exceeded(feedURL string) bool {
+	quota, ok := q.feeds[feedURL]
+	if !ok {
+		return false
+	}
+	return (quota.PerHour > 0 && q.hour[feedURL] >= quota.PerHour) ||
+		(quota.PerDay > 0 && q.day[feedURL] >= quota.PerDay)
+}
+
+// overflowDigest - дайджест для новостей сверх квоты; выключенный, если их надо держать в очереди.
+func (q *quotas) overflowDigest(feedURL string) telegram.DigestConfig {
+	return q.feeds[feedURL].OverflowDigest
+}
+
+func (q *quotas) sent(feedURL string) {
+	q.hour[feedURL]++
+	q.day[feedURL]++
+}
+
+// interleaveFeeds чередует новости фидов по кругу, сохраняя порядок внутри фида:
+// фид, выложивший разом много новостей, не задерживает остальные. Круг начинается
+// с фида, чья новость старше.
+func interleaveFeeds(items []feed.FeedItem) []feed.FeedItem {
+	var order []string
+	byFeed := make(map[string][]feed.FeedItem)
+	for _, item := range items {
+		if _, ok := byFeed[item.FeedURL]; !ok {
+			order = append(order, item.FeedURL)
+		}
+		byFeed[item.FeedURL] = append(byFeed[item.FeedURL], item)
+	}
+
+	result := make([]feed.FeedItem, 0, len(items))
+	for len(result) < len(items) {
+		for _, url := range order {
+			if queue := byFeed[url]; len(queue) > 0 {
+				result = append(result, queue[0])
+				byFeed[url] = queue[1:]
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"testing"
+
+	"rssgram/internal/feed"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInterleaveFeeds(t *testing.T) {
+	items := []feed.FeedItem{
+		{ID: "a1", FeedURL: "a"},
+		{ID: "a2", FeedURL: "a"},
+		{ID: "a3", FeedURL: "a"},
+		{ID: "b1", FeedURL: "b"},
+		{ID: "a4", FeedURL: "a"},
+		{ID: "c1", FeedURL: "c"},
+		{ID: "b2", FeedURL: "b"},
+	}
+
+	var ids []string
+	for _, item := range interleaveFeeds(items) {
+		ids = append(ids, item.ID)
+	}
+	assert.Equal(t, []string{"a1", "b1", "c1", "a2", "b2", "a3", "a4"}, ids)
+
+	assert.Empty(t, interleaveFeeds(nil))
+}
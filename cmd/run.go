@@ -0,0 +1,549 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"log/syslog"
+	"net/http"
+	"os/signal"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
+
+	"rssgram/internal"
+	"rssgram/internal/feed"
+	"rssgram/internal/metrics"
+	"rssgram/internal/outputs/telegram"
+	"rssgram/internal/storage"
+	"rssgram/internal/storage/backend"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+)
+
+// runService - основной режим: забирает фиды и отправляет новости, пока не придёт SIGINT/SIGTERM.
+func (c *cli) runService(ctx context.Context, args []string) error {
+	fs := c.flagSet("run")
+	fs.BoolVar(&c.dryRun, "dry-run", c.dryRun, "write messages to the log instead of Telegram (env RSSGRAM_DRY_RUN)")
+	fs.StringVar(&c.dryRunFile, "dry-run-file", c.dryRunFile, "write dry run messages to this JSONL file (env RSSGRAM_DRY_RUN_FILE)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cnf, err := c.loadConfig()
+	if err != nil {
+		return err
+	}
+
+	if err = c.setupLogger(cnf.Log); err != nil {
+		return err
+	}
+	logger := c.logger
+
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	storage, err := backend.Open(ctx, cnf.Database)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	// если горутины не остановились за shutdown_timeout, они ещё пишут в базу и файл dry-run:
+	// закрывать их нельзя, процесс и так завершается
+	drained := true
+	defer func() {
+		if drained {
+			storage.Close()
+		}
+	}()
+
+	w, _ := syslog.New(syslog.LOG_SYSLOG|syslog.LOG_INFO, "rssgram")
+	log.SetOutput(w)
+
+	err = storage.Migrate(ctx)
+	if err != nil {
+		return fmt.Errorf("migrate failed: %w", err)
+	}
+
+	var wg sync.WaitGroup
+	run := func(f func()) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f()
+		}()
+	}
+
+	// клиент dry-run создаётся один раз: файл открыт на всё время работы
+	var dryRun *telegram.DryRunClient
+	if cnf.DryRun.Enabled {
+		dryRun, err = telegram.NewDryRunClient(cnf.DryRun, cnf.Telegram.ChannelName, logger.With(zap.String("module", "dry_run")))
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if drained {
+				dryRun.Close()
+			}
+		}()
+
+		logger.Warn("dry run mode: messages are not sent to Telegram", zap.String("file", cnf.DryRun.File))
+	}
+
+	config := internal.NewConfigHolder(c.source(), cnf, logger.With(zap.String("module", "config")))
+	if c.logLevel != nil {
+		config.WatchLogLevel(*c.logLevel)
+	}
+
+	run(func() {
+		if err := config.Watch(ctx); err != nil {
+			logger.Error("config hot-reload disabled", zap.Error(err))
+		}
+	})
+	run(func() { feedGetter(ctx, config, storage, logger.With(zap.String("module", "feed_manager"))) })
+	run(func() { itemSender(ctx, config, storage, dryRun, logger.With(zap.String("module", "sender"))) })
+	run(func() { metricHandler(ctx, cnf, storage, logger.With(zap.String("module", "metric_handler"))) })
+	run(func() { pruner(ctx, config, storage, logger.With(zap.String("module", "pruner"))) })
+
+	<-ctx.Done()
+	stop()
+
+	shutdownTimeout := cnf.GetShutdownTimeout()
+	logger.Info(fmt.Sprintf("shutting down, waiting up to %s for in-flight work", shutdownTimeout))
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		logger.Info("shutdown complete")
+	case <-time.After(shutdownTimeout):
+		drained = false
+		return fmt.Errorf("shutdown timed out after %s", shutdownTimeout)
+	}
+
+	return nil
+}
+
+func feedGetter(ctx context.Context, config *internal.ConfigHolder, storage backend.Storage, logger *zap.Logger) {
+	// менеджер один на все проходы: он помнит robots.txt сайтов и паузы между запросами к ним
+	m := feed.NewManager(storage)
+	var feeds feedConfigs
+
+	ticker := time.NewTicker(1 * time.Millisecond)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-ticker.C:
+			ticker.Stop()
+			cnf := config.Load()
+			_feedGetter(ctx, m, cnf, feeds.get(cnf, logger), storage, logger)
+			ticker.Reset(10 * time.Second)
+		}
+
+	}
+}
+
+func _feedGetter(ctx context.Context, m *feed.Manager, cnf *internal.Config, feeds []feed.FeedConfig, storage backend.Storage, logger *zap.Logger) {
+	metrics.FeedsCount.Set(float64(len(feeds)))
+
+	for _, fc := range feeds {
+		if ctx.Err() != nil {
+			return
+		}
+
+		err := m.ProcessFeed(ctx, fc, logger)
+		if err != nil {
+			logger.Error("failed to process feed", zap.String("url", fc.URL), zap.Error(err))
+			continue
+		}
+	}
+
+	// устаревший кэш обогащения больше не прочитается, его можно удалить
+	_, err := storage.DeleteEnrichCache(ctx, time.Now().Add(-cnf.Enrich.WithDefaults().CacheTTL))
+	if err != nil {
+		logger.Error("failed to delete expired enrich cache", zap.Error(err))
+	}
+}
+
+// feedConfigs хранит настройки фидов, собранные из одного снимка конфига. Фильтры и правила
+// компилируются, только когда ConfigHolder отдаёт новый снимок, а не на каждом проходе.
+type feedConfigs struct {
+	cnf   *internal.Config
+	feeds []feed.FeedConfig
+}
+
+func (c *feedConfigs) get(cnf *internal.Config, logger *zap.Logger) []feed.FeedConfig {
+	if cnf == c.cnf {
+		return c.feeds
+	}
+
+	feeds := make([]feed.FeedConfig, 0, len(cnf.Feeds))
+	for _, f := range cnf.Feeds {
+		fc, err := newFeedConfig(f, cnf).Compile()
+		if err != nil {
+			// конфиг проверен при загрузке, сюда попасть не должно; фид сообщит об ошибке при обработке
+			logger.Error("failed to compile feed config", zap.String("url", f.URL), zap.Error(err))
+		}
+		feeds = append(feeds, fc)
+	}
+
+	c.cnf, c.feeds = cnf, feeds
+	return feeds
+}
+
+// newFeedConfig - временный перегон из старого ConfigFeed.
+// Глобальные фильтры и правила применяются к каждому фиду вместе с его собственными,
+// правила фида проверяются первыми.
+func newFeedConfig(f internal.FeedConfig, cnf *internal.Config) feed.FeedConfig {
+	return feed.FeedConfig{
+		Name:            f.Name,
+		URL:             f.URL,
+		Key:             f.Key,
+		DescriptionType: f.DescriptionType,
+		Tags:            f.Tags,
+		Filters:         cnf.Filters.Merge(f.Filters),
+		Rules:           append(slices.Clip(f.Rules), cnf.Rules...),
+		Dedup:           cnf.Dedup,
+		Enrich:          cnf.Enrich,
+	}
+}
+
+func itemSender(ctx context.Context, config *internal.ConfigHolder, storage backend.Storage, dryRun *telegram.DryRunClient, logger *zap.Logger) {
+	state := newSenderState()
+
+	ticker := time.NewTicker(1 * time.Millisecond)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-ticker.C:
+			ticker.Stop()
+			_itemSender(ctx, config.Load(), storage, dryRun, state, logger)
+			ticker.Reset(10 * time.Second)
+		}
+
+	}
+}
+
+// newOutputs собирает выходы для отправки: default из секции telegram и именованные из outputs.
+func newOutputs(cnf *internal.Config, dryRun *telegram.DryRunClient, logger *zap.Logger) map[string]*telegram.TelegramChannelOutput {
+	outputs := make(map[string]*telegram.TelegramChannelOutput, len(cnf.Outputs)+1)
+
+	add := func(name string, conf telegram.TelegramChannelOutputConfig) {
+		if dryRun != nil {
+			outputs[name] = telegram.NewTelegramChannelOutputWithClient(conf, dryRun.WithChatID(conf.ChannelName), cnf.EnableTags)
+			return
+		}
+		outputs[name] = telegram.NewTelegramChannelOutput(conf, logger.With(zap.String("output", name)), cnf.EnableTags)
+	}
+
+	add(feed.DefaultOutput, cnf.Telegram)
+	for _, o := range cnf.Outputs {
+		add(o.Name, o.WithDefaults(cnf.Telegram.TelegramChannelClientConfig))
+	}
+
+	return outputs
+}
+
+// itemOutputs возвращает выходы, в которые новость ещё не отправлена. Выходы, убранные
+// из конфига после сохранения новости, пропускаются; если не осталось ни одного, новость уходит в default.
+func itemOutputs(item *feed.FeedItem, outputs map[string]*telegram.TelegramChannelOutput, logger *zap.Logger) []string {
+	var names []string
+	for _, name := range item.Outputs {
+		if _, ok := outputs[name]; !ok {
+			logger.Warn("item output is not configured, skipped", zap.String("item_id", item.ID), zap.String("output", name))
+			continue
+		}
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		names = []string{feed.DefaultOutput}
+	}
+
+	return slices.DeleteFunc(names, func(name string) bool {
+		return slices.Contains(item.SentOutputs, name)
+	})
+}
+
+// pushItem отправляет новость в выходы names. Если у новости несколько выходов, каждая удачная
+// отправка запоминается, чтобы после ошибки в следующем выходе повтор не дублировал сообщение.
+func pushItem(ctx context.Context, item *feed.FeedItem, names []string, track bool, outputs map[string]*telegram.TelegramChannelOutput, store backend.Storage) error {
+	pushCtx := context.WithValue(ctx, "item_id", item.ID)
+
+	for _, name := range names {
+		isSuccess, err := outputs[name].Push(pushCtx, item)
+		if err != nil {
+			return fmt.Errorf("failed to send item to %s: %w", name, err)
+		}
+		if !isSuccess {
+			return fmt.Errorf("failed to send item to %s", name)
+		}
+
+		if track {
+			if err = store.SetItemOutputSent(ctx, item.ID, name); err != nil {
+				return err
+			}
+			item.SentOutputs = append(item.SentOutputs, name)
+		}
+	}
+
+	return nil
+}
+
+// _itemSender отправляет накопившиеся новости в выходы, выбранные правилами маршрутизации,
+// а новости фидов и выходов с digest - дайджестами по их расписанию. Фиды чередуются;
+// новости сверх квоты фида, раньше min_interval канала и в тихие часы с defer остаются в очереди.
+// С dryRun сообщения пишутся в лог или файл, а новости помечаются статусом dry_run.
+func _itemSender(ctx context.Context, cnf *internal.Config, store backend.Storage, dryRun *telegram.DryRunClient, state *senderState, logger *zap.Logger) {
+	outputs := newOutputs(cnf, dryRun, logger)
+	deliveryStatus := storage.DeliveryStatusSent
+
+	if dryRun != nil {
+		deliveryStatus = storage.DeliveryStatusDryRun
+	}
+
+	itemsToSend, err := store.GetItemsReadyToSend(ctx, 0)
+	if err != nil {
+		logger.Error("failed to get items to send", zap.Error(err))
+	}
+
+	metrics.ItemsReadyToSendCount.Set(float64(len(itemsToSend)))
+
+	failedItems, err := store.GetCountItemsSendFailed(ctx)
+	if err != nil {
+		logger.Error("failed to get failed items", zap.Error(err))
+	}
+
+	metrics.ItemsSentFailedCount.Set(float64(failedItems))
+
+	logger.Debug(fmt.Sprintf("got %d items to send", len(itemsToSend)))
+
+	quotas, err := loadQuotas(ctx, cnf, store, time.Now())
+	if err != nil {
+		logger.Error("failed to load quotas", zap.Error(err))
+		return
+	}
+	spacing := outputSpacing(cnf)
+	digests := newDigests(cnf)
+
+	hold, release, err := quietHours(cnf, state, time.Now())
+	if err != nil {
+		logger.Error("failed to check quiet hours", zap.Error(err))
+	}
+	digests.hold = hold
+
+	var deferredByQuota, deferredBySpacing, deferredByQuietHours int
+
+	itemsToSend = interleaveFeeds(itemsToSend)
+	for i := range itemsToSend {
+		item := &itemsToSend[i]
+		names := itemOutputs(item, outputs, logger)
+
+		// выходы с дайджестом копят новость, в остальные она уходит сразу
+		var immediate []string
+		var batches []*digestBatch
+		for _, name := range names {
+			if b := digests.batch(item, name); b != nil {
+				batches = append(batches, b)
+			} else {
+				immediate = append(immediate, name)
+			}
+		}
+
+		// новость сверх квоты или интервала не отбрасывается, а ждёт следующего прохода
+		deferred := false
+		if len(immediate) > 0 && quotas.exceeded(item.FeedURL) {
+			if conf := quotas.overflowDigest(item.FeedURL); conf.Enabled() {
+				for _, name := range immediate {
+					batches = append(batches, digests.overflow(item, name, conf))
+				}
+			} else {
+				deferred = true
+				deferredByQuota++
+			}
+			immediate = nil
+		}
+
+		var ready []string
+		var spaced, held bool
+		for _, name := range immediate {
+			switch last, ok := state.lastPost[name]; {
+			case hold[name]:
+				held = true
+			case release[name]:
+				batches = append(batches, digests.quiet(name))
+			case ok && time.Since(last) < spacing[name]:
+				spaced = true
+			default:
+				ready = append(ready, name)
+			}
+		}
+		if held {
+			deferredByQuietHours++
+		}
+		if spaced {
+			deferredBySpacing++
+		}
+		deferred = deferred || held || spaced
+
+		// начатая отправка не прерывается при остановке: иначе сообщение может уйти в Telegram,
+		// а отметка is_sent - не записаться, и после рестарта новость отправится повторно
+		itemCtx := context.WithoutCancel(ctx)
+
+		if len(ready) > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(1 * time.Second):
+			}
+			logger.Debug(fmt.Sprintf("sending %s ...", item.ID))
+
+			err := pushItem(itemCtx, item, ready, len(names) > 1, outputs, store)
+			if err != nil {
+				logger.Error("failed to send item", zap.String("item_id", item.ID), zap.Error(err))
+				metrics.ItemsSentErrorCount.WithLabelValues(item.FeedTitle).Inc()
+				err = store.IncrementItemFailedCounter(itemCtx, item.ID)
+				if err != nil {
+					logger.Error("failed to increment item failed", zap.Error(err))
+				}
+				continue
+			}
+
+			quotas.sent(item.FeedURL)
+			for _, name := range ready {
+				state.lastPost[name] = time.Now()
+			}
+		}
+
+		for _, b := range batches {
+			b.add(item, len(batches) > 1 || deferred)
+		}
+		if len(batches) > 0 || deferred {
+			continue
+		}
+
+		err = store.SetItemIsSent(itemCtx, item.ID, deliveryStatus)
+		if err != nil {
+			logger.Error("failed to set is_sent for item", zap.Error(err))
+			continue
+		}
+		logger.Debug("sent")
+
+		metrics.ItemsSentSuccessCount.WithLabelValues(item.FeedTitle).Inc()
+	}
+
+	metrics.ItemsDeferredCount.WithLabelValues("quota").Set(float64(deferredByQuota))
+	metrics.ItemsDeferredCount.WithLabelValues("spacing").Set(float64(deferredBySpacing))
+	metrics.ItemsDeferredCount.WithLabelValues("quiet_hours").Set(float64(deferredByQuietHours))
+
+	sendDigests(ctx, digests, outputs, store, deliveryStatus, logger)
+}
+
+func pruner(ctx context.Context, config *internal.ConfigHolder, storage backend.Storage, logger *zap.Logger) {
+	lastVacuum := time.Now()
+
+	ticker := time.NewTicker(1 * time.Millisecond)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-ticker.C:
+			ticker.Stop()
+
+			cnf := config.Load()
+			retention := cnf.Retention.WithDefaults()
+
+			if !cnf.Retention.Enabled {
+				// retention можно включить без рестарта
+				ticker.Reset(retention.Interval)
+				continue
+			}
+
+			_pruner(ctx, cnf, retention, storage, logger)
+
+			if time.Since(lastVacuum) >= retention.VacuumInterval {
+				lastVacuum = time.Now()
+				if err := storage.Vacuum(ctx); err != nil {
+					logger.Error("failed to vacuum database", zap.Error(err))
+				}
+			}
+
+			ticker.Reset(retention.Interval)
+		}
+	}
+}
+
+func _pruner(ctx context.Context, cnf *internal.Config, retention storage.RetentionConfig, store backend.Storage, logger *zap.Logger) {
+	activeFeeds := make([]string, 0, len(cnf.Feeds))
+	for _, f := range cnf.Feeds {
+		activeFeeds = append(activeFeeds, f.URL)
+	}
+
+	result, err := store.PruneItems(ctx, retention.Policy(time.Now(), activeFeeds))
+	if err != nil {
+		logger.Error("failed to prune items", zap.Error(err))
+	} else {
+		metrics.ItemsPrunedCount.WithLabelValues("expired").Add(float64(result.ExpiredItems))
+		metrics.ItemsPrunedCount.WithLabelValues("removed_feed").Add(float64(result.RemovedFeedItems))
+		logger.Info("items pruned",
+			zap.Int64("expired", result.ExpiredItems),
+			zap.Int64("removed_feed_items", result.RemovedFeedItems),
+			zap.Int64("removed_feeds", result.RemovedFeeds),
+		)
+	}
+
+	size, err := store.Size(ctx)
+	if err != nil {
+		logger.Error("failed to get database size", zap.Error(err))
+		return
+	}
+	metrics.DatabaseSizeBytes.Set(float64(size))
+}
+
+func metricHandler(ctx context.Context, cnf *internal.Config, storage backend.Storage, logger *zap.Logger) {
+	if !cnf.Metrics.Enabled {
+		logger.Info("metrics disabled")
+		return
+	}
+
+	if cnf.Metrics.Port == 0 {
+		logger.Warn("metrics port must be greater than 0")
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.Handle("/search", searchHandler(storage, logger))
+
+	srv := &http.Server{
+		Addr:    fmt.Sprintf(":%d", cnf.Metrics.Port),
+		Handler: mux,
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cnf.GetShutdownTimeout())
+		defer cancel()
+
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			logger.Error("failed to shutdown http server", zap.Error(err))
+		}
+	}()
+
+	logger.Info(fmt.Sprintf("start to serve /metrics and /search on %d port", cnf.Metrics.Port))
+	err := srv.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Error("http server failed", zap.Error(err))
+	}
+}
@@ -0,0 +1,392 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"rssgram/internal"
+	"rssgram/internal/feed"
+	"rssgram/internal/outputs/telegram"
+	"rssgram/internal/storage"
+	"rssgram/internal/storage/backend"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestItemSender_DryRun(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	store, err := backend.Open(ctx, storage.Config{DSN: "file:" + filepath.Join(dir, "data.db")})
+	require.NoError(t, err)
+	defer store.Close()
+	require.NoError(t, store.Migrate(ctx))
+
+	published := time.Now().UTC()
+	require.NoError(t, store.InsertItem(ctx, &feed.FeedItem{
+		ID:          "item-1",
+		FeedURL:     "https://example.com/rss",
+		FeedTitle:   "Test Feed",
+		Title:       "Dry run title",
+		Link:        "https://example.com/1",
+		PublishedAt: &published,
+	}))
+
+	path := filepath.Join(dir, "messages.jsonl")
+	dryRun, err := telegram.NewDryRunClient(telegram.DryRunConfig{Enabled: true, File: path}, "@test_channel", zap.NewNop())
+	require.NoError(t, err)
+
+	cnf := &internal.Config{}
+	cnf.Telegram.ChannelName = "@test_channel"
+
+	_itemSender(ctx, cnf, store, dryRun, newSenderState(), zap.NewNop())
+	require.NoError(t, dryRun.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"item_id":"item-1"`)
+	assert.Contains(t, string(data), "Dry run title")
+
+	items, err := store.ListItems(ctx, storage.ItemFilter{})
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, storage.ItemStatusDryRun, items[0].Status)
+
+	ready, err := store.GetItemsReadyToSend(ctx, 0)
+	require.NoError(t, err)
+	assert.Empty(t, ready)
+}
+
+func TestItemSender_Outputs(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	store, err := backend.Open(ctx, storage.Config{DSN: "file:" + filepath.Join(dir, "data.db")})
+	require.NoError(t, err)
+	defer store.Close()
+	require.NoError(t, store.Migrate(ctx))
+
+	silent := true
+	published := time.Now().UTC()
+	require.NoError(t, store.InsertItem(ctx, &feed.FeedItem{
+		ID:          "routed",
+		FeedURL:     "https://example.com/rss",
+		FeedTitle:   "Test Feed",
+		Title:       "Routed title",
+		Link:        "https://example.com/1",
+		PublishedAt: &published,
+		Outputs:     []string{"default", "security", "removed"},
+		Silent:      &silent,
+	}))
+	// в default новость уже ушла при прошлой попытке
+	require.NoError(t, store.SetItemOutputSent(ctx, "routed", "default"))
+
+	path := filepath.Join(dir, "messages.jsonl")
+	dryRun, err := telegram.NewDryRunClient(telegram.DryRunConfig{Enabled: true, File: path}, "@test_channel", zap.NewNop())
+	require.NoError(t, err)
+
+	cnf := &internal.Config{
+		Outputs: []telegram.OutputConfig{{
+			Name:                        "security",
+			TelegramChannelClientConfig: telegram.TelegramChannelClientConfig{ChannelName: "@security"},
+		}},
+	}
+	cnf.Telegram.ChannelName = "@test_channel"
+
+	_itemSender(ctx, cnf, store, dryRun, newSenderState(), zap.NewNop())
+	require.NoError(t, dryRun.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 1)
+
+	var msg telegram.DryRunMessage
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &msg))
+	assert.Equal(t, "@security", msg.ChatID)
+	assert.True(t, msg.DisableNotification)
+
+	ready, err := store.GetItemsReadyToSend(ctx, 0)
+	require.NoError(t, err)
+	assert.Empty(t, ready)
+}
+
+func TestItemSender_Digest(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	store, err := backend.Open(ctx, storage.Config{DSN: "file:" + filepath.Join(dir, "data.db")})
+	require.NoError(t, err)
+	defer store.Close()
+	require.NoError(t, store.Migrate(ctx))
+
+	published := time.Now().UTC()
+	insert := func(id, feedURL, feedTitle string) {
+		require.NoError(t, store.InsertItem(ctx, &feed.FeedItem{
+			ID:          id,
+			FeedURL:     feedURL,
+			FeedTitle:   feedTitle,
+			Title:       "Title " + id,
+			Link:        "https://example.com/" + id,
+			PublishedAt: &published,
+		}))
+	}
+	insert("busy-1", "https://busy.example.com/rss", "Busy")
+	insert("busy-2", "https://busy.example.com/rss", "Busy")
+	insert("quiet-1", "https://quiet.example.com/rss", "Quiet")
+
+	path := filepath.Join(dir, "messages.jsonl")
+	dryRun, err := telegram.NewDryRunClient(telegram.DryRunConfig{Enabled: true, File: path}, "@test_channel", zap.NewNop())
+	require.NoError(t, err)
+
+	cnf := &internal.Config{
+		Feeds: []internal.FeedConfig{
+			{Name: "Busy", URL: "https://busy.example.com/rss", Digest: telegram.DigestConfig{EveryItems: 2, Every: 24 * time.Hour}},
+			{Name: "Quiet", URL: "https://quiet.example.com/rss"},
+		},
+	}
+	cnf.Telegram.ChannelName = "@test_channel"
+
+	_itemSender(ctx, cnf, store, dryRun, newSenderState(), zap.NewNop())
+
+	// третья новость ждёт следующего дайджеста
+	insert("busy-3", "https://busy.example.com/rss", "Busy")
+	_itemSender(ctx, cnf, store, dryRun, newSenderState(), zap.NewNop())
+	require.NoError(t, dryRun.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+
+	var messages []telegram.DryRunMessage
+	for _, line := range lines {
+		var msg telegram.DryRunMessage
+		require.NoError(t, json.Unmarshal([]byte(line), &msg))
+		messages = append(messages, msg)
+	}
+	assert.Contains(t, messages[0].Text, "Title quiet-1")
+	assert.Contains(t, messages[1].Text, "<b>Digest: Busy</b>")
+	assert.Contains(t, messages[1].Text, "Title busy-1")
+	assert.Contains(t, messages[1].Text, "Title busy-2")
+
+	ready, err := store.GetItemsReadyToSend(ctx, 0)
+	require.NoError(t, err)
+	require.Len(t, ready, 1)
+	assert.Equal(t, "busy-3", ready[0].ID)
+
+	items, err := store.ListItems(ctx, storage.ItemFilter{IDs: []string{"busy-1", "busy-2"}})
+	require.NoError(t, err)
+	for _, item := range items {
+		assert.Equal(t, storage.ItemStatusDryRun, item.Status, item.ID)
+	}
+}
+
+func TestItemSender_Quota(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	store, err := backend.Open(ctx, storage.Config{DSN: "file:" + filepath.Join(dir, "data.db")})
+	require.NoError(t, err)
+	defer store.Close()
+	require.NoError(t, store.Migrate(ctx))
+
+	published := time.Now().UTC().Add(-time.Hour)
+	insert := func(id, feedURL string) {
+		at := published
+		require.NoError(t, store.InsertItem(ctx, &feed.FeedItem{
+			ID:          id,
+			FeedURL:     feedURL,
+			FeedTitle:   feedURL,
+			Title:       "Title " + id,
+			Link:        "https://example.com/" + id,
+			PublishedAt: &at,
+		}))
+		published = published.Add(time.Minute)
+	}
+	insert("busy-1", "https://busy.example.com/rss")
+	insert("busy-2", "https://busy.example.com/rss")
+	insert("busy-3", "https://busy.example.com/rss")
+	insert("quiet-1", "https://quiet.example.com/rss")
+
+	path := filepath.Join(dir, "messages.jsonl")
+	dryRun, err := telegram.NewDryRunClient(telegram.DryRunConfig{Enabled: true, File: path}, "@test_channel", zap.NewNop())
+	require.NoError(t, err)
+
+	cnf := &internal.Config{
+		Feeds: []internal.FeedConfig{
+			{Name: "Busy", URL: "https://busy.example.com/rss", Quota: internal.QuotaConfig{PerHour: 2}},
+			{Name: "Quiet", URL: "https://quiet.example.com/rss"},
+		},
+	}
+	cnf.Telegram.ChannelName = "@test_channel"
+
+	_itemSender(ctx, cnf, store, dryRun, newSenderState(), zap.NewNop())
+
+	// сверх квоты новость осталась в очереди, тихий фид не ждал всех новостей шумного
+	ready, err := store.GetItemsReadyToSend(ctx, 0)
+	require.NoError(t, err)
+	require.Len(t, ready, 1)
+	assert.Equal(t, "busy-3", ready[0].ID)
+
+	// с overflow_digest новость сверх квоты уходит дайджестом
+	cnf.Feeds[0].Quota.OverflowDigest = telegram.DigestConfig{EveryItems: 1}
+	_itemSender(ctx, cnf, store, dryRun, newSenderState(), zap.NewNop())
+	require.NoError(t, dryRun.Close())
+
+	ready, err = store.GetItemsReadyToSend(ctx, 0)
+	require.NoError(t, err)
+	assert.Empty(t, ready)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	var texts []string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var msg telegram.DryRunMessage
+		require.NoError(t, json.Unmarshal([]byte(line), &msg))
+		texts = append(texts, msg.Text)
+	}
+	require.Len(t, texts, 4)
+	assert.Contains(t, texts[0], "Title busy-1")
+	assert.Contains(t, texts[1], "Title quiet-1")
+	assert.Contains(t, texts[2], "Title busy-2")
+	assert.Contains(t, texts[3], "<b>Digest: Busy</b>")
+	assert.Contains(t, texts[3], "Title busy-3")
+}
+
+func TestItemSender_MinInterval(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	store, err := backend.Open(ctx, storage.Config{DSN: "file:" + filepath.Join(dir, "data.db")})
+	require.NoError(t, err)
+	defer store.Close()
+	require.NoError(t, store.Migrate(ctx))
+
+	published := time.Now().UTC()
+	for _, id := range []string{"first", "second"} {
+		require.NoError(t, store.InsertItem(ctx, &feed.FeedItem{
+			ID:          id,
+			FeedURL:     "https://example.com/rss/" + id,
+			FeedTitle:   "Test Feed",
+			Title:       "Title " + id,
+			Link:        "https://example.com/" + id,
+			PublishedAt: &published,
+		}))
+		published = published.Add(time.Second)
+	}
+
+	dryRun, err := telegram.NewDryRunClient(telegram.DryRunConfig{Enabled: true}, "@test_channel", zap.NewNop())
+	require.NoError(t, err)
+	defer dryRun.Close()
+
+	cnf := &internal.Config{}
+	cnf.Telegram.ChannelName = "@test_channel"
+	cnf.Telegram.MinInterval = time.Hour
+
+	state := newSenderState()
+	_itemSender(ctx, cnf, store, dryRun, state, zap.NewNop())
+
+	ready, err := store.GetItemsReadyToSend(ctx, 0)
+	require.NoError(t, err)
+	require.Len(t, ready, 1)
+	assert.Equal(t, "second", ready[0].ID)
+
+	// интервал помнится между проходами
+	_itemSender(ctx, cnf, store, dryRun, state, zap.NewNop())
+	ready, err = store.GetItemsReadyToSend(ctx, 0)
+	require.NoError(t, err)
+	assert.Len(t, ready, 1)
+}
+
+func TestItemSender_QuietHours(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	store, err := backend.Open(ctx, storage.Config{DSN: "file:" + filepath.Join(dir, "data.db")})
+	require.NoError(t, err)
+	defer store.Close()
+	require.NoError(t, store.Migrate(ctx))
+
+	published := time.Now().UTC()
+	for _, id := range []string{"first", "second"} {
+		require.NoError(t, store.InsertItem(ctx, &feed.FeedItem{
+			ID:          id,
+			FeedURL:     "https://example.com/rss",
+			FeedTitle:   "Test Feed",
+			Title:       "Title " + id,
+			Link:        "https://example.com/" + id,
+			PublishedAt: &published,
+		}))
+		published = published.Add(time.Second)
+	}
+
+	path := filepath.Join(dir, "messages.jsonl")
+	dryRun, err := telegram.NewDryRunClient(telegram.DryRunConfig{Enabled: true, File: path}, "@test_channel", zap.NewNop())
+	require.NoError(t, err)
+
+	cnf := &internal.Config{}
+	cnf.Telegram.ChannelName = "@test_channel"
+	cnf.Telegram.SilentMode = telegram.SilentModeConfig{
+		// весь день, каждый день
+		Windows: []telegram.SilentWindow{{Start: "00:00:00", Finish: "00:00:00"}},
+		Mode:    telegram.SilentModeDefer,
+		Release: telegram.SilentReleaseDigest,
+	}
+
+	state := newSenderState()
+	_itemSender(ctx, cnf, store, dryRun, state, zap.NewNop())
+
+	ready, err := store.GetItemsReadyToSend(ctx, 0)
+	require.NoError(t, err)
+	assert.Len(t, ready, 2)
+
+	// окно закончилось: накопленное уходит одним дайджестом
+	cnf.Telegram.SilentMode.Windows = nil
+	_itemSender(ctx, cnf, store, dryRun, state, zap.NewNop())
+	require.NoError(t, dryRun.Close())
+
+	ready, err = store.GetItemsReadyToSend(ctx, 0)
+	require.NoError(t, err)
+	assert.Empty(t, ready)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 1)
+
+	var msg telegram.DryRunMessage
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &msg))
+	assert.Contains(t, msg.Text, "<b>Digest</b>")
+	assert.Contains(t, msg.Text, "Title first")
+	assert.Contains(t, msg.Text, "Title second")
+	assert.Empty(t, state.held)
+}
+
+func TestFeedConfigs_Get(t *testing.T) {
+	cnf := &internal.Config{Feeds: []internal.FeedConfig{{Name: "Blog", URL: "https://example.com/rss"}}}
+
+	var feeds feedConfigs
+	first := feeds.get(cnf, zap.NewNop())
+	require.Len(t, first, 1)
+
+	// тот же снимок - те же собранные настройки
+	assert.Same(t, &first[0], &feeds.get(cnf, zap.NewNop())[0])
+
+	// новый снимок собирается заново
+	next := *cnf
+	second := feeds.get(&next, zap.NewNop())
+	require.Len(t, second, 1)
+	assert.NotSame(t, &first[0], &second[0])
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"rssgram/internal/storage"
+	"rssgram/internal/storage/backend"
+
+	"go.uber.org/zap"
+)
+
+func parseSearchTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// newSearchQuery собирает запрос из строковых параметров CLI или HTTP.
+func newSearchQuery(q, feedName, from, to, sent, limit string) (storage.SearchQuery, error) {
+	query := storage.SearchQuery{
+		Query: q,
+		Feed:  feedName,
+	}
+
+	var err error
+
+	if query.From, err = parseSearchTime(from); err != nil {
+		return query, fmt.Errorf("invalid from %q: %w", from, err)
+	}
+	if query.To, err = parseSearchTime(to); err != nil {
+		return query, fmt.Errorf("invalid to %q: %w", to, err)
+	}
+
+	if sent != "" {
+		isSent, err := strconv.ParseBool(sent)
+		if err != nil {
+			return query, fmt.Errorf("invalid sent %q: %w", sent, err)
+		}
+		query.IsSent = &isSent
+	}
+
+	if limit != "" {
+		if query.Limit, err = strconv.Atoi(limit); err != nil {
+			return query, fmt.Errorf("invalid limit %q: %w", limit, err)
+		}
+	}
+
+	return query, nil
+}
+
+func (c *cli) search(ctx context.Context, args []string) error {
+	fs := c.flagSet("search")
+	feedName := fs.String("feed", "", "feed URL or name")
+	from := fs.String("from", "", "published at or after (2006-01-02 or RFC 3339)")
+	to := fs.String("to", "", "published before (2006-01-02 or RFC 3339)")
+	sent := fs.String("sent", "", "filter by sent status (true/false)")
+	limit := fs.String("limit", "", "max results (default 20)")
+	asJSON := fs.Bool("json", false, "print results as JSON")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	query, err := newSearchQuery(strings.Join(fs.Args(), " "), *feedName, *from, *to, *sent, *limit)
+	if err != nil {
+		return err
+	}
+
+	store, _, err := c.openStorage(ctx)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	if !*asJSON {
+		query.HighlightStart, query.HighlightEnd = "\033[1m", "\033[0m"
+	}
+
+	results, err := store.Search(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		return writeJSON(c.stdout, results)
+	}
+
+	for _, r := range results {
+		status := "pending"
+		if r.IsSent {
+			status = "sent"
+		}
+		fmt.Fprintf(c.stdout, "%s  [%s] %s (%.2f)\n", r.PublishedAt.Format(time.DateTime), r.FeedTitle, status, r.Score)
+		fmt.Fprintf(c.stdout, "  %s\n", r.TitleSnippet)
+		if r.DescriptionSnippet != "" {
+			fmt.Fprintf(c.stdout, "  %s\n", r.DescriptionSnippet)
+		}
+		fmt.Fprintf(c.stdout, "  %s\n\n", r.Link)
+	}
+
+	return nil
+}
+
+// writeJSON печатает результат команды с флагом -json.
+func writeJSON(out io.Writer, v any) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func searchHandler(store backend.Storage, logger *zap.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params := r.URL.Query()
+
+		query, err := newSearchQuery(
+			params.Get("q"),
+			params.Get("feed"),
+			params.Get("from"),
+			params.Get("to"),
+			params.Get("sent"),
+			params.Get("limit"),
+		)
+		if err == nil && strings.TrimSpace(query.Query) == "" {
+			err = fmt.Errorf("parameter q is required")
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		results, err := store.Search(r.Context(), query)
+		if err != nil {
+			logger.Error("failed to search items", zap.Error(err))
+			http.Error(w, "search failed", http.StatusInternalServerError)
+			return
+		}
+
+		if results == nil {
+			results = []storage.SearchResult{}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(results)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"rssgram/internal/feed"
+	"rssgram/internal/storage"
+	"rssgram/internal/storage/backend"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestNewSearchQuery(t *testing.T) {
+	query, err := newSearchQuery("linux", "Opennet", "2024-05-01", "2024-06-01T00:00:00Z", "false", "5")
+	require.NoError(t, err)
+
+	assert.Equal(t, "linux", query.Query)
+	assert.Equal(t, "Opennet", query.Feed)
+	assert.Equal(t, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), query.From)
+	assert.Equal(t, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), query.To)
+	require.NotNil(t, query.IsSent)
+	assert.False(t, *query.IsSent)
+	assert.Equal(t, 5, query.Limit)
+
+	query, err = newSearchQuery("linux", "", "", "", "", "")
+	require.NoError(t, err)
+	assert.Nil(t, query.IsSent)
+	assert.True(t, query.From.IsZero())
+
+	_, err = newSearchQuery("linux", "", "yesterday", "", "", "")
+	assert.Error(t, err)
+
+	_, err = newSearchQuery("linux", "", "", "", "maybe", "")
+	assert.Error(t, err)
+}
+
+func TestSearchHandler(t *testing.T) {
+	ctx := context.Background()
+
+	store, err := backend.Open(ctx, storage.Config{DSN: "file:" + filepath.Join(t.TempDir(), "data.db")})
+	require.NoError(t, err)
+	defer store.Close()
+	require.NoError(t, store.Migrate(ctx))
+
+	publishedAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	require.NoError(t, store.InsertItem(ctx, &feed.FeedItem{
+		ID:          "1",
+		FeedTitle:   "Opennet",
+		Title:       "Выпуск ядра Linux <script>",
+		Link:        "https://example.com/1",
+		Description: "Новый выпуск",
+		PublishedAt: &publishedAt,
+	}))
+
+	handler := searchHandler(store, zap.NewNop())
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/search?q=linux&sent=false", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var results []storage.SearchResult
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &results))
+	require.Len(t, results, 1)
+	assert.Equal(t, "1", results[0].ItemID)
+	// текст новости экранируется, разметка остаётся только у совпадений
+	assert.Contains(t, results[0].TitleSnippet, "<b>Linux</b> &lt;script&gt;")
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/search?q=rust", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, "[]", rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/search", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"rssgram/internal/feed"
+	"rssgram/internal/outputs/telegram"
+)
+
+// sendTest отправляет в канал тестовое сообщение, чтобы проверить токен бота и права в канале.
+func (c *cli) sendTest(ctx context.Context, args []string) error {
+	fs := c.flagSet("send-test")
+	title := fs.String("title", "rssgram test message", "message title")
+	text := fs.String("text", "If you can read this, the bot token and channel are configured correctly.", "message text")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cnf, err := c.loadConfig()
+	if err != nil {
+		return err
+	}
+	// в dry run конфиг проходит проверку без токена и канала, а тестовое сообщение уходит в Telegram
+	if cnf.Telegram.BotToken == "" || cnf.Telegram.ChannelName == "" {
+		return fmt.Errorf("telegram.bot_token and telegram.channel_name are required to send a test message")
+	}
+
+	now := time.Now()
+	item := &feed.FeedItem{
+		ID:          "send-test",
+		FeedTitle:   "rssgram",
+		Title:       *title,
+		Link:        "https://github.com/realwhite/rssgram",
+		Description: *text,
+		PublishedAt: &now,
+	}
+
+	output := telegram.NewTelegramChannelOutput(cnf.Telegram, c.logger, cnf.EnableTags)
+
+	ok, err := output.Push(ctx, item)
+	if err != nil {
+		return fmt.Errorf("failed to send test message: %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("failed to send test message")
+	}
+
+	fmt.Fprintf(c.stdout, "test message sent to %s\n", cnf.Telegram.ChannelName)
+	return nil
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+// version задаётся при сборке: -ldflags "-X main.version=v1.2.3".
+var version = "dev"
+
+func (c *cli) version(ctx context.Context, args []string) error {
+	fs := c.flagSet("version")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	revision := "unknown"
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range info.Settings {
+			if s.Key == "vcs.revision" {
+				revision = s.Value
+			}
+		}
+	}
+
+	fmt.Fprintf(c.stdout, "rssgram %s (%s, %s)\n", version, revision, runtime.Version())
+	return nil
+}
@@ -2,20 +2,56 @@ package internal
 
 import (
 	"os"
+	"time"
 
+	"rssgram/internal/feed"
 	"rssgram/internal/outputs/telegram"
+	"rssgram/internal/storage"
 
 	"gopkg.in/yaml.v3"
 )
 
 type FeedConfig struct {
-	Name            string   `yaml:"name"`
-	URL             string   `yaml:"url"`
-	Type            string   `yaml:"type"`
-	Interval        string   `yaml:"interval"`
-	Key             string   `yaml:"key"`
-	DescriptionType string   `yaml:"description_type"`
-	Tags            []string `yaml:"tags"`
+	Name            string             `yaml:"name"`
+	URL             string             `yaml:"url"`
+	Type            string             `yaml:"type"`
+	Interval        string             `yaml:"interval"`
+	Key             string             `yaml:"key"`
+	DescriptionType string             `yaml:"description_type"`
+	Tags            []string           `yaml:"tags"`
+	Filters         feed.FiltersConfig `yaml:"filters"`
+	Rules           []feed.RoutingRule `yaml:"rules"`
+	// Digest отправляет новости фида дайджестом, важнее digest выхода
+	Digest telegram.DigestConfig `yaml:"digest"`
+	// Quota дополняет общую quota: заданные здесь поля важнее
+	Quota QuotaConfig `yaml:"quota"`
+}
+
+// QuotaConfig ограничивает, сколько новостей фида отправляется за скользящие час и сутки.
+// Новости сверх квоты остаются в очереди до освобождения квоты или, если задан
+// overflow_digest, уходят дайджестом по его расписанию. 0 - без ограничения.
+type QuotaConfig struct {
+	PerHour        int                   `yaml:"per_hour"`
+	PerDay         int                   `yaml:"per_day"`
+	OverflowDigest telegram.DigestConfig `yaml:"overflow_digest"`
+}
+
+// Merge возвращает общую квоту, дополненную заданными полями квоты фида.
+func (c QuotaConfig) Merge(feed QuotaConfig) QuotaConfig {
+	if feed.PerHour != 0 {
+		c.PerHour = feed.PerHour
+	}
+	if feed.PerDay != 0 {
+		c.PerDay = feed.PerDay
+	}
+	if feed.OverflowDigest.Enabled() {
+		c.OverflowDigest = feed.OverflowDigest
+	}
+	return c
+}
+
+func (c QuotaConfig) Enabled() bool {
+	return c.PerHour > 0 || c.PerDay > 0
 }
 
 type MetricsConfig struct {
@@ -28,20 +64,88 @@ type Config struct {
 	Telegram   telegram.TelegramChannelOutputConfig `yaml:"telegram"`
 	EnableTags bool                                 `yaml:"enable_tags"`
 	Metrics    MetricsConfig                        `yaml:"metrics"`
+	Database   storage.Config                       `yaml:"database"`
+	Retention  storage.RetentionConfig              `yaml:"retention"`
+	DryRun     telegram.DryRunConfig                `yaml:"dry_run"`
+	// Outputs - дополнительные каналы для правил маршрутизации
+	Outputs []telegram.OutputConfig `yaml:"outputs"`
+	// Filters применяются ко всем фидам вместе с filters самого фида
+	Filters feed.FiltersConfig `yaml:"filters"`
+	// Rules проверяются после rules самого фида
+	Rules []feed.RoutingRule `yaml:"rules"`
+	Dedup feed.DedupConfig   `yaml:"dedup"`
+	// Enrich - загрузка страниц новостей для description_type link и fulltext
+	Enrich feed.EnrichConfig `yaml:"enrich"`
+	// Quota - квота по умолчанию для каждого фида
+	Quota QuotaConfig `yaml:"quota"`
+	Log   LogConfig   `yaml:"log"`
+
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+
+	// source - разобранный YAML, по нему Validate находит номера строк
+	source *yaml.Node
+	// loadErrors - проблемы подстановки переменных и чтения *_file, Validate возвращает их вместе с остальными
+	loadErrors ValidationErrors
 }
 
+const defaultShutdownTimeout = 30 * time.Second
+
+// GetShutdownTimeout - сколько ждать завершения отправки и записи в базу при остановке.
+func (c *Config) GetShutdownTimeout() time.Duration {
+	if c.ShutdownTimeout <= 0 {
+		return defaultShutdownTimeout
+	}
+	return c.ShutdownTimeout
+}
+
+// OutputNames - выходы, на которые могут ссылаться правила маршрутизации.
+func (c *Config) OutputNames() []string {
+	names := []string{feed.DefaultOutput}
+	for _, o := range c.Outputs {
+		names = append(names, o.Name)
+	}
+	return names
+}
+
+const DefaultConfigPath = "config.yaml"
+
 func ParseConfig() (*Config, error) {
+	return ParseConfigFile(DefaultConfigPath)
+}
+
+// ParseConfigFile читает конфиг, подставляя ${NAME} из окружения процесса.
+func ParseConfigFile(path string) (*Config, error) {
+	return parseConfigFile(path, os.LookupEnv)
+}
+
+func parseConfigFile(path string, lookupEnv func(string) (string, bool)) (*Config, error) {
 	var cnf Config
 
-	cnfBytes, err := os.ReadFile("config.yaml")
+	cnfBytes, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
 
-	err = yaml.Unmarshal(cnfBytes, &cnf)
+	var node yaml.Node
+	err = yaml.Unmarshal(cnfBytes, &node)
 	if err != nil {
 		return nil, err
 	}
 
+	v := &validator{root: &node}
+	expandEnv(&node, lookupEnv, v)
+
+	// пустой файл даёт пустой узел, Decode на нём не нужен
+	if len(node.Content) > 0 {
+		err = node.Decode(&cnf)
+		if err != nil {
+			return nil, err
+		}
+	}
+	cnf.source = &node
+
+	cnf.resolveSecretFiles(v)
+	cnf.loadErrors = v.errs
+
 	return &cnf, nil
 }
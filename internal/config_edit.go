@@ -0,0 +1,83 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// feedEntry - фид в том виде, в каком он дописывается в файл: без пустых полей.
+type feedEntry struct {
+	Name            string   `yaml:"name"`
+	URL             string   `yaml:"url"`
+	Type            string   `yaml:"type,omitempty"`
+	Interval        string   `yaml:"interval,omitempty"`
+	Key             string   `yaml:"key,omitempty"`
+	DescriptionType string   `yaml:"description_type,omitempty"`
+	Tags            []string `yaml:"tags,omitempty,flow"`
+}
+
+// AppendFeeds дописывает фиды в конец секции feeds YAML-конфига.
+// Правка идёт через yaml.Node, поэтому комментарии и порядок ключей сохраняются.
+func AppendFeeds(data []byte, feeds []FeedConfig) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("config root is not a mapping")
+	}
+
+	var list *yaml.Node
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == "feeds" {
+			list = root.Content[i+1]
+			break
+		}
+	}
+	if list == nil || list.Kind == yaml.ScalarNode && list.Tag == "!!null" {
+		if list == nil {
+			list = &yaml.Node{}
+			root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "feeds"}, list)
+		}
+		*list = yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	}
+	if list.Kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("feeds is not a list")
+	}
+
+	for _, f := range feeds {
+		var node yaml.Node
+		entry := feedEntry{
+			Name:            f.Name,
+			URL:             f.URL,
+			Type:            f.Type,
+			Interval:        f.Interval,
+			Key:             f.Key,
+			DescriptionType: f.DescriptionType,
+			Tags:            f.Tags,
+		}
+		if err := node.Encode(entry); err != nil {
+			return nil, fmt.Errorf("failed to encode feed %s: %w", f.URL, err)
+		}
+		list.Content = append(list.Content, &node)
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to encode config: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode config: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,99 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppendFeeds(t *testing.T) {
+	feeds := []FeedConfig{
+		{Name: "LWN", URL: "https://lwn.net/headlines/rss", Tags: []string{"it", "linux"}},
+		{Name: "Go Blog", URL: "https://go.dev/blog/feed.atom", Interval: "1h"},
+	}
+
+	tests := []struct {
+		name     string
+		config   string
+		expected string
+	}{
+		{
+			name: "append to existing list",
+			config: `# telegram settings
+telegram:
+  channel_name: "@test_channel" # channel
+feeds:
+  - name: First
+    url: https://example.com/rss
+enable_tags: true
+`,
+			expected: `# telegram settings
+telegram:
+  channel_name: "@test_channel" # channel
+feeds:
+  - name: First
+    url: https://example.com/rss
+  - name: LWN
+    url: https://lwn.net/headlines/rss
+    tags: [it, linux]
+  - name: Go Blog
+    url: https://go.dev/blog/feed.atom
+    interval: 1h
+enable_tags: true
+`,
+		},
+		{
+			name: "no feeds key",
+			config: `telegram:
+  channel_name: "@test_channel"
+`,
+			expected: `telegram:
+  channel_name: "@test_channel"
+feeds:
+  - name: LWN
+    url: https://lwn.net/headlines/rss
+    tags: [it, linux]
+  - name: Go Blog
+    url: https://go.dev/blog/feed.atom
+    interval: 1h
+`,
+		},
+		{
+			name: "empty feeds",
+			config: `feeds:
+`,
+			expected: `feeds:
+  - name: LWN
+    url: https://lwn.net/headlines/rss
+    tags: [it, linux]
+  - name: Go Blog
+    url: https://go.dev/blog/feed.atom
+    interval: 1h
+`,
+		},
+		{
+			name:   "empty file",
+			config: "",
+			expected: `feeds:
+  - name: LWN
+    url: https://lwn.net/headlines/rss
+    tags: [it, linux]
+  - name: Go Blog
+    url: https://go.dev/blog/feed.atom
+    interval: 1h
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := AppendFeeds([]byte(tt.config), feeds)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, string(result))
+		})
+	}
+
+	_, err := AppendFeeds([]byte("feeds: abc\n"), feeds)
+	assert.Error(t, err)
+}
@@ -2,7 +2,9 @@ package internal
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"rssgram/internal/outputs/telegram"
 
@@ -195,8 +197,475 @@ func TestFeedConfig_Validation(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			isValid := tt.feed.URL != "" && (tt.feed.URL == "https://example.com/rss" || tt.feed.URL == "")
-			assert.Equal(t, tt.expectValid, isValid)
+			cnf := &Config{
+				Feeds: []FeedConfig{tt.feed},
+				Telegram: telegram.TelegramChannelOutputConfig{
+					TelegramChannelClientConfig: telegram.TelegramChannelClientConfig{
+						ChannelName: "@test_channel",
+						BotToken:    "test_token",
+					},
+				},
+			}
+			err := cnf.Validate()
+			assert.Equal(t, tt.expectValid, err == nil, err)
 		})
 	}
 }
+
+func TestConfig_GetShutdownTimeout(t *testing.T) {
+	cnf := &Config{}
+	assert.Equal(t, 30*time.Second, cnf.GetShutdownTimeout())
+
+	cnf.ShutdownTimeout = 5 * time.Second
+	assert.Equal(t, 5*time.Second, cnf.GetShutdownTimeout())
+}
+
+func TestConfig_Validate(t *testing.T) {
+	valid := &Config{
+		Feeds: []FeedConfig{{Name: "Test Feed", URL: "https://example.com/rss"}},
+		Telegram: telegram.TelegramChannelOutputConfig{
+			TelegramChannelClientConfig: telegram.TelegramChannelClientConfig{
+				ChannelName: "@test_channel",
+				BotToken:    "test_token",
+			},
+		},
+	}
+	assert.NoError(t, valid.Validate())
+
+	invalid := &Config{
+		Feeds: []FeedConfig{{Name: "Test Feed", URL: "not-a-url"}},
+	}
+	err := invalid.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "telegram.channel_name")
+	assert.Contains(t, err.Error(), "telegram.bot_token")
+	assert.Contains(t, err.Error(), "feeds[0].url")
+}
+
+func TestConfig_Validate_Lines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `telegram:
+  channel_name: "@test_channel"
+  bot_token: "test_token"
+  silent_mode:
+    start: "23:00"
+    timezone: "Mars/Olympus"
+feeds:
+  - url: https://example.com/rss
+  - name: Без адреса
+  - url: https://example.com/rss
+    interval: сутки
+database:
+  driver: mysql
+`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+
+	cnf, err := ParseConfigFile(path)
+	require.NoError(t, err)
+
+	var verrs ValidationErrors
+	require.ErrorAs(t, cnf.Validate(), &verrs)
+
+	expected := []ValidationError{
+		{Path: "telegram.silent_mode", Line: 4},
+		{Path: "telegram.silent_mode.start", Line: 5},
+		{Path: "telegram.silent_mode.timezone", Line: 6},
+		// ключа url нет - строка элемента списка
+		{Path: "feeds[1].url", Line: 9},
+		{Path: "feeds[2].url", Line: 10},
+		{Path: "feeds[2].interval", Line: 11},
+		{Path: "database.driver", Line: 13},
+	}
+	require.Len(t, verrs, len(expected), verrs.Error())
+	for i, e := range expected {
+		assert.Equal(t, e.Path, verrs[i].Path)
+		assert.Equal(t, e.Line, verrs[i].Line, e.Path)
+		assert.NotEmpty(t, verrs[i].Message)
+	}
+}
+
+func TestConfig_Validate_Filters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `telegram:
+  channel_name: "@test_channel"
+  bot_token: "test_token"
+filters:
+  exclude:
+    - keywords: [реклама, promo]
+    - regex: "(unclosed"
+feeds:
+  - url: https://example.com/rss
+    filters:
+      include:
+        - fields: [title, body]
+          keywords: [go]
+      exclude:
+        - name: empty
+`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+
+	cnf, err := ParseConfigFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"реклама", "promo"}, cnf.Filters.Exclude[0].Keywords)
+
+	var verrs ValidationErrors
+	require.ErrorAs(t, cnf.Validate(), &verrs)
+
+	expected := []ValidationError{
+		{Path: "feeds[0].filters.include[0]", Line: 12},
+		{Path: "feeds[0].filters.exclude[0]", Line: 15},
+		{Path: "filters.exclude[1]", Line: 7},
+	}
+	require.Len(t, verrs, len(expected), verrs.Error())
+	for i, e := range expected {
+		assert.Equal(t, e.Path, verrs[i].Path)
+		assert.Equal(t, e.Line, verrs[i].Line, e.Path)
+	}
+	assert.Contains(t, verrs[0].Message, `unknown field "body"`)
+}
+
+func TestConfig_Validate_Rules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `telegram:
+  channel_name: "@test_channel"
+  bot_token: "test_token"
+outputs:
+  - name: security
+    channel_name: "@security"
+  - name: default
+    channel_name: "@other"
+rules:
+  - when: 'item.title contains "CVE"'
+    outputs: [security, default]
+  - when: 'item.title +'
+    drop: true
+feeds:
+  - url: https://example.com/rss
+    rules:
+      - when: '"go" in feed.tags'
+        outputs: [golang]
+`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+
+	cnf, err := ParseConfigFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, cnf.OutputNames(), "security")
+
+	var verrs ValidationErrors
+	require.ErrorAs(t, cnf.Validate(), &verrs)
+
+	expected := []ValidationError{
+		{Path: "outputs[1].name", Line: 7},
+		{Path: "feeds[0].rules[0].outputs[0]", Line: 18},
+		{Path: "rules[1]", Line: 12},
+	}
+	require.Len(t, verrs, len(expected), verrs.Error())
+	for i, e := range expected {
+		assert.Equal(t, e.Path, verrs[i].Path)
+		assert.Equal(t, e.Line, verrs[i].Line, e.Path)
+	}
+	assert.Contains(t, verrs[1].Message, `unknown output "golang"`)
+	assert.Contains(t, verrs[2].Message, "invalid expression")
+}
+
+func TestConfig_Validate_DedupSimilarity(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `telegram:
+  channel_name: "@test_channel"
+  bot_token: "test_token"
+dedup:
+  enabled: true
+  similarity: 85
+feeds:
+  - url: https://example.com/rss
+`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+
+	cnf, err := ParseConfigFile(path)
+	require.NoError(t, err)
+
+	var verrs ValidationErrors
+	require.ErrorAs(t, cnf.Validate(), &verrs)
+	require.Len(t, verrs, 1, verrs.Error())
+	assert.Equal(t, "dedup.similarity", verrs[0].Path)
+	assert.Equal(t, 6, verrs[0].Line)
+}
+
+func TestConfig_Validate_Digest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `telegram:
+  channel_name: "@test_channel"
+  bot_token: "test_token"
+  digest:
+    at: ["09:00", "9pm"]
+    timezone: Europe/Moscow
+outputs:
+  - name: news
+    channel_name: "@news"
+    digest:
+      every: 1h
+feeds:
+  - url: https://example.com/rss
+    digest:
+      every_items: -1
+      timezone: Mars/Olympus
+`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+
+	cnf, err := ParseConfigFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, time.Hour, cnf.Outputs[0].Digest.Every)
+
+	var verrs ValidationErrors
+	require.ErrorAs(t, cnf.Validate(), &verrs)
+
+	expected := []ValidationError{
+		{Path: "telegram.digest.at[1]", Line: 5},
+		{Path: "feeds[0].digest.every_items", Line: 15},
+		{Path: "feeds[0].digest.timezone", Line: 16},
+	}
+	require.Len(t, verrs, len(expected), verrs.Error())
+	for i, e := range expected {
+		assert.Equal(t, e.Path, verrs[i].Path)
+		assert.Equal(t, e.Line, verrs[i].Line, e.Path)
+	}
+}
+
+func TestQuotaConfig_Merge(t *testing.T) {
+	global := QuotaConfig{PerHour: 10, PerDay: 50, OverflowDigest: telegram.DigestConfig{Every: time.Hour}}
+
+	assert.Equal(t, global, global.Merge(QuotaConfig{}))
+
+	merged := global.Merge(QuotaConfig{PerHour: 2, OverflowDigest: telegram.DigestConfig{EveryItems: 5}})
+	assert.Equal(t, 2, merged.PerHour)
+	assert.Equal(t, 50, merged.PerDay)
+	assert.Equal(t, telegram.DigestConfig{EveryItems: 5}, merged.OverflowDigest)
+
+	assert.False(t, QuotaConfig{}.Enabled())
+	assert.True(t, QuotaConfig{PerDay: 1}.Enabled())
+}
+
+func TestConfig_Validate_Quota(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `telegram:
+  channel_name: "@test_channel"
+  bot_token: "test_token"
+  min_interval: -1m
+quota:
+  per_hour: 5
+feeds:
+  - url: https://example.com/rss
+    quota:
+      per_day: -1
+      overflow_digest:
+        at: ["25:00"]
+`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+
+	cnf, err := ParseConfigFile(path)
+	require.NoError(t, err)
+
+	var verrs ValidationErrors
+	require.ErrorAs(t, cnf.Validate(), &verrs)
+
+	expected := []ValidationError{
+		{Path: "telegram.min_interval", Line: 4},
+		{Path: "feeds[0].quota.per_day", Line: 10},
+		{Path: "feeds[0].quota.overflow_digest.at[0]", Line: 12},
+	}
+	require.Len(t, verrs, len(expected), verrs.Error())
+	for i, e := range expected {
+		assert.Equal(t, e.Path, verrs[i].Path)
+		assert.Equal(t, e.Line, verrs[i].Line, e.Path)
+	}
+}
+
+func TestConfig_Validate_SilentMode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `telegram:
+  channel_name: "@test_channel"
+  bot_token: "test_token"
+  silent_mode:
+    mode: later
+    release: digest
+    windows:
+      - days: [sat, sun]
+        start: "00:00:00"
+        finish: "00:00:00"
+      - days: [weekend]
+        start: "23:00:00"
+        finish: "08:00:00"
+outputs:
+  - name: security
+    channel_name: "@security"
+    silent_mode:
+      release: burst
+feeds:
+  - url: https://example.com/rss
+`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+
+	cnf, err := ParseConfigFile(path)
+	require.NoError(t, err)
+
+	var verrs ValidationErrors
+	require.ErrorAs(t, cnf.Validate(), &verrs)
+
+	expected := []ValidationError{
+		{Path: "telegram.silent_mode.windows[1]", Line: 11},
+		{Path: "telegram.silent_mode.mode", Line: 5},
+		{Path: "outputs[0].silent_mode.release", Line: 18},
+		{Path: "outputs[0].silent_mode", Line: 17},
+	}
+	require.Len(t, verrs, len(expected), verrs.Error())
+	for i, e := range expected {
+		assert.Equal(t, e.Path, verrs[i].Path)
+		assert.Equal(t, e.Line, verrs[i].Line, e.Path)
+	}
+}
+
+func TestConfig_Validate_SilentModeWithoutWindows(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `telegram:
+  channel_name: "@test_channel"
+  bot_token: "test_token"
+  silent_mode:
+    mode: defer
+    release: digest
+outputs:
+  - name: security
+    channel_name: "@security"
+    min_interval: 1m
+    silent_mode:
+      mode: defer
+      windows:
+        - days: [sat, sun]
+  - name: news
+    channel_name: "@news"
+    min_interval: 1m
+    silent_mode:
+      mode: defer
+      start: "23:00:00"
+      finish: "08:00:00"
+feeds:
+  - url: https://example.com/rss
+`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+
+	cnf, err := ParseConfigFile(path)
+	require.NoError(t, err)
+
+	var verrs ValidationErrors
+	require.ErrorAs(t, cnf.Validate(), &verrs)
+
+	expected := []ValidationError{
+		{Path: "telegram.silent_mode", Line: 4},
+		{Path: "outputs[0].silent_mode.windows[0]", Line: 14},
+	}
+	require.Len(t, verrs, len(expected), verrs.Error())
+	for i, e := range expected {
+		assert.Equal(t, e.Path, verrs[i].Path)
+		assert.Equal(t, e.Line, verrs[i].Line, e.Path)
+	}
+}
+
+func TestConfig_Validate_GradualRelease(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `telegram:
+  channel_name: "@test_channel"
+  bot_token: "test_token"
+  silent_mode:
+    mode: defer
+    release: gradual
+    start: "23:00:00"
+    finish: "08:00:00"
+outputs:
+  - name: inherited
+    channel_name: "@inherited"
+  - name: spaced
+    channel_name: "@spaced"
+    min_interval: 1m
+  - name: digest
+    channel_name: "@digest"
+    silent_mode:
+      mode: defer
+      release: digest
+      start: "23:00:00"
+      finish: "08:00:00"
+feeds:
+  - url: https://example.com/rss
+`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+
+	cnf, err := ParseConfigFile(path)
+	require.NoError(t, err)
+
+	var verrs ValidationErrors
+	require.ErrorAs(t, cnf.Validate(), &verrs)
+
+	// без min_interval накопленное за тихие часы ушло бы разом; silent_mode выход наследует, интервал - нет
+	expected := []ValidationError{
+		{Path: "telegram.min_interval", Line: 1},
+		{Path: "outputs[0].min_interval", Line: 10},
+	}
+	require.Len(t, verrs, len(expected), verrs.Error())
+	for i, e := range expected {
+		assert.Equal(t, e.Path, verrs[i].Path)
+		assert.Equal(t, e.Line, verrs[i].Line, e.Path)
+	}
+}
+
+func TestConfig_Validate_DryRun(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `outputs:
+  - name: security
+feeds:
+  - url: https://example.com/rss
+`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+
+	cnf, err := ParseConfigFile(path)
+	require.NoError(t, err)
+
+	var verrs ValidationErrors
+	require.ErrorAs(t, cnf.Validate(), &verrs)
+	assert.Contains(t, verrs.Error(), "telegram.bot_token")
+	assert.Contains(t, verrs.Error(), "telegram.channel_name")
+	assert.Contains(t, verrs.Error(), "outputs[0].channel_name")
+
+	// без секретов и каналов конфиг годится для dry run
+	cnf.DryRun.Enabled = true
+	assert.NoError(t, cnf.Validate())
+}
+
+func TestConfig_Validate_Enrich(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `telegram:
+  channel_name: "@test_channel"
+  bot_token: "test_token"
+enrich:
+  cache_ttl: -1h
+  respect_robots: true
+  host_delay: -2s
+feeds:
+  - url: https://example.com/rss
+    description_type: fulltext
+`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+
+	cnf, err := ParseConfigFile(path)
+	require.NoError(t, err)
+
+	var verrs ValidationErrors
+	require.ErrorAs(t, cnf.Validate(), &verrs)
+
+	expected := []ValidationError{
+		{Path: "enrich.cache_ttl", Line: 5},
+		{Path: "enrich.host_delay", Line: 7},
+	}
+	require.Len(t, verrs, len(expected), verrs.Error())
+	for i, e := range expected {
+		assert.Equal(t, e.Path, verrs[i].Path)
+		assert.Equal(t, e.Line, verrs[i].Line, e.Path)
+	}
+}
@@ -0,0 +1,98 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"time"
+)
+
+// EnvPrefix - префикс переменных окружения, которые переопределяют значения из файла.
+const EnvPrefix = "RSSGRAM_"
+
+// envOverrides - переменные RSSGRAM_* и поля конфига, которые они заменяют.
+var envOverrides = []struct {
+	name  string
+	apply func(c *Config, value string) error
+}{
+	{"DB_DRIVER", func(c *Config, v string) error { c.Database.Driver = v; return nil }},
+	{"DB", func(c *Config, v string) error { c.Database.DSN = v; return nil }},
+	{"TELEGRAM_CHANNEL", func(c *Config, v string) error { c.Telegram.ChannelName = v; return nil }},
+	{"TELEGRAM_BOT_TOKEN", func(c *Config, v string) error { c.Telegram.BotToken = v; return nil }},
+	{"TELEGRAM_BOT_TOKEN_FILE", func(c *Config, v string) (err error) { c.Telegram.BotToken, err = readSecretFile(v); return }},
+	{"DB_FILE", func(c *Config, v string) (err error) { c.Database.DSN, err = readSecretFile(v); return }},
+	{"METRICS_ENABLED", func(c *Config, v string) (err error) { c.Metrics.Enabled, err = strconv.ParseBool(v); return }},
+	{"METRICS_PORT", func(c *Config, v string) (err error) { c.Metrics.Port, err = strconv.Atoi(v); return }},
+	{"DRY_RUN", func(c *Config, v string) (err error) { c.DryRun.Enabled, err = strconv.ParseBool(v); return }},
+	{"DRY_RUN_FILE", func(c *Config, v string) error { c.DryRun.File = v; return nil }},
+	{"LOG_LEVEL", func(c *Config, v string) error { c.Log.Level = v; return nil }},
+	{"LOG_FORMAT", func(c *Config, v string) error { c.Log.Format = v; return nil }},
+	{"SHUTDOWN_TIMEOUT", func(c *Config, v string) (err error) { c.ShutdownTimeout, err = time.ParseDuration(v); return }},
+}
+
+// ApplyEnv заменяет значения конфига заданными переменными окружения.
+func (c *Config) ApplyEnv(lookupEnv func(string) (string, bool)) error {
+	for _, o := range envOverrides {
+		value, ok := lookupEnv(EnvPrefix + o.name)
+		if !ok {
+			continue
+		}
+		if err := o.apply(c, value); err != nil {
+			return fmt.Errorf("invalid %s%s %q: %w", EnvPrefix, o.name, value, err)
+		}
+	}
+	return nil
+}
+
+// ConfigSource описывает, откуда берётся конфиг: файл, затем RSSGRAM_*, затем флаги CLI.
+// Тот же порядок применяется при перечитывании конфига.
+type ConfigSource struct {
+	Path string
+	// DSN из флага --db, важнее RSSGRAM_DB и database.dsn
+	DSN string
+	// флаги --dry-run и --dry-run-file
+	DryRun     bool
+	DryRunFile string
+	// по умолчанию os.LookupEnv
+	LookupEnv func(string) (string, bool)
+	// отсутствие файла не ошибка - всё берётся из окружения и флагов
+	Optional bool
+}
+
+func (s ConfigSource) Load() (*Config, error) {
+	path := s.Path
+	if path == "" {
+		path = DefaultConfigPath
+	}
+
+	lookupEnv := s.LookupEnv
+	if lookupEnv == nil {
+		lookupEnv = os.LookupEnv
+	}
+
+	cnf, err := parseConfigFile(path, lookupEnv)
+	if s.Optional && errors.Is(err, fs.ErrNotExist) {
+		cnf, err = &Config{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err = cnf.ApplyEnv(lookupEnv); err != nil {
+		return nil, err
+	}
+
+	if s.DSN != "" {
+		cnf.Database.DSN = s.DSN
+	}
+	if s.DryRun {
+		cnf.DryRun.Enabled = true
+	}
+	if s.DryRunFile != "" {
+		cnf.DryRun.File = s.DryRunFile
+	}
+
+	return cnf, nil
+}
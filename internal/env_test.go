@@ -0,0 +1,72 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"rssgram/internal/outputs/telegram"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mapEnv(env map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}
+}
+
+func TestConfig_ApplyEnv(t *testing.T) {
+	cnf := &Config{}
+	cnf.Telegram.ChannelName = "@from_file"
+
+	err := cnf.ApplyEnv(mapEnv(map[string]string{
+		"RSSGRAM_TELEGRAM_BOT_TOKEN": "token",
+		"RSSGRAM_DB_DRIVER":          "postgres",
+		"RSSGRAM_DB":                 "postgres://localhost/rssgram",
+		"RSSGRAM_METRICS_ENABLED":    "true",
+		"RSSGRAM_METRICS_PORT":       "9090",
+		"RSSGRAM_SHUTDOWN_TIMEOUT":   "10s",
+		"RSSGRAM_DRY_RUN":            "1",
+	}))
+	require.NoError(t, err)
+
+	assert.Equal(t, "@from_file", cnf.Telegram.ChannelName)
+	assert.Equal(t, "token", cnf.Telegram.BotToken)
+	assert.Equal(t, "postgres", cnf.Database.Driver)
+	assert.Equal(t, "postgres://localhost/rssgram", cnf.Database.DSN)
+	assert.Equal(t, MetricsConfig{Enabled: true, Port: 9090}, cnf.Metrics)
+	assert.Equal(t, 10*time.Second, cnf.ShutdownTimeout)
+	assert.True(t, cnf.DryRun.Enabled)
+
+	err = cnf.ApplyEnv(mapEnv(map[string]string{"RSSGRAM_METRICS_PORT": "abc"}))
+	assert.ErrorContains(t, err, "RSSGRAM_METRICS_PORT")
+}
+
+func TestConfigSource_Load(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("database:\n  dsn: file:from-file.db\n"), 0644))
+
+	env := mapEnv(map[string]string{"RSSGRAM_DB": "file:from-env.db"})
+
+	cnf, err := ConfigSource{Path: path, LookupEnv: env}.Load()
+	require.NoError(t, err)
+	assert.Equal(t, "file:from-env.db", cnf.Database.DSN)
+
+	cnf, err = ConfigSource{Path: path, DSN: "file:from-flag.db", DryRun: true, DryRunFile: "out.jsonl", LookupEnv: env}.Load()
+	require.NoError(t, err)
+	assert.Equal(t, "file:from-flag.db", cnf.Database.DSN)
+	assert.Equal(t, telegram.DryRunConfig{Enabled: true, File: "out.jsonl"}, cnf.DryRun)
+
+	missing := filepath.Join(dir, "missing.yaml")
+	_, err = ConfigSource{Path: missing, LookupEnv: env}.Load()
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	cnf, err = ConfigSource{Path: missing, LookupEnv: env, Optional: true}.Load()
+	require.NoError(t, err)
+	assert.Equal(t, "file:from-env.db", cnf.Database.DSN)
+}
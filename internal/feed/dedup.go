@@ -0,0 +1,74 @@
+package feed
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// DedupConfig - пропуск новостей, которые уже пришли из другого фида (агрегатора, зеркала).
+// Новости сравниваются по NormalizeURL от канонического адреса или ссылки, а если задан
+// Similarity - ещё и по отпечаткам заголовка и описания (Fingerprint).
+type DedupConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// сколько после отправки новость считается дублем; неотправленные - всегда
+	Window time.Duration `yaml:"window"`
+	// дописывать к ещё не отправленной новости названия других фидов, где она встретилась
+	AnnotateSources bool `yaml:"annotate_sources"`
+	// доля совпадающих битов отпечатков, с которой новость считается почти дублем; 0 - не сравнивать
+	Similarity float64 `yaml:"similarity"`
+}
+
+func (c DedupConfig) WithDefaults() DedupConfig {
+	if c.Window <= 0 {
+		c.Window = 72 * time.Hour
+	}
+	return c
+}
+
+// FilterReasonDuplicate - новость уже сохранена из другого фида.
+const FilterReasonDuplicate = "duplicate"
+
+// FilterReasonNearDuplicate - похожая новость уже сохранена, см. DedupConfig.Similarity.
+const FilterReasonNearDuplicate = "near_duplicate"
+
+// trackingParams - параметры ссылок, которые не меняют страницу, а только помечают источник перехода.
+var trackingParams = []string{"fbclid", "gclid", "yclid", "mc_cid", "mc_eid", "ref_src"}
+
+func isTrackingParam(name string) bool {
+	name = strings.ToLower(name)
+	return strings.HasPrefix(name, "utm_") || slices.Contains(trackingParams, name)
+}
+
+// NormalizeURL приводит ссылку на новость к виду, одинаковому для всех фидов, где она встречается:
+// без схемы, www., фрагмента, завершающего слэша и меток utm_*, с отсортированными параметрами.
+// Результат - ключ для поиска дублей, а не рабочий адрес. Не разобранная ссылка возвращается как есть.
+func NormalizeURL(link string) string {
+	link = strings.TrimSpace(link)
+	u, err := url.Parse(link)
+	if err != nil || u.Host == "" {
+		return link
+	}
+
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	if port := u.Port(); port != "" && port != "80" && port != "443" {
+		host += ":" + port
+	}
+
+	path := strings.TrimRight(u.EscapedPath(), "/")
+
+	query := u.Query()
+	for name := range query {
+		if isTrackingParam(name) {
+			query.Del(name)
+		}
+	}
+
+	normalized := host + path
+	// Encode сортирует параметры по имени
+	if encoded := query.Encode(); encoded != "" {
+		normalized += "?" + encoded
+	}
+	return normalized
+}
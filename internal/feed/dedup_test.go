@@ -0,0 +1,144 @@
+package feed
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"rssgram/internal/storage"
+
+	"github.com/mmcdole/gofeed"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		link     string
+		expected string
+	}{
+		{"https://example.com/post", "example.com/post"},
+		{"http://WWW.Example.com/post/", "example.com/post"},
+		{"https://example.com/post?utm_source=rss&utm_medium=feed#comments", "example.com/post"},
+		{"https://example.com/post?id=2&fbclid=abc&a=1", "example.com/post?a=1&id=2"},
+		{"https://example.com:443/", "example.com"},
+		{"https://example.com:8080/post", "example.com:8080/post"},
+		{"https://example.com/%D0%BD%D0%BE%D0%B2%D0%BE%D1%81%D1%82%D1%8C", "example.com/%D0%BD%D0%BE%D0%B2%D0%BE%D1%81%D1%82%D1%8C"},
+		{"/relative/path", "/relative/path"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.link, func(t *testing.T) {
+			assert.Equal(t, tt.expected, NormalizeURL(tt.link))
+		})
+	}
+}
+
+// TestManager_ProcessFeed_Dedup проверяет, что новости, уже сохранённые из другого фида, пропускаются,
+// а к ожидающей отправки дописывается источник.
+func TestManager_ProcessFeed_Dedup(t *testing.T) {
+	mockRepo := &MockRepo{}
+	manager := NewManager(mockRepo)
+
+	at := func(hour int) *time.Time {
+		t := time.Date(2024, 5, 1, hour, 0, 0, 0, time.UTC)
+		return &t
+	}
+
+	manager.parserFactory = func() gofeedParser {
+		return &staticParser{feed: &gofeed.Feed{
+			Title: "Aggregator",
+			Items: []*gofeed.Item{
+				{Title: "Queued elsewhere", Link: "https://www.origin.com/queued/?utm_source=agg", PublishedParsed: at(14)},
+				{Title: "Sent elsewhere", Link: "https://origin.com/sent", PublishedParsed: at(13)},
+				{Title: "Fresh", Link: "https://origin.com/fresh", PublishedParsed: at(12)},
+				{Title: "Fresh again", Link: "https://origin.com/fresh#top", PublishedParsed: at(11)},
+			},
+		}}
+	}
+
+	feedConfig := FeedConfig{
+		Name:  "Aggregator",
+		URL:   "https://aggregator.com/rss",
+		Dedup: DedupConfig{Enabled: true, AnnotateSources: true},
+	}
+
+	mockRepo.On("GetFeedByURL", mock.Anything, feedConfig.URL).
+		Return(&storage.StoredFeed{URL: feedConfig.URL, LastPosted: *at(10)}, nil)
+	mockRepo.On("FindDuplicateItem", mock.Anything, "origin.com/queued", mock.Anything).
+		Return(&storage.StoredItem{ID: "queued", FeedURL: "https://origin.com/rss", Status: storage.ItemStatusPending}, nil)
+	mockRepo.On("FindDuplicateItem", mock.Anything, "origin.com/sent", mock.Anything).
+		Return(&storage.StoredItem{ID: "sent", FeedURL: "https://origin.com/rss", Status: storage.ItemStatusSent}, nil)
+	mockRepo.On("FindDuplicateItem", mock.Anything, "origin.com/fresh", mock.Anything).Return(nil, nil).Once()
+	mockRepo.On("AddItemSource", mock.Anything, "queued", "Aggregator").Return(nil).Once()
+	mockRepo.On("InsertItem", mock.Anything, mock.MatchedBy(func(item *FeedItem) bool {
+		return item.Title == "Fresh" && item.NormalizedLink == "origin.com/fresh"
+	})).Return(nil).Once()
+	mockRepo.On("UpsertFeed", mock.Anything, feedConfig.URL, mock.Anything, *at(14)).Return(nil)
+
+	err := manager.ProcessFeed(context.Background(), feedConfig, zap.NewNop())
+	require.NoError(t, err)
+
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNumberOfCalls(t, "InsertItem", 1)
+	mockRepo.AssertNumberOfCalls(t, "AddItemSource", 1)
+}
+
+// TestManager_ProcessFeed_NearDuplicates проверяет, что пересказ уже сохранённой новости из другого фида
+// и повтор внутри фида пропускаются по отпечаткам, а похожая только темой новость сохраняется.
+func TestManager_ProcessFeed_NearDuplicates(t *testing.T) {
+	mockRepo := &MockRepo{}
+	manager := NewManager(mockRepo)
+
+	at := func(hour int) *time.Time {
+		t := time.Date(2024, 5, 1, hour, 0, 0, 0, time.UTC)
+		return &t
+	}
+
+	manager.parserFactory = func() gofeedParser {
+		return &staticParser{feed: &gofeed.Feed{
+			Title: "Aggregator",
+			Items: []*gofeed.Item{
+				{
+					Title:           "Банк России сохранил ключевую ставку на уровне 16%",
+					Description:     "Совет директоров Банка России оставил ключевую ставку без изменений.",
+					Link:            "https://aggregator.com/1",
+					PublishedParsed: at(14),
+				},
+				{Title: "Apple unveils new iPad Air with M3 chip", Link: "https://aggregator.com/2", PublishedParsed: at(13)},
+				{Title: "Apple unveils new MacBook Pro with M4 chip", Link: "https://aggregator.com/3", PublishedParsed: at(12)},
+				{Title: "New MacBook Pro with M4 chip unveiled by Apple", Link: "https://aggregator.com/4", PublishedParsed: at(11)},
+			},
+		}}
+	}
+
+	feedConfig := FeedConfig{
+		Name:  "Aggregator",
+		URL:   "https://aggregator.com/rss",
+		Dedup: DedupConfig{Enabled: true, AnnotateSources: true, Similarity: 0.85},
+	}
+
+	mockRepo.On("GetFeedByURL", mock.Anything, feedConfig.URL).
+		Return(&storage.StoredFeed{URL: feedConfig.URL, LastPosted: *at(10)}, nil)
+	mockRepo.On("FindDuplicateItem", mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+	mockRepo.On("ListFingerprints", mock.Anything, mock.Anything).Return([]storage.ItemFingerprint{{
+		ItemID:      "rate",
+		FeedURL:     "https://news.com/rss",
+		Fingerprint: Fingerprint("ЦБ сохранил ключевую ставку на уровне 16%", "Банк России оставил ключевую ставку без изменений."),
+		Status:      storage.ItemStatusPending,
+	}}, nil).Once()
+	mockRepo.On("AddItemSource", mock.Anything, "rate", "Aggregator").Return(nil).Once()
+	mockRepo.On("InsertItem", mock.Anything, mock.MatchedBy(func(item *FeedItem) bool {
+		return item.Fingerprint != 0 && (item.Link == "https://aggregator.com/2" || item.Link == "https://aggregator.com/3")
+	})).Return(nil).Twice()
+	mockRepo.On("UpsertFeed", mock.Anything, feedConfig.URL, mock.Anything, *at(14)).Return(nil)
+
+	err := manager.ProcessFeed(context.Background(), feedConfig, zap.NewNop())
+	require.NoError(t, err)
+
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNumberOfCalls(t, "InsertItem", 2)
+}
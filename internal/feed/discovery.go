@@ -0,0 +1,121 @@
+package feed
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/go-shiori/dom"
+	"golang.org/x/net/html"
+)
+
+// feedLinkTypes - значения type у <link rel="alternate">, которые указывают на фид.
+var feedLinkTypes = []string{
+	"application/rss+xml",
+	"application/atom+xml",
+	"application/feed+json",
+}
+
+// isFeedLink решает, ведёт ли ссылка на фид. application/json так же отдают API и oEmbed,
+// поэтому он принимается только за JSON Feed по title или по адресу .json.
+func isFeedLink(linkType, title string, u *url.URL) bool {
+	if slices.Contains(feedLinkTypes, linkType) {
+		return true
+	}
+	if linkType != "application/json" {
+		return false
+	}
+	return strings.Contains(strings.ToLower(title), "json feed") || strings.HasSuffix(strings.ToLower(u.Path), ".json")
+}
+
+// commonFeedPaths проверяются, если страница не ссылается на фид.
+var commonFeedPaths = []string{"/feed", "/rss.xml", "/atom.xml", "/feed.xml", "/index.xml", "/rss", "/feed.json"}
+
+// DiscoverFeeds возвращает возможные адреса фида сайта в порядке предпочтения:
+// ссылки <link rel="alternate"> со страницы pageURL, затем типичные пути от корня сайта.
+func (p *SiteParser) DiscoverFeeds(ctx context.Context, pageURL string) ([]string, error) {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse url %s: %w", pageURL, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	p.setUA(req)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get content by url %s: %w", pageURL, err)
+	}
+	defer resp.Body.Close()
+
+	var candidates []string
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if resp.StatusCode == http.StatusOK && (mediaType == "text/html" || mediaType == "application/xhtml+xml") {
+		doc, err := html.Parse(io.LimitReader(resp.Body, 5<<20))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse html: %w", err)
+		}
+		// после редиректа относительные ссылки считаются от итогового адреса
+		candidates = feedLinks(doc, resp.Request.URL)
+	}
+
+	for _, path := range commonFeedPaths {
+		u := base.ResolveReference(&url.URL{Path: path}).String()
+		if !slices.Contains(candidates, u) {
+			candidates = append(candidates, u)
+		}
+	}
+
+	return candidates, nil
+}
+
+// feedLinks достаёт адреса фидов из <link rel="alternate">. Фиды комментариев
+// уходят в конец: обычно сайт ссылается и на основной фид, и на них.
+func feedLinks(doc *html.Node, base *url.URL) []string {
+	if b := dom.QuerySelector(doc, "base[href]"); b != nil {
+		if u, err := base.Parse(dom.GetAttribute(b, "href")); err == nil {
+			base = u
+		}
+	}
+
+	var links, comments []string
+	for _, link := range dom.QuerySelectorAll(doc, "link[href]") {
+		rel := strings.Fields(strings.ToLower(dom.GetAttribute(link, "rel")))
+		if !slices.Contains(rel, "alternate") {
+			continue
+		}
+
+		u, err := base.Parse(strings.TrimSpace(dom.GetAttribute(link, "href")))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			continue
+		}
+
+		title := strings.ToLower(dom.GetAttribute(link, "title"))
+		linkType, _, _ := mime.ParseMediaType(dom.GetAttribute(link, "type"))
+		if !isFeedLink(linkType, title, u) {
+			continue
+		}
+
+		href := u.String()
+		if slices.Contains(links, href) || slices.Contains(comments, href) {
+			continue
+		}
+
+		if strings.Contains(title, "comment") || strings.Contains(title, "коммент") || strings.Contains(u.Path, "comments") {
+			comments = append(comments, href)
+		} else {
+			links = append(links, href)
+		}
+	}
+
+	return append(links, comments...)
+}
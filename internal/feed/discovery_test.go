@@ -0,0 +1,143 @@
+package feed
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"rssgram/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"golang.org/x/net/html"
+)
+
+func TestFeedLinks(t *testing.T) {
+	page := `<html><head>
+<link rel="stylesheet" href="/style.css">
+<link rel="alternate" type="application/rss+xml" title="Comments" href="/comments/feed">
+<link rel="alternate" type="application/atom+xml" href="/atom.xml">
+<link rel="Alternate" type="application/rss+xml; charset=utf-8" href="https://cdn.example.com/rss">
+<link rel="alternate" type="application/atom+xml" href="/atom.xml">
+<link rel="alternate" type="text/html" hreflang="en" href="/en/">
+<link rel="alternate" type="application/feed+json" href="javascript:alert(1)">
+<link rel="alternate" type="application/json" href="/wp-json/wp/v2/posts/1">
+<link rel="alternate" type="application/json+oembed" href="/oembed?url=1">
+<link rel="alternate" type="application/json" title="My Blog JSON Feed" href="/feeds/main">
+<link rel="alternate" type="application/json" href="/feed.json">
+</head><body></body></html>`
+
+	doc, err := html.Parse(strings.NewReader(page))
+	require.NoError(t, err)
+
+	base, _ := url.Parse("https://example.com/blog/")
+	assert.Equal(t, []string{
+		"https://example.com/atom.xml",
+		"https://cdn.example.com/rss",
+		"https://example.com/feeds/main",
+		"https://example.com/feed.json",
+		"https://example.com/comments/feed",
+	}, feedLinks(doc, base))
+
+	doc, err = html.Parse(strings.NewReader(`<html><head><base href="https://example.org/news/"><link rel="alternate" type="application/rss+xml" href="rss.xml"></head></html>`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://example.org/news/rss.xml"}, feedLinks(doc, base))
+}
+
+func TestSiteParser_DiscoverFeeds(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, `<html><head><link rel="alternate" type="application/rss+xml" href="/rss.xml"></head></html>`)
+	}))
+	defer server.Close()
+
+	candidates, err := NewSiteParser().DiscoverFeeds(context.Background(), server.URL+"/blog/post")
+	require.NoError(t, err)
+
+	// найденная ссылка первая, типичные пути без повторов
+	assert.Equal(t, []string{
+		server.URL + "/rss.xml",
+		server.URL + "/feed",
+		server.URL + "/atom.xml",
+		server.URL + "/feed.xml",
+		server.URL + "/index.xml",
+		server.URL + "/rss",
+		server.URL + "/feed.json",
+	}, candidates)
+}
+
+const discoveryTestRSS = `<?xml version="1.0"?>
+<rss version="2.0"><channel><title>Blog</title>
+<item><title>Post</title><link>https://example.com/post</link><guid>post</guid><pubDate>Mon, 01 Jan 2024 10:00:00 +0000</pubDate></item>
+</channel></rss>`
+
+func TestManager_ProcessFeed_Discovery(t *testing.T) {
+	var pageHits atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		pageHits.Add(1)
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><head><title>Blog</title><link rel="alternate" type="application/rss+xml" href="/posts/index.xml"></head></html>`)
+	})
+	mux.HandleFunc("/about", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><head><title>About</title></head></html>`)
+	})
+	mux.HandleFunc("/posts/index.xml", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		fmt.Fprint(w, discoveryTestRSS)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	feedConfig := FeedConfig{Name: "Blog", URL: server.URL + "/"}
+	resolved := server.URL + "/posts/index.xml"
+
+	t.Run("new feed", func(t *testing.T) {
+		mockRepo := &MockRepo{}
+		mockRepo.On("GetFeedByURL", mock.Anything, feedConfig.URL).Return(nil, nil)
+		mockRepo.On("UpsertFeed", mock.Anything, feedConfig.URL, mock.Anything, mock.Anything).Return(nil)
+		mockRepo.On("SetFeedResolvedURL", mock.Anything, feedConfig.URL, resolved).Return(nil)
+
+		require.NoError(t, NewManager(mockRepo).ProcessFeed(context.Background(), feedConfig, zap.NewNop()))
+		mockRepo.AssertExpectations(t)
+		assert.EqualValues(t, 2, pageHits.Load())
+	})
+
+	t.Run("resolved url is used", func(t *testing.T) {
+		pageHits.Store(0)
+
+		mockRepo := &MockRepo{}
+		mockRepo.On("GetFeedByURL", mock.Anything, feedConfig.URL).Return(&storage.StoredFeed{
+			URL:         feedConfig.URL,
+			LastPosted:  time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),
+			ResolvedURL: resolved,
+		}, nil)
+		mockRepo.On("UpsertFeed", mock.Anything, feedConfig.URL, mock.Anything, mock.Anything).Return(nil)
+
+		require.NoError(t, NewManager(mockRepo).ProcessFeed(context.Background(), feedConfig, zap.NewNop()))
+		mockRepo.AssertExpectations(t)
+		mockRepo.AssertNotCalled(t, "SetFeedResolvedURL", mock.Anything, mock.Anything, mock.Anything)
+		assert.Zero(t, pageHits.Load())
+	})
+
+	t.Run("page without feed", func(t *testing.T) {
+		mockRepo := &MockRepo{}
+		mockRepo.On("GetFeedByURL", mock.Anything, mock.Anything).Return(nil, nil)
+
+		err := NewManager(mockRepo).ProcessFeed(context.Background(), FeedConfig{URL: server.URL + "/about"}, zap.NewNop())
+		assert.ErrorContains(t, err, "no feed found on the page")
+	})
+}
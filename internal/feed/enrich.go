@@ -0,0 +1,69 @@
+package feed
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultUserAgent - User-Agent запросов к robots.txt, если enrich.user_agent не задан.
+// Страницы новостей тогда загружаются с браузерным User-Agent.
+const DefaultUserAgent = RobotsAgent + "/1.0"
+
+// EnrichConfig - как загружаются страницы новостей для description_type link и fulltext.
+type EnrichConfig struct {
+	// сколько помнить результат обогащения страницы
+	CacheTTL time.Duration `yaml:"cache_ttl"`
+	// User-Agent запросов к страницам и robots.txt, его имя продукта ищется в robots.txt;
+	// по умолчанию страницы запрашиваются с браузерным, а robots.txt - с DefaultUserAgent
+	UserAgent string `yaml:"user_agent"`
+	// не загружать страницы, закрытые для RobotsAgent в robots.txt
+	RespectRobots bool `yaml:"respect_robots"`
+	// пауза между запросами к одному хосту
+	HostDelay time.Duration `yaml:"host_delay"`
+}
+
+func (c EnrichConfig) WithDefaults() EnrichConfig {
+	if c.CacheTTL <= 0 {
+		c.CacheTTL = 24 * time.Hour
+	}
+	return c
+}
+
+// hostLimiter разносит запросы к одному хосту по времени. Новости обогащаются параллельно,
+// поэтому каждый запрос сразу занимает своё время, а потом ждёт его. Хосты, пауза которых
+// уже прошла, забываются: следующий запрос к ним всё равно уйдёт сразу.
+type hostLimiter struct {
+	mu   sync.Mutex
+	next map[string]time.Time
+}
+
+func newHostLimiter() *hostLimiter {
+	return &hostLimiter{next: make(map[string]time.Time)}
+}
+
+func (l *hostLimiter) wait(ctx context.Context, host string, delay time.Duration) error {
+	l.mu.Lock()
+	now := time.Now()
+	for h, next := range l.next {
+		if next.Before(now) {
+			delete(l.next, h)
+		}
+	}
+	at := l.next[host]
+	if at.Before(now) {
+		at = now
+	}
+	l.next[host] = at.Add(delay)
+	l.mu.Unlock()
+
+	timer := time.NewTimer(time.Until(at))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
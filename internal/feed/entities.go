@@ -9,7 +9,13 @@ import (
 	"github.com/google/uuid"
 )
 
-const FeedDescriptionTypeLink string = "link"
+const (
+	FeedDescriptionTypeItem string = "item"
+	FeedDescriptionTypeLink string = "link"
+	FeedDescriptionTypeNone string = "none"
+	// FeedDescriptionTypeFulltext - описание заменяется основным текстом статьи со страницы
+	FeedDescriptionTypeFulltext string = "fulltext"
+)
 
 type Feed struct {
 	ID          uuid.UUID              `json:"id"`
@@ -22,20 +28,41 @@ type Feed struct {
 	Metadata    map[string]interface{} `json:"metadata"`
 	Tags        []string               `json:"tags"`
 
+	// ResolvedURL - адрес, с которого фид загружен на самом деле, если URL ведёт на страницу сайта
+	ResolvedURL string `json:"resolved_url,omitempty"`
+
 	StoredLastSavedItem time.Time
 }
 
 type FeedItem struct {
 	ID          string                 `json:"id"`
+	FeedURL     string                 `json:"feed_url"`
 	FeedTitle   string                 `json:"feed_title"`
 	Title       string                 `json:"title"`
 	Link        string                 `json:"link"`
 	ImageURL    string                 `json:"image_url"`
 	Description string                 `json:"description"`
+	Author      string                 `json:"author,omitempty"`
 	PublishedAt *time.Time             `json:"published_at"`
 	UpdatedAt   *time.Time             `json:"updated_at"`
 	Tags        []string               `json:"tags"`
 	Metadata    map[string]interface{} `json:"metadata"`
+
+	// Outputs - выходы, выбранные правилами маршрутизации; пусто - DefaultOutput
+	Outputs []string `json:"outputs,omitempty"`
+	// Silent, если задан, заменяет тихий режим выхода
+	Silent *bool `json:"silent,omitempty"`
+	// SentOutputs - выходы, в которые новость уже отправлена
+	SentOutputs []string `json:"-"`
+
+	// CanonicalURL - канонический адрес страницы новости, если он найден при обогащении
+	CanonicalURL string `json:"canonical_url,omitempty"`
+	// NormalizedLink - ключ поиска одной и той же новости в разных фидах, см. NormalizeURL
+	NormalizedLink string `json:"normalized_link,omitempty"`
+	// ExtraSources - другие фиды, в которых встретилась эта новость
+	ExtraSources []string `json:"extra_sources,omitempty"`
+	// Fingerprint - SimHash заголовка и описания для поиска почти дублей, см. Fingerprint
+	Fingerprint uint64 `json:"fingerprint,omitempty"`
 }
 
 func (fi *FeedItem) GetMetadataJson() (string, error) {
@@ -54,6 +81,27 @@ func (fi *FeedItem) GetTagsJson() (string, error) {
 	return string(bytes), err
 }
 
+func (fi *FeedItem) GetOutputsJson() (string, error) {
+	if len(fi.Outputs) == 0 {
+		return "[]", nil
+	}
+	bytes, err := json.Marshal(fi.Outputs)
+	return string(bytes), err
+}
+
+// GetPublishedAt возвращает время публикации в UTC. Если в фиде его нет,
+// берётся время обновления, а если нет и его - fallback (обычно время получения фида).
+func (fi *FeedItem) GetPublishedAt(fallback time.Time) time.Time {
+	switch {
+	case fi.PublishedAt != nil:
+		return fi.PublishedAt.UTC()
+	case fi.UpdatedAt != nil:
+		return fi.UpdatedAt.UTC()
+	default:
+		return fallback.UTC()
+	}
+}
+
 func NewFeedItem(feedTitle, title, link, imageURL, description string, publishedAt *time.Time, updatedAt *time.Time, tags []string) FeedItem {
 	h := sha256.New()
 	h.Write([]byte(fmt.Sprintf("%s__%s__%s__%s", title, link, description, imageURL)))
@@ -73,9 +121,37 @@ func NewFeedItem(feedTitle, title, link, imageURL, description string, published
 }
 
 type FeedConfig struct {
-	Name            string   `json:"name" yaml:"name"`
-	URL             string   `json:"url" yaml:"url"`
-	Key             string   `json:"key" yaml:"key"`
-	DescriptionType string   `json:"description_type" yaml:"description_type"`
-	Tags            []string `json:"tags" yaml:"tags"`
+	Name            string        `json:"name" yaml:"name"`
+	URL             string        `json:"url" yaml:"url"`
+	Key             string        `json:"key" yaml:"key"`
+	DescriptionType string        `json:"description_type" yaml:"description_type"`
+	Tags            []string      `json:"tags" yaml:"tags"`
+	Filters         FiltersConfig `json:"filters" yaml:"filters"`
+	Rules           []RoutingRule `json:"rules" yaml:"rules"`
+	Dedup           DedupConfig   `json:"dedup" yaml:"dedup"`
+	Enrich          EnrichConfig  `json:"enrich" yaml:"enrich"`
+
+	// заранее собранные фильтры и правила, см. Compile
+	filter *Filter
+	router *Router
+}
+
+// Compile собирает фильтры и правила фида один раз, чтобы проходы по фиду не компилировали
+// регулярные выражения и выражения правил заново. Без Compile они собираются при каждой обработке.
+func (c FeedConfig) Compile() (FeedConfig, error) {
+	if !c.Filters.IsEmpty() {
+		filter, err := NewFilter(c.Filters)
+		if err != nil {
+			return c, fmt.Errorf("invalid filters of feed %s: %w", c.URL, err)
+		}
+		c.filter = filter
+	}
+	if len(c.Rules) > 0 {
+		router, err := NewRouter(c.Rules)
+		if err != nil {
+			return c, fmt.Errorf("invalid rules of feed %s: %w", c.URL, err)
+		}
+		c.router = router
+	}
+	return c, nil
 }
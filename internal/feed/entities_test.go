@@ -195,6 +195,46 @@ func TestFeedItem_GetTagsJson(t *testing.T) {
 	}
 }
 
+func TestFeedItem_GetPublishedAt(t *testing.T) {
+	msk := time.FixedZone("MSK", 3*60*60)
+	published := time.Date(2024, 5, 1, 10, 0, 0, 0, msk)
+	updated := time.Date(2024, 5, 2, 10, 0, 0, 0, msk)
+	fallback := time.Date(2024, 5, 3, 10, 0, 0, 0, msk)
+
+	tests := []struct {
+		name        string
+		publishedAt *time.Time
+		updatedAt   *time.Time
+		expected    time.Time
+	}{
+		{
+			name:        "published",
+			publishedAt: &published,
+			updatedAt:   &updated,
+			expected:    time.Date(2024, 5, 1, 7, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "only updated",
+			updatedAt: &updated,
+			expected:  time.Date(2024, 5, 2, 7, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "no dates",
+			expected: time.Date(2024, 5, 3, 7, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := &FeedItem{PublishedAt: tt.publishedAt, UpdatedAt: tt.updatedAt}
+			result := item.GetPublishedAt(fallback)
+
+			assert.Equal(t, tt.expected, result)
+			assert.Equal(t, time.UTC, result.Location())
+		})
+	}
+}
+
 func TestFeedItem_ID_Consistency(t *testing.T) {
 	// Check that ID is generated consistently for identical data
 	item1 := NewFeedItem(
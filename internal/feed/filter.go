@@ -0,0 +1,226 @@
+package feed
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/microcosm-cc/bluemonday"
+)
+
+// Поля новости, по которым работают фильтры.
+const (
+	FilterFieldTitle       = "title"
+	FilterFieldDescription = "description"
+	FilterFieldLink        = "link"
+	FilterFieldTags        = "tags"
+	FilterFieldAuthor      = "author"
+)
+
+var FilterFields = []string{FilterFieldTitle, FilterFieldDescription, FilterFieldLink, FilterFieldTags, FilterFieldAuthor}
+
+// defaultFilterFields - где искать, если fields не заданы.
+var defaultFilterFields = []string{FilterFieldTitle, FilterFieldDescription}
+
+// FilterRule срабатывает, если в одном из полей найдено любое из keywords или regex.
+// Ключевые слова ищутся целиком, без учёта регистра, если не задан case_sensitive.
+type FilterRule struct {
+	// имя правила в метриках и логах; по умолчанию - слова или regex
+	Name          string   `yaml:"name"`
+	Fields        []string `yaml:"fields"`
+	Keywords      []string `yaml:"keywords"`
+	Regex         string   `yaml:"regex"`
+	CaseSensitive bool     `yaml:"case_sensitive"`
+}
+
+// FiltersConfig - правила отбора новостей. Если include заданы, проходят только новости,
+// подошедшие хотя бы под одно из них; новости, подошедшие под любое exclude, отбрасываются.
+type FiltersConfig struct {
+	Include []FilterRule `yaml:"include"`
+	Exclude []FilterRule `yaml:"exclude"`
+}
+
+// Merge добавляет к глобальным правилам правила фида.
+func (c FiltersConfig) Merge(other FiltersConfig) FiltersConfig {
+	return FiltersConfig{
+		Include: append(slices.Clip(c.Include), other.Include...),
+		Exclude: append(slices.Clip(c.Exclude), other.Exclude...),
+	}
+}
+
+func (c FiltersConfig) IsEmpty() bool {
+	return len(c.Include) == 0 && len(c.Exclude) == 0
+}
+
+// FilterReasonNotIncluded - новость не подошла ни под одно правило include.
+const FilterReasonNotIncluded = "not_included"
+
+type filterRule struct {
+	name   string
+	fields []string
+	re     *regexp.Regexp
+}
+
+// Filter - скомпилированные FiltersConfig.
+type Filter struct {
+	include []filterRule
+	exclude []filterRule
+}
+
+// keywordPattern ищет слово целиком: \b в RE2 знает только ASCII, поэтому
+// границы слова для кириллицы задаются явно и только у букв и цифр на краях слова.
+func keywordPattern(keyword string) string {
+	const boundaryStart = `(?:^|[^\pL\pN_])`
+	const boundaryEnd = `(?:$|[^\pL\pN_])`
+
+	pattern := regexp.QuoteMeta(keyword)
+	runes := []rune(keyword)
+	if isWordRune(runes[0]) {
+		pattern = boundaryStart + pattern
+	}
+	if isWordRune(runes[len(runes)-1]) {
+		pattern += boundaryEnd
+	}
+	return pattern
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+func compileFilterRule(rule FilterRule) (filterRule, error) {
+	var patterns []string
+	for _, keyword := range rule.Keywords {
+		if keyword = strings.TrimSpace(keyword); keyword != "" {
+			patterns = append(patterns, keywordPattern(keyword))
+		}
+	}
+	if rule.Regex != "" {
+		if _, err := regexp.Compile(rule.Regex); err != nil {
+			return filterRule{}, fmt.Errorf("invalid regex %q: %w", rule.Regex, err)
+		}
+		patterns = append(patterns, "(?:"+rule.Regex+")")
+	}
+	if len(patterns) == 0 {
+		return filterRule{}, fmt.Errorf("keywords or regex is required")
+	}
+
+	pattern := strings.Join(patterns, "|")
+	if !rule.CaseSensitive {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return filterRule{}, fmt.Errorf("invalid rule: %w", err)
+	}
+
+	fields := rule.Fields
+	if len(fields) == 0 {
+		fields = defaultFilterFields
+	}
+	for _, field := range fields {
+		if !slices.Contains(FilterFields, field) {
+			return filterRule{}, fmt.Errorf("unknown field %q, expected one of %s", field, strings.Join(FilterFields, ", "))
+		}
+	}
+
+	name := rule.Name
+	if name == "" {
+		name = rule.Regex
+		if len(rule.Keywords) > 0 {
+			name = strings.Join(rule.Keywords, ",")
+		}
+	}
+
+	return filterRule{name: name, fields: fields, re: re}, nil
+}
+
+func compileFilterRules(rules []FilterRule, kind string) ([]filterRule, error) {
+	compiled := make([]filterRule, 0, len(rules))
+	for i, rule := range rules {
+		r, err := compileFilterRule(rule)
+		if err != nil {
+			return nil, fmt.Errorf("%s[%d]: %w", kind, i, err)
+		}
+		compiled = append(compiled, r)
+	}
+	return compiled, nil
+}
+
+// ValidateRule проверяет одно правило, для сообщений об ошибках конфига.
+func ValidateRule(rule FilterRule) error {
+	_, err := compileFilterRule(rule)
+	return err
+}
+
+func NewFilter(conf FiltersConfig) (*Filter, error) {
+	include, err := compileFilterRules(conf.Include, "include")
+	if err != nil {
+		return nil, err
+	}
+	exclude, err := compileFilterRules(conf.Exclude, "exclude")
+	if err != nil {
+		return nil, err
+	}
+	return &Filter{include: include, exclude: exclude}, nil
+}
+
+var stripTags = bluemonday.StripTagsPolicy()
+
+func (r filterRule) match(item *FeedItem) bool {
+	for _, field := range r.fields {
+		var values []string
+		switch field {
+		case FilterFieldTitle:
+			values = []string{item.Title}
+		case FilterFieldDescription:
+			// описание - HTML, атрибуты и разметка не должны срабатывать
+			values = []string{html.UnescapeString(stripTags.Sanitize(item.Description))}
+		case FilterFieldLink:
+			values = []string{item.Link}
+		case FilterFieldTags:
+			values = item.Tags
+		case FilterFieldAuthor:
+			values = []string{item.Author}
+		}
+
+		for _, v := range values {
+			if r.re.MatchString(v) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Match решает, оставить ли новость. Для отброшенной возвращается причина:
+// not_included или exclude:<имя правила>.
+func (f *Filter) Match(item *FeedItem) (bool, string) {
+	if f == nil {
+		return true, ""
+	}
+
+	if len(f.include) > 0 {
+		included := false
+		for _, r := range f.include {
+			if r.match(item) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false, FilterReasonNotIncluded
+		}
+	}
+
+	for _, r := range f.exclude {
+		if r.match(item) {
+			return false, "exclude:" + r.name
+		}
+	}
+
+	return true, ""
+}
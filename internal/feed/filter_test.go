@@ -0,0 +1,176 @@
+package feed
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestFilter_Match(t *testing.T) {
+	item := FeedItem{
+		Title:       "Вышел Go 1.23: итераторы и телеметрия",
+		Description: `<p class="sponsored">Подробности <a href="https://go.dev/blog">в блоге</a></p>`,
+		Link:        "https://example.com/news/go-1-23?utm_source=rss",
+		Tags:        []string{"golang", "release"},
+		Author:      "Gopher",
+	}
+
+	tests := []struct {
+		name    string
+		filters FiltersConfig
+		keep    bool
+		reason  string
+	}{
+		{
+			name: "no rules",
+			keep: true,
+		},
+		{
+			name:    "keyword in title, case insensitive",
+			filters: FiltersConfig{Exclude: []FilterRule{{Keywords: []string{"GO"}}}},
+			keep:    false,
+			reason:  "exclude:GO",
+		},
+		{
+			name:    "keyword matches whole words only",
+			filters: FiltersConfig{Exclude: []FilterRule{{Keywords: []string{"итератор", "гофер"}}}},
+			keep:    true,
+		},
+		{
+			name:    "cyrillic keyword",
+			filters: FiltersConfig{Exclude: []FilterRule{{Name: "telemetry", Keywords: []string{"ТЕЛЕМЕТРИЯ"}}}},
+			keep:    false,
+			reason:  "exclude:telemetry",
+		},
+		{
+			name:    "case sensitive keyword",
+			filters: FiltersConfig{Exclude: []FilterRule{{Keywords: []string{"go"}, CaseSensitive: true}}},
+			keep:    true,
+		},
+		{
+			name:    "regex",
+			filters: FiltersConfig{Exclude: []FilterRule{{Regex: `go \d+\.\d+`}}},
+			keep:    false,
+			reason:  `exclude:go \d+\.\d+`,
+		},
+		{
+			name:    "html markup of description is ignored",
+			filters: FiltersConfig{Exclude: []FilterRule{{Keywords: []string{"sponsored", "go.dev"}}}},
+			keep:    true,
+		},
+		{
+			name:    "description text",
+			filters: FiltersConfig{Exclude: []FilterRule{{Keywords: []string{"в блоге"}}}},
+			keep:    false,
+			reason:  "exclude:в блоге",
+		},
+		{
+			name:    "only listed fields",
+			filters: FiltersConfig{Exclude: []FilterRule{{Fields: []string{FilterFieldLink}, Keywords: []string{"телеметрия"}}}},
+			keep:    true,
+		},
+		{
+			name:    "link",
+			filters: FiltersConfig{Exclude: []FilterRule{{Fields: []string{FilterFieldLink}, Regex: `utm_`}}},
+			keep:    false,
+			reason:  "exclude:utm_",
+		},
+		{
+			name:    "tags and author",
+			filters: FiltersConfig{Exclude: []FilterRule{{Fields: []string{FilterFieldTags, FilterFieldAuthor}, Keywords: []string{"gopher"}}}},
+			keep:    false,
+			reason:  "exclude:gopher",
+		},
+		{
+			name:    "included",
+			filters: FiltersConfig{Include: []FilterRule{{Keywords: []string{"rust"}}, {Fields: []string{FilterFieldTags}, Keywords: []string{"golang"}}}},
+			keep:    true,
+		},
+		{
+			name:    "not included",
+			filters: FiltersConfig{Include: []FilterRule{{Keywords: []string{"rust"}}}},
+			keep:    false,
+			reason:  FilterReasonNotIncluded,
+		},
+		{
+			name: "exclude wins over include",
+			filters: FiltersConfig{
+				Include: []FilterRule{{Keywords: []string{"go"}}},
+				Exclude: []FilterRule{{Name: "release", Fields: []string{FilterFieldTags}, Keywords: []string{"release"}}},
+			},
+			keep:   false,
+			reason: "exclude:release",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := NewFilter(tt.filters)
+			require.NoError(t, err)
+
+			keep, reason := filter.Match(&item)
+			assert.Equal(t, tt.keep, keep)
+			assert.Equal(t, tt.reason, reason)
+		})
+	}
+}
+
+func TestValidateRule(t *testing.T) {
+	tests := []struct {
+		name string
+		rule FilterRule
+		err  string
+	}{
+		{name: "keywords", rule: FilterRule{Keywords: []string{"go"}}},
+		{name: "regex", rule: FilterRule{Regex: `(?i)^sale`, Fields: []string{FilterFieldTitle}}},
+		{name: "empty", rule: FilterRule{Keywords: []string{" "}}, err: "keywords or regex is required"},
+		{name: "invalid regex", rule: FilterRule{Regex: `(`}, err: "invalid regex"},
+		{name: "unknown field", rule: FilterRule{Keywords: []string{"go"}, Fields: []string{"body"}}, err: `unknown field "body"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateRule(tt.rule)
+			if tt.err == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+}
+
+func TestFiltersConfig_Merge(t *testing.T) {
+	global := FiltersConfig{Exclude: make([]FilterRule, 1, 4)}
+	global.Exclude[0] = FilterRule{Name: "global"}
+
+	a := global.Merge(FiltersConfig{Exclude: []FilterRule{{Name: "a"}}})
+	b := global.Merge(FiltersConfig{Include: []FilterRule{{Name: "b"}}, Exclude: []FilterRule{{Name: "b"}}})
+
+	// правила одного фида не попадают в другой
+	assert.Equal(t, []FilterRule{{Name: "global"}, {Name: "a"}}, a.Exclude)
+	assert.Equal(t, []FilterRule{{Name: "global"}, {Name: "b"}}, b.Exclude)
+	assert.Equal(t, []FilterRule{{Name: "b"}}, b.Include)
+	assert.True(t, FiltersConfig{}.IsEmpty())
+}
+
+func TestFeedConfig_Compile(t *testing.T) {
+	conf, err := FeedConfig{
+		URL:     "https://example.com/rss",
+		Filters: FiltersConfig{Exclude: []FilterRule{{Name: "ads", Keywords: []string{"реклама"}}}},
+	}.Compile()
+	require.NoError(t, err)
+
+	// проход берёт собранный фильтр, а не компилирует Filters заново
+	conf.Filters = FiltersConfig{Exclude: []FilterRule{{Name: "other", Keywords: []string{"новость"}}}}
+	feed := &Feed{Config: conf, Items: []FeedItem{{Title: "Новость"}, {Title: "Реклама"}}}
+	require.NoError(t, NewManager(nil).applyFilters(feed, zap.NewNop()))
+	require.Len(t, feed.Items, 1)
+	assert.Equal(t, "Новость", feed.Items[0].Title)
+
+	_, err = FeedConfig{Filters: FiltersConfig{Include: []FilterRule{{Regex: "("}}}}.Compile()
+	assert.ErrorContains(t, err, "invalid filters")
+}
@@ -2,7 +2,10 @@ package feed
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
+	"sort"
 	"sync"
 	"time"
 
@@ -23,21 +26,44 @@ type repo interface {
 	GetFeedByURL(ctx context.Context, url string) (*storage.StoredFeed, error)
 	DeleteFeed(ctx context.Context, url string) error
 	UpsertFeed(ctx context.Context, url string, lastChecked, lastPost time.Time) error
+	SetFeedResolvedURL(ctx context.Context, url, resolvedURL string) error
 
 	InsertItem(ctx context.Context, item *FeedItem) error
+	FindDuplicateItem(ctx context.Context, normalizedLink string, sentAfter time.Time) (*storage.StoredItem, error)
+	AddItemSource(ctx context.Context, itemID, source string) error
+	ListFingerprints(ctx context.Context, sentAfter time.Time) ([]storage.ItemFingerprint, error)
+
+	GetEnrichCache(ctx context.Context, key string, fetchedAfter time.Time) (*SiteDescription, error)
+	SetEnrichCache(ctx context.Context, key string, description SiteDescription, fetchedAt time.Time) error
 }
 
 type Manager struct {
 	repo          repo
 	parserFactory func() gofeedParser
+	// discoverFeeds ищет адреса фидов на странице сайта
+	discoverFeeds func(ctx context.Context, pageURL string) ([]string, error)
+	// robots и hosts общие для всех фидов и живут, пока живёт менеджер
+	robots *robotsCache
+	hosts  *hostLimiter
 }
 
-func (fm *Manager) EnrichFeedItems(feed *Feed) error {
-	if feed.Config.DescriptionType != FeedDescriptionTypeLink {
+// EnrichFeedItems дополняет новости данными их страниц. Страница берётся из кэша в базе,
+// если он моложе cache_ttl; закрытые robots.txt страницы с respect_robots не загружаются.
+// Ошибки загрузки не прерывают обработку: новость остаётся с данными фида.
+func (fm *Manager) EnrichFeedItems(ctx context.Context, feed *Feed, ctxLogger *zap.Logger) error {
+	switch feed.Config.DescriptionType {
+	case FeedDescriptionTypeLink, FeedDescriptionTypeFulltext:
+	default:
 		return nil
 	}
 
+	conf := feed.Config.Enrich.WithDefaults()
 	p := NewSiteParser()
+	p.userAgent = conf.UserAgent
+	p.hosts = fm.hosts
+	p.hostDelay = conf.HostDelay
+	fulltext := feed.Config.DescriptionType == FeedDescriptionTypeFulltext
+
 	wg := sync.WaitGroup{}
 
 	for i := range feed.Items {
@@ -46,32 +72,95 @@ func (fm *Manager) EnrichFeedItems(feed *Feed) error {
 		wg.Add(1)
 		go func(item *FeedItem) {
 			defer wg.Done()
-			siteDescription, err := p.GetDescription(item.Link)
-			if err != nil {
-				// TODO: LOG
-				return
+			itemLogger := ctxLogger.With(zap.String("link", item.Link))
+			result := "page"
+			defer func() {
+				metrics.ItemsEnrichCount.WithLabelValues(feed.Title, result).Inc()
+			}()
+
+			key := feed.Config.DescriptionType + ":" + item.Link
+			if fm.repo != nil {
+				cached, err := fm.repo.GetEnrichCache(ctx, key, time.Now().Add(-conf.CacheTTL))
+				if err != nil {
+					itemLogger.Error("failed to get enrich cache", zap.Error(err))
+				}
+				if cached != nil {
+					result = "cache"
+					applySiteDescription(item, *cached)
+					return
+				}
 			}
 
-			if siteDescription.Description != "" {
-				item.Description = siteDescription.Description
-			} else if siteDescription.Title != "" {
-				item.Description = siteDescription.Title
+			if conf.RespectRobots {
+				allowed, err := fm.robots.allowed(ctx, p, item.Link)
+				if err != nil {
+					itemLogger.Debug("failed to get robots.txt", zap.Error(err))
+				}
+				if !allowed {
+					result = "robots"
+					itemLogger.Debug("page is disallowed by robots.txt")
+					return
+				}
 			}
 
-			if siteDescription.Image != "" {
-				item.ImageURL = siteDescription.Image
+			fetchedAt := time.Now()
+			siteDescription, err := p.describe(ctx, item.Link, fulltext)
+			if err != nil {
+				result = "error"
+				itemLogger.Debug("failed to enrich item", zap.Error(err))
+				return
+			}
+
+			if fm.repo != nil {
+				if err = fm.repo.SetEnrichCache(ctx, key, siteDescription, fetchedAt); err != nil {
+					itemLogger.Error("failed to set enrich cache", zap.Error(err))
+				}
 			}
+			applySiteDescription(item, siteDescription)
 		}(item)
 	}
 
 	wg.Wait()
 
+	// при остановке загрузки страниц обрываются: такие новости сохранять нельзя,
+	// их без потерь обогатит следующий запуск
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
+func applySiteDescription(item *FeedItem, siteDescription SiteDescription) {
+	if siteDescription.Text != "" {
+		item.Description = siteDescription.Text
+	} else if siteDescription.Description != "" {
+		item.Description = siteDescription.Description
+	} else if siteDescription.Title != "" {
+		item.Description = siteDescription.Title
+	}
+
+	if siteDescription.Image != "" {
+		item.ImageURL = siteDescription.Image
+	}
+
+	item.CanonicalURL = siteDescription.Canonical
+
+	if metadata := siteDescription.Metadata(); len(metadata) > 0 {
+		if item.Metadata == nil {
+			item.Metadata = make(map[string]interface{}, len(metadata))
+		}
+		maps.Copy(item.Metadata, metadata)
+	}
+	if item.Author == "" {
+		item.Author = siteDescription.Page.Author
+	}
+}
+
 func (fm *Manager) ProcessFeed(ctx context.Context, f FeedConfig, logger *zap.Logger) error {
 	ctxLogger := logger.With(zap.String("feed", f.Name), zap.String("url", f.URL))
 	var isNewFeed bool
+	var resolvedURL string
 
 	storedFeed, err := fm.repo.GetFeedByURL(ctx, f.URL)
 	if err != nil {
@@ -81,10 +170,12 @@ func (fm *Manager) ProcessFeed(ctx context.Context, f FeedConfig, logger *zap.Lo
 	if storedFeed == nil {
 		isNewFeed = true
 		ctxLogger.Info("feed is new")
+	} else {
+		resolvedURL = storedFeed.ResolvedURL
 	}
 
 	startTime := time.Now()
-	feed, err := fm.GetFeed(ctx, f)
+	feed, err := fm.getFeed(ctx, f, resolvedURL)
 	if err != nil {
 		metrics.FeedGetError.WithLabelValues(f.Name).Inc()
 		return fmt.Errorf("failed get feed by url %s: %w", f.URL, err)
@@ -108,11 +199,25 @@ func (fm *Manager) ProcessFeed(ctx context.Context, f FeedConfig, logger *zap.Lo
 		metrics.NewItemsCount.WithLabelValues(feed.Title).Add(float64(newItems))
 	}
 
+	// last_post не двигается, если до новостей дело не дошло
+	if err = ctx.Err(); err != nil {
+		return fmt.Errorf("feed %s is not saved: %w", f.URL, err)
+	}
+
 	err = fm.repo.UpsertFeed(ctx, f.URL, time.Now().UTC(), lastItemPublishedAt)
 	if err != nil {
 		return fmt.Errorf("failed upserting feed %s: %w", f.URL, err)
 	}
 
+	if feed.ResolvedURL != resolvedURL {
+		ctxLogger.Info("feed url resolved", zap.String("resolved_url", feed.ResolvedURL))
+
+		err = fm.repo.SetFeedResolvedURL(ctx, f.URL, feed.ResolvedURL)
+		if err != nil {
+			return fmt.Errorf("failed saving resolved url of feed %s: %w", f.URL, err)
+		}
+	}
+
 	return nil
 }
 
@@ -129,14 +234,30 @@ func (fm *Manager) processFeed(ctx context.Context, feed *Feed, ctxLogger *zap.L
 	ctxLogger.Debug(fmt.Sprintf("new items: %d", newItemsAmount))
 
 	startTime := time.Now()
-	if err := fm.EnrichFeedItems(feed); err != nil {
+	if err := fm.EnrichFeedItems(ctx, feed, ctxLogger); err != nil {
 		return newItemsAmount, lastItemPublishedAt, fmt.Errorf("failed enriching feed items (%s): %w", feed.URL, err)
 	}
 
 	metrics.ItemsEnrichTimeSec.WithLabelValues(feed.Title).Observe(time.Since(startTime).Seconds())
 
+	// фильтры смотрят на новость после обогащения - в том виде, в каком она уйдёт в канал
+	if err := fm.applyFilters(feed, ctxLogger); err != nil {
+		return newItemsAmount, lastItemPublishedAt, err
+	}
+	if err := fm.applyRules(feed, ctxLogger); err != nil {
+		return newItemsAmount, lastItemPublishedAt, err
+	}
+	if err := fm.applyDedup(ctx, feed, ctxLogger); err != nil {
+		return newItemsAmount, lastItemPublishedAt, err
+	}
+	newItemsAmount = len(feed.Items)
+
 	for i := range feed.Items {
-		err := fm.repo.InsertItem(context.Background(), &feed.Items[i])
+		if err := ctx.Err(); err != nil {
+			return newItemsAmount, lastItemPublishedAt, err
+		}
+
+		err := fm.repo.InsertItem(ctx, &feed.Items[i])
 		if err != nil {
 			return newItemsAmount, lastItemPublishedAt, fmt.Errorf("failed inserting item %d: %w", i, err) // TODO: log instead break ???
 		}
@@ -147,6 +268,238 @@ func (fm *Manager) processFeed(ctx context.Context, feed *Feed, ctxLogger *zap.L
 	return newItemsAmount, lastItemPublishedAt, nil
 }
 
+// Preview забирает фид, отбрасывает новости по фильтрам и обогащает последние limit так же, как ProcessFeed,
+// но ничего не пишет в базу. Новости возвращаются в порядке отправки - от старых к новым.
+func (fm *Manager) Preview(ctx context.Context, f FeedConfig, limit int) (*Feed, error) {
+	feed, err := fm.GetFeed(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(feed.Items, func(i, j int) bool {
+		return feed.Items[i].GetPublishedAt(time.Time{}).Before(feed.Items[j].GetPublishedAt(time.Time{}))
+	})
+
+	// сначала по данным фида, чтобы limit считался по прошедшим фильтры новостям,
+	// и ещё раз после обогащения, как в ProcessFeed
+	if err = fm.applyFilters(feed, zap.NewNop()); err != nil {
+		return nil, err
+	}
+
+	if limit > 0 && len(feed.Items) > limit {
+		feed.Items = feed.Items[len(feed.Items)-limit:]
+	}
+
+	if err = fm.EnrichFeedItems(ctx, feed, zap.NewNop()); err != nil {
+		return nil, fmt.Errorf("failed enriching feed items (%s): %w", feed.URL, err)
+	}
+
+	if err = fm.applyFilters(feed, zap.NewNop()); err != nil {
+		return nil, err
+	}
+	if err = fm.applyRules(feed, zap.NewNop()); err != nil {
+		return nil, err
+	}
+
+	return feed, nil
+}
+
+// applyFilters убирает из фида новости, не прошедшие фильтры конфига.
+// Время последней новости уже посчитано по всем новостям, поэтому отброшенные не вернутся.
+func (fm *Manager) applyFilters(feed *Feed, ctxLogger *zap.Logger) error {
+	if feed.Config.Filters.IsEmpty() {
+		return nil
+	}
+
+	filter := feed.Config.filter
+	if filter == nil {
+		var err error
+		if filter, err = NewFilter(feed.Config.Filters); err != nil {
+			return fmt.Errorf("invalid filters of feed %s: %w", feed.URL, err)
+		}
+	}
+
+	items := feed.Items[:0]
+	for _, item := range feed.Items {
+		keep, reason := filter.Match(&item)
+		if keep {
+			items = append(items, item)
+			continue
+		}
+
+		metrics.ItemsFilteredCount.WithLabelValues(feed.Title, reason).Inc()
+		ctxLogger.Info("item filtered",
+			zap.String("reason", reason),
+			zap.String("title", item.Title),
+			zap.String("link", item.Link),
+		)
+	}
+	feed.Items = items
+
+	return nil
+}
+
+// applyRules применяет к новостям правила маршрутизации: отбрасывает их или
+// запоминает выходы и тихий режим для отправки.
+func (fm *Manager) applyRules(feed *Feed, ctxLogger *zap.Logger) error {
+	if len(feed.Config.Rules) == 0 {
+		return nil
+	}
+
+	router := feed.Config.router
+	if router == nil {
+		var err error
+		if router, err = NewRouter(feed.Config.Rules); err != nil {
+			return fmt.Errorf("invalid rules of feed %s: %w", feed.URL, err)
+		}
+	}
+
+	items := feed.Items[:0]
+	for _, item := range feed.Items {
+		route, err := router.Route(feed.Config, &item)
+		if err != nil {
+			ctxLogger.Warn("failed to evaluate rule", zap.String("link", item.Link), zap.Error(err))
+		}
+
+		if route.Drop {
+			reason := "rule:" + route.Rule
+			metrics.ItemsFilteredCount.WithLabelValues(feed.Title, reason).Inc()
+			ctxLogger.Info("item filtered",
+				zap.String("reason", reason),
+				zap.String("title", item.Title),
+				zap.String("link", item.Link),
+			)
+			continue
+		}
+
+		item.Outputs = route.Outputs
+		item.Silent = route.Silent
+		items = append(items, item)
+	}
+	feed.Items = items
+
+	return nil
+}
+
+// applyDedup заполняет нормализованные ссылки и отпечатки новостей и, если дедупликация включена,
+// убирает новости, которые уже сохранены из другого фида или повторяются в этом: с той же ссылкой
+// или, если задан порог similarity, с почти тем же заголовком и описанием.
+func (fm *Manager) applyDedup(ctx context.Context, feed *Feed, ctxLogger *zap.Logger) error {
+	for i := range feed.Items {
+		item := &feed.Items[i]
+		link := item.Link
+		if item.CanonicalURL != "" {
+			link = item.CanonicalURL
+		}
+		item.NormalizedLink = NormalizeURL(link)
+		item.Fingerprint = Fingerprint(item.Title, item.Description)
+	}
+
+	if !feed.Config.Dedup.Enabled {
+		return nil
+	}
+	conf := feed.Config.Dedup.WithDefaults()
+	sentAfter := time.Now().Add(-conf.Window)
+
+	// отпечатки из базы загружаются один раз на фид и только если есть что сравнивать
+	var recent []storage.ItemFingerprint
+	recentLoaded := false
+
+	seen := make(map[string]bool, len(feed.Items))
+	items := feed.Items[:0]
+	for _, item := range feed.Items {
+		if item.NormalizedLink != "" {
+			if seen[item.NormalizedLink] {
+				metrics.ItemsFilteredCount.WithLabelValues(feed.Title, FilterReasonDuplicate).Inc()
+				ctxLogger.Info("duplicate item skipped", zap.String("title", item.Title), zap.String("link", item.Link))
+				continue
+			}
+			seen[item.NormalizedLink] = true
+
+			dup, err := fm.repo.FindDuplicateItem(ctx, item.NormalizedLink, sentAfter)
+			if err != nil {
+				return fmt.Errorf("failed to find duplicate of %s: %w", item.Link, err)
+			}
+			// та же новость этого фида - её пропустит InsertItem
+			if dup != nil && dup.ID != item.ID {
+				if err = fm.skipDuplicate(ctx, feed, conf, &item, dup, FilterReasonDuplicate, ctxLogger); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		if conf.Similarity > 0 && item.Fingerprint != 0 {
+			if !recentLoaded {
+				var err error
+				if recent, err = fm.repo.ListFingerprints(ctx, sentAfter); err != nil {
+					return fmt.Errorf("failed to list fingerprints: %w", err)
+				}
+				recentLoaded = true
+			}
+
+			if dup, similarity := nearestFingerprint(recent, &item); dup != nil && similarity >= conf.Similarity {
+				stored := &storage.StoredItem{ID: dup.ItemID, FeedURL: dup.FeedURL, Status: dup.Status}
+				err := fm.skipDuplicate(ctx, feed, conf, &item, stored, FilterReasonNearDuplicate, ctxLogger,
+					zap.Float64("similarity", similarity))
+				if err != nil {
+					return err
+				}
+				continue
+			}
+
+			// следующие новости этого фида сравниваются и с этой
+			recent = append(recent, storage.ItemFingerprint{
+				ItemID:      item.ID,
+				FeedURL:     item.FeedURL,
+				Fingerprint: item.Fingerprint,
+				Status:      storage.ItemStatusPending,
+			})
+		}
+
+		items = append(items, item)
+	}
+	feed.Items = items
+
+	return nil
+}
+
+// nearestFingerprint ищет самый похожий на новость отпечаток, кроме её собственного.
+func nearestFingerprint(fingerprints []storage.ItemFingerprint, item *FeedItem) (*storage.ItemFingerprint, float64) {
+	var nearest *storage.ItemFingerprint
+	var maxSimilarity float64
+	for i := range fingerprints {
+		if fingerprints[i].ItemID == item.ID {
+			continue
+		}
+		if similarity := Similarity(fingerprints[i].Fingerprint, item.Fingerprint); nearest == nil || similarity > maxSimilarity {
+			nearest, maxSimilarity = &fingerprints[i], similarity
+		}
+	}
+	return nearest, maxSimilarity
+}
+
+// skipDuplicate учитывает пропущенную новость и, если включено, дописывает её фид к ожидающему дублю.
+func (fm *Manager) skipDuplicate(ctx context.Context, feed *Feed, conf DedupConfig, item *FeedItem, dup *storage.StoredItem, reason string, ctxLogger *zap.Logger, fields ...zap.Field) error {
+	metrics.ItemsFilteredCount.WithLabelValues(feed.Title, reason).Inc()
+	ctxLogger.Info("duplicate item skipped", append([]zap.Field{
+		zap.String("reason", reason),
+		zap.String("title", item.Title),
+		zap.String("link", item.Link),
+		zap.String("duplicate_of", dup.ID),
+		zap.String("duplicate_feed", dup.FeedURL),
+	}, fields...)...)
+
+	// отправленное сообщение не редактируется, дописать источник можно только в очереди
+	if conf.AnnotateSources && dup.FeedURL != item.FeedURL && (dup.Status == storage.ItemStatusPending || dup.Status == storage.ItemStatusFailed) {
+		if err := fm.repo.AddItemSource(ctx, dup.ID, item.FeedTitle); err != nil {
+			return fmt.Errorf("failed to annotate item %s: %w", dup.ID, err)
+		}
+	}
+
+	return nil
+}
+
 func (fm *Manager) getMaxPublishedAt(f *Feed) time.Time {
 	var maxPublishedAt time.Time
 
@@ -179,14 +532,59 @@ func (fm *Manager) filterItemsAfterByRefTime(f *Feed, refTime time.Time) int {
 	return len(newItems)
 }
 
-func (fm *Manager) GetFeed(ctx context.Context, f FeedConfig) (*Feed, error) {
+// fetchTimeout ограничивает каждую загрузку: фида, страницы сайта и адресов-кандидатов.
+const fetchTimeout = 10 * time.Second
 
+// fetchFeed загружает фид. Если по адресу из конфига отдаётся страница сайта, а не фид,
+// адрес фида ищется на ней. resolvedURL - найденный раньше адрес, он пробуется первым.
+// Возвращается фид и адрес, с которого он загружен.
+func (fm *Manager) fetchFeed(ctx context.Context, f FeedConfig, resolvedURL string) (*gofeed.Feed, string, error) {
 	fp := fm.parserFactory()
-	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
-	defer cancel()
 
-	respFeed, err := fp.ParseURLWithContext(f.URL, ctx)
+	parse := func(url string) (*gofeed.Feed, error) {
+		ctx, cancel := context.WithTimeout(ctx, fetchTimeout)
+		defer cancel()
+		return fp.ParseURLWithContext(url, ctx)
+	}
+
+	if resolvedURL != "" {
+		respFeed, err := parse(resolvedURL)
+		if err == nil {
+			return respFeed, resolvedURL, nil
+		}
+		// фид мог переехать - ищем заново со страницы из конфига
+	}
 
+	respFeed, err := parse(f.URL)
+	if err == nil {
+		return respFeed, f.URL, nil
+	}
+	if !errors.Is(err, gofeed.ErrFeedTypeNotDetected) || fm.discoverFeeds == nil {
+		return nil, "", err
+	}
+
+	discoverCtx, cancel := context.WithTimeout(ctx, fetchTimeout)
+	candidates, discoverErr := fm.discoverFeeds(discoverCtx, f.URL)
+	cancel()
+	if discoverErr != nil {
+		return nil, "", fmt.Errorf("%w, feed discovery failed: %w", err, discoverErr)
+	}
+
+	for _, candidate := range candidates {
+		if respFeed, candidateErr := parse(candidate); candidateErr == nil {
+			return respFeed, candidate, nil
+		}
+	}
+
+	return nil, "", fmt.Errorf("%w, no feed found on the page", err)
+}
+
+func (fm *Manager) GetFeed(ctx context.Context, f FeedConfig) (*Feed, error) {
+	return fm.getFeed(ctx, f, "")
+}
+
+func (fm *Manager) getFeed(ctx context.Context, f FeedConfig, resolvedURL string) (*Feed, error) {
+	respFeed, fetchedURL, err := fm.fetchFeed(ctx, f, resolvedURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse feed URL %s: %w", f.URL, err)
 	}
@@ -211,17 +609,26 @@ func (fm *Manager) GetFeed(ctx context.Context, f FeedConfig) (*Feed, error) {
 			tags = item.Categories
 		}
 
-		items = append(items,
-			NewFeedItem(
-				feedTitle,
-				item.Title,
-				item.Link,
-				imageURL,
-				item.Description,
-				item.PublishedParsed,
-				item.UpdatedParsed,
-				tags,
-			))
+		feedItem := NewFeedItem(
+			feedTitle,
+			item.Title,
+			item.Link,
+			imageURL,
+			item.Description,
+			item.PublishedParsed,
+			item.UpdatedParsed,
+			tags,
+		)
+		feedItem.FeedURL = f.URL
+		if len(item.Authors) > 0 && item.Authors[0] != nil {
+			feedItem.Author = item.Authors[0].Name
+		}
+
+		items = append(items, feedItem)
+	}
+
+	if fetchedURL == f.URL {
+		fetchedURL = ""
 	}
 
 	feed := &Feed{
@@ -229,6 +636,7 @@ func (fm *Manager) GetFeed(ctx context.Context, f FeedConfig) (*Feed, error) {
 		Config:      f,
 		Title:       feedTitle,
 		URL:         f.URL,
+		ResolvedURL: fetchedURL,
 		Description: respFeed.Description,
 		Items:       items,
 		Key:         f.Key,
@@ -243,5 +651,8 @@ func NewManager(repo repo) *Manager {
 	return &Manager{
 		repo:          repo,
 		parserFactory: func() gofeedParser { return gofeed.NewParser() },
+		discoverFeeds: NewSiteParser().DiscoverFeeds,
+		robots:        newRobotsCache(),
+		hosts:         newHostLimiter(),
 	}
 }
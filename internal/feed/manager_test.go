@@ -2,13 +2,17 @@ package feed
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
 	"rssgram/internal/storage"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/mmcdole/gofeed"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 )
 
@@ -35,11 +39,50 @@ func (m *MockRepo) UpsertFeed(ctx context.Context, url string, lastChecked, last
 	return args.Error(0)
 }
 
+func (m *MockRepo) SetFeedResolvedURL(ctx context.Context, url, resolvedURL string) error {
+	args := m.Called(ctx, url, resolvedURL)
+	return args.Error(0)
+}
+
 func (m *MockRepo) InsertItem(ctx context.Context, item *FeedItem) error {
 	args := m.Called(ctx, item)
 	return args.Error(0)
 }
 
+func (m *MockRepo) FindDuplicateItem(ctx context.Context, normalizedLink string, sentAfter time.Time) (*storage.StoredItem, error) {
+	args := m.Called(ctx, normalizedLink, sentAfter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*storage.StoredItem), args.Error(1)
+}
+
+func (m *MockRepo) AddItemSource(ctx context.Context, itemID, source string) error {
+	args := m.Called(ctx, itemID, source)
+	return args.Error(0)
+}
+
+func (m *MockRepo) ListFingerprints(ctx context.Context, sentAfter time.Time) ([]storage.ItemFingerprint, error) {
+	args := m.Called(ctx, sentAfter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]storage.ItemFingerprint), args.Error(1)
+}
+
+func (m *MockRepo) GetEnrichCache(ctx context.Context, key string, fetchedAfter time.Time) (*SiteDescription, error) {
+	args := m.Called(ctx, key, fetchedAfter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*SiteDescription), args.Error(1)
+}
+
+func (m *MockRepo) SetEnrichCache(ctx context.Context, key string, description SiteDescription, fetchedAt time.Time) error {
+	args := m.Called(ctx, key, description, fetchedAt)
+	return args.Error(0)
+}
+
 // TestNewManager проверяет, что менеджер создаётся корректно и содержит переданный repo.
 func TestNewManager(t *testing.T) {
 	mockRepo := &MockRepo{}
@@ -329,6 +372,7 @@ func TestFeedManager_GetFeed_TagsPriority(t *testing.T) {
 	assert.NoError(t, err)
 	for _, item := range feed.Items {
 		assert.Equal(t, []string{"tag1", "tag2"}, item.Tags)
+		assert.Equal(t, feedConfigWithTags.URL, item.FeedURL)
 	}
 
 	// 2. Если в конфиге нет тегов, используются из RSS
@@ -362,3 +406,272 @@ func (f *fakeParser) ParseURLWithContext(url string, ctx context.Context) (*gofe
 func timePtr(t time.Time) *time.Time {
 	return &t
 }
+
+type staticParser struct {
+	feed *gofeed.Feed
+}
+
+func (p *staticParser) ParseURLWithContext(url string, ctx context.Context) (*gofeed.Feed, error) {
+	return p.feed, nil
+}
+
+// TestManager_Preview проверяет, что превью берёт последние новости в порядке отправки и не обращается к базе.
+func TestManager_Preview(t *testing.T) {
+	mockRepo := &MockRepo{}
+	manager := NewManager(mockRepo)
+
+	at := func(hour int) *time.Time {
+		t := time.Date(2024, 5, 1, hour, 0, 0, 0, time.UTC)
+		return &t
+	}
+
+	manager.parserFactory = func() gofeedParser {
+		return &staticParser{feed: &gofeed.Feed{
+			Title: "FakeFeed",
+			Items: []*gofeed.Item{
+				{Title: "Newest", Link: "https://example.com/3", PublishedParsed: at(12)},
+				{Title: "Oldest", Link: "https://example.com/1", PublishedParsed: at(10)},
+				{Title: "Middle", Link: "https://example.com/2", PublishedParsed: at(11)},
+			},
+		}}
+	}
+
+	feed, err := manager.Preview(context.Background(), FeedConfig{Name: "Preview", URL: "https://example.com/rss"}, 2)
+	require.NoError(t, err)
+
+	require.Len(t, feed.Items, 2)
+	assert.Equal(t, "Middle", feed.Items[0].Title)
+	assert.Equal(t, "Newest", feed.Items[1].Title)
+	assert.Equal(t, "Preview", feed.Items[0].FeedTitle)
+
+	// вызовов репозитория не было
+	mockRepo.AssertExpectations(t)
+	assert.Empty(t, mockRepo.Calls)
+}
+
+// TestManager_ProcessFeed_Filters проверяет, что отфильтрованные новости не сохраняются,
+// но время последней новости считается по всем.
+func TestManager_ProcessFeed_Filters(t *testing.T) {
+	mockRepo := &MockRepo{}
+	manager := NewManager(mockRepo)
+
+	at := func(hour int) *time.Time {
+		t := time.Date(2024, 5, 1, hour, 0, 0, 0, time.UTC)
+		return &t
+	}
+
+	manager.parserFactory = func() gofeedParser {
+		return &staticParser{feed: &gofeed.Feed{
+			Title: "FakeFeed",
+			Items: []*gofeed.Item{
+				{Title: "Скидки недели", Link: "https://example.com/3", PublishedParsed: at(12)},
+				{Title: "Go 1.23", Link: "https://example.com/2", PublishedParsed: at(11)},
+				{Title: "Старая новость", Link: "https://example.com/1", PublishedParsed: at(9)},
+			},
+		}}
+	}
+
+	feedConfig := FeedConfig{
+		Name: "Test Feed",
+		URL:  "https://example.com/rss",
+		Filters: FiltersConfig{
+			Exclude: []FilterRule{{Name: "ads", Keywords: []string{"скидки"}}},
+		},
+	}
+
+	mockRepo.On("GetFeedByURL", mock.Anything, feedConfig.URL).
+		Return(&storage.StoredFeed{URL: feedConfig.URL, LastPosted: *at(10)}, nil)
+	mockRepo.On("InsertItem", mock.Anything, mock.MatchedBy(func(item *FeedItem) bool {
+		return item.Title == "Go 1.23"
+	})).Return(nil).Once()
+	mockRepo.On("UpsertFeed", mock.Anything, feedConfig.URL, mock.Anything, *at(12)).Return(nil)
+
+	err := manager.ProcessFeed(context.Background(), feedConfig, zap.NewNop())
+	require.NoError(t, err)
+
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNumberOfCalls(t, "InsertItem", 1)
+}
+
+// TestManager_ProcessFeed_Rules проверяет, что правила отбрасывают новости и сохраняют маршрут остальных.
+func TestManager_ProcessFeed_Rules(t *testing.T) {
+	mockRepo := &MockRepo{}
+	manager := NewManager(mockRepo)
+
+	at := func(hour int) *time.Time {
+		t := time.Date(2024, 5, 1, hour, 0, 0, 0, time.UTC)
+		return &t
+	}
+
+	manager.parserFactory = func() gofeedParser {
+		return &staticParser{feed: &gofeed.Feed{
+			Title: "FakeFeed",
+			Items: []*gofeed.Item{
+				{Title: "Sponsored post", Link: "https://example.com/3", PublishedParsed: at(12)},
+				{Title: "OpenSSH vulnerability", Link: "https://example.com/2", Categories: []string{"security"}, PublishedParsed: at(11)},
+			},
+		}}
+	}
+
+	silent := true
+	feedConfig := FeedConfig{
+		Name: "Test Feed",
+		URL:  "https://example.com/rss",
+		Rules: []RoutingRule{
+			{Name: "ads", When: `item.title startsWith "Sponsored"`, Drop: true},
+			{When: `"security" in item.tags`, Outputs: []string{"security"}, Silent: &silent},
+		},
+	}
+
+	mockRepo.On("GetFeedByURL", mock.Anything, feedConfig.URL).
+		Return(&storage.StoredFeed{URL: feedConfig.URL, LastPosted: *at(10)}, nil)
+	mockRepo.On("InsertItem", mock.Anything, mock.MatchedBy(func(item *FeedItem) bool {
+		return item.Title == "OpenSSH vulnerability" &&
+			assert.ObjectsAreEqual([]string{"security"}, item.Outputs) &&
+			item.Silent != nil && *item.Silent
+	})).Return(nil).Once()
+	mockRepo.On("UpsertFeed", mock.Anything, feedConfig.URL, mock.Anything, *at(12)).Return(nil)
+
+	err := manager.ProcessFeed(context.Background(), feedConfig, zap.NewNop())
+	require.NoError(t, err)
+
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNumberOfCalls(t, "InsertItem", 1)
+}
+
+func TestManager_EnrichFeedItems_CacheAndRobots(t *testing.T) {
+	var mu sync.Mutex
+	var hits, userAgents []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits = append(hits, r.URL.Path)
+		userAgents = append(userAgents, r.UserAgent())
+		mu.Unlock()
+
+		if r.URL.Path == "/robots.txt" {
+			w.Write([]byte("User-agent: *\nDisallow: /\n\nUser-agent: rssgram\nDisallow: /private\n"))
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><meta name="description" content="Fresh"></head></html>`))
+	}))
+	defer server.Close()
+
+	feed := &Feed{
+		Title: "Test Feed",
+		Config: FeedConfig{
+			DescriptionType: FeedDescriptionTypeLink,
+			Enrich:          EnrichConfig{UserAgent: "rssgram/2.0 (+https://example.com/bot)", RespectRobots: true},
+		},
+		Items: []FeedItem{
+			{Link: server.URL + "/cached", Description: "From feed"},
+			{Link: server.URL + "/fresh", Description: "From feed"},
+			{Link: server.URL + "/private/1", Description: "From feed"},
+		},
+	}
+
+	mockRepo := &MockRepo{}
+	mockRepo.On("GetEnrichCache", mock.Anything, "link:"+server.URL+"/cached", mock.Anything).
+		Return(&SiteDescription{Description: "Cached"}, nil)
+	mockRepo.On("GetEnrichCache", mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+	mockRepo.On("SetEnrichCache", mock.Anything, "link:"+server.URL+"/fresh", mock.MatchedBy(func(d SiteDescription) bool {
+		return d.Description == "Fresh"
+	}), mock.Anything).Return(nil).Once()
+
+	manager := NewManager(mockRepo)
+	require.NoError(t, manager.EnrichFeedItems(context.Background(), feed, zap.NewNop()))
+
+	assert.Equal(t, "Cached", feed.Items[0].Description)
+	assert.Equal(t, "Fresh", feed.Items[1].Description)
+	assert.Equal(t, "From feed", feed.Items[2].Description)
+	mockRepo.AssertExpectations(t)
+
+	// из кэша и закрытое robots.txt не загружаются, robots.txt - один раз на сайт
+	assert.ElementsMatch(t, []string{"/robots.txt", "/fresh"}, hits)
+	for _, ua := range userAgents {
+		assert.Equal(t, "rssgram/2.0 (+https://example.com/bot)", ua)
+	}
+
+	// robots.txt помнится между проходами
+	hits = nil
+	feed.Items = []FeedItem{{Link: server.URL + "/private/2"}}
+	require.NoError(t, manager.EnrichFeedItems(context.Background(), feed, zap.NewNop()))
+	assert.Empty(t, hits)
+}
+
+func TestManager_EnrichFeedItems_DefaultUserAgent(t *testing.T) {
+	var mu sync.Mutex
+	agents := make(map[string]string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		agents[r.URL.Path] = r.UserAgent()
+		mu.Unlock()
+
+		if r.URL.Path == "/robots.txt" {
+			w.Write([]byte("User-agent: rssgram\nDisallow: /private\n"))
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><meta name="description" content="Fresh"></head></html>`))
+	}))
+	defer server.Close()
+
+	feed := &Feed{
+		Title: "Test Feed",
+		Config: FeedConfig{
+			DescriptionType: FeedDescriptionTypeLink,
+			Enrich:          EnrichConfig{RespectRobots: true},
+		},
+		Items: []FeedItem{{Link: server.URL + "/news/1"}, {Link: server.URL + "/private/1"}},
+	}
+
+	require.NoError(t, NewManager(nil).EnrichFeedItems(context.Background(), feed, zap.NewNop()))
+
+	// robots.txt запрашивается и читается как rssgram, страница - с браузерным User-Agent
+	assert.Equal(t, "Fresh", feed.Items[0].Description)
+	assert.Empty(t, feed.Items[1].Description)
+	assert.Equal(t, DefaultUserAgent, agents["/robots.txt"])
+	assert.Contains(t, userAgents, agents["/news/1"])
+	assert.NotContains(t, agents, "/private/1")
+}
+
+// TestManager_ProcessFeed_CancelledDuringEnrich проверяет, что при остановке во время обогащения
+// ни новости, ни last_post фида не сохраняются и следующий запуск обработает их заново.
+func TestManager_ProcessFeed_CancelledDuringEnrich(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cancel()
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><meta name="description" content="Page"></head></html>`))
+	}))
+	defer server.Close()
+
+	published := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	mockRepo := &MockRepo{}
+	manager := NewManager(mockRepo)
+	manager.parserFactory = func() gofeedParser {
+		return &staticParser{feed: &gofeed.Feed{
+			Title: "FakeFeed",
+			Items: []*gofeed.Item{{Title: "Go 1.23", Link: server.URL + "/1", PublishedParsed: &published}},
+		}}
+	}
+
+	feedConfig := FeedConfig{
+		Name:            "Test Feed",
+		URL:             "https://example.com/rss",
+		DescriptionType: FeedDescriptionTypeLink,
+	}
+
+	mockRepo.On("GetFeedByURL", mock.Anything, feedConfig.URL).
+		Return(&storage.StoredFeed{URL: feedConfig.URL, LastPosted: published.Add(-time.Hour)}, nil)
+	mockRepo.On("GetEnrichCache", mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+	mockRepo.On("SetEnrichCache", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+
+	err := manager.ProcessFeed(ctx, feedConfig, zap.NewNop())
+	require.ErrorIs(t, err, context.Canceled)
+
+	mockRepo.AssertNotCalled(t, "InsertItem", mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "UpsertFeed", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,220 @@
+package feed
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/go-shiori/dom"
+	"golang.org/x/net/html"
+)
+
+// PageMetadata - разметка страницы новости для поисковиков и соцсетей: Open Graph,
+// Twitter cards и schema.org JSON-LD. Пустые поля на странице не найдены.
+type PageMetadata struct {
+	SiteName string `json:"site_name,omitempty"`
+	// Author - автор из JSON-LD, meta[name=author] или article:author
+	Author string `json:"author,omitempty"`
+	// PublishedAt - время публикации как на странице, обычно ISO 8601
+	PublishedAt string   `json:"published_at,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	// SchemaType - @type статьи в JSON-LD, например NewsArticle
+	SchemaType string `json:"schema_type,omitempty"`
+	Headline   string `json:"headline,omitempty"`
+	// Image - картинка из twitter:image или JSON-LD
+	Image string `json:"image,omitempty"`
+
+	TwitterCard    string `json:"twitter_card,omitempty"`
+	TwitterSite    string `json:"twitter_site,omitempty"`
+	TwitterCreator string `json:"twitter_creator,omitempty"`
+}
+
+// Ключи FeedItem.Metadata, которые заполняет обогащение со страницы.
+const (
+	MetadataSiteName       = "site_name"
+	MetadataAuthor         = "author"
+	MetadataPublishedAt    = "published_at"
+	MetadataTags           = "tags"
+	MetadataCanonicalURL   = "canonical_url"
+	MetadataSchemaType     = "schema_type"
+	MetadataHeadline       = "headline"
+	MetadataTwitterCard    = "twitter_card"
+	MetadataTwitterSite    = "twitter_site"
+	MetadataTwitterCreator = "twitter_creator"
+)
+
+// Metadata возвращает найденные на странице значения для FeedItem.Metadata, без пустых.
+func (d SiteDescription) Metadata() map[string]any {
+	metadata := make(map[string]any)
+	set := func(key, value string) {
+		if value != "" {
+			metadata[key] = value
+		}
+	}
+
+	m := d.Page
+	set(MetadataSiteName, m.SiteName)
+	set(MetadataAuthor, m.Author)
+	set(MetadataPublishedAt, m.PublishedAt)
+	set(MetadataCanonicalURL, d.Canonical)
+	set(MetadataSchemaType, m.SchemaType)
+	set(MetadataHeadline, m.Headline)
+	set(MetadataTwitterCard, m.TwitterCard)
+	set(MetadataTwitterSite, m.TwitterSite)
+	set(MetadataTwitterCreator, m.TwitterCreator)
+	if len(m.Tags) > 0 {
+		metadata[MetadataTags] = m.Tags
+	}
+
+	return metadata
+}
+
+// metaContent - content первого meta с этим name или property.
+func metaContent(doc *html.Node, key string) string {
+	for _, attr := range []string{"name", "property"} {
+		if item := dom.QuerySelector(doc, `meta[`+attr+`="`+key+`"]`); item != nil {
+			if content := strings.TrimSpace(dom.GetAttribute(item, "content")); content != "" {
+				return content
+			}
+		}
+	}
+	return ""
+}
+
+func parsePageMetadata(doc *html.Node) PageMetadata {
+	m := PageMetadata{
+		SiteName:       metaContent(doc, "og:site_name"),
+		TwitterCard:    metaContent(doc, "twitter:card"),
+		TwitterSite:    metaContent(doc, "twitter:site"),
+		TwitterCreator: metaContent(doc, "twitter:creator"),
+		Image:          metaContent(doc, "twitter:image"),
+	}
+
+	for _, item := range dom.QuerySelectorAll(doc, `meta[property="article:tag"]`) {
+		if tag := strings.TrimSpace(dom.GetAttribute(item, "content")); tag != "" {
+			m.Tags = append(m.Tags, tag)
+		}
+	}
+
+	// JSON-LD точнее meta, но есть не на всех страницах
+	if article, ok := parseJSONLDArticle(doc); ok {
+		m.SchemaType = jsonLDFirst(article["@type"])
+		m.Headline = jsonLDText(article["headline"])
+		m.Author = jsonLDNames(article["author"])
+		m.PublishedAt = jsonLDText(article["datePublished"])
+		if image := jsonLDImage(article["image"]); image != "" && m.Image == "" {
+			m.Image = image
+		}
+	}
+
+	if m.Author == "" {
+		m.Author = metaContent(doc, "author")
+	}
+	if m.Author == "" {
+		m.Author = metaContent(doc, "article:author")
+	}
+	if m.PublishedAt == "" {
+		m.PublishedAt = metaContent(doc, "article:published_time")
+	}
+
+	return m
+}
+
+// parseJSONLDArticle ищет статью во всех блоках JSON-LD страницы, в том числе в @graph.
+// Битые блоки пропускаются.
+func parseJSONLDArticle(doc *html.Node) (map[string]any, bool) {
+	for _, script := range dom.QuerySelectorAll(doc, `script[type="application/ld+json"]`) {
+		var data any
+		if err := json.Unmarshal([]byte(dom.TextContent(script)), &data); err != nil {
+			continue
+		}
+		if article, ok := findJSONLDArticle(data); ok {
+			return article, true
+		}
+	}
+	return nil, false
+}
+
+func findJSONLDArticle(data any) (map[string]any, bool) {
+	switch v := data.(type) {
+	case []any:
+		for _, node := range v {
+			if article, ok := findJSONLDArticle(node); ok {
+				return article, true
+			}
+		}
+	case map[string]any:
+		if isJSONLDArticle(v["@type"]) {
+			return v, true
+		}
+		if graph, ok := v["@graph"]; ok {
+			return findJSONLDArticle(graph)
+		}
+	}
+	return nil, false
+}
+
+// isJSONLDArticle - Article и его подтипы: NewsArticle, BlogPosting, ReportageNewsArticle и т.д.
+func isJSONLDArticle(t any) bool {
+	var types []any
+	switch v := t.(type) {
+	case string:
+		types = []any{v}
+	case []any:
+		types = v
+	}
+
+	for _, t := range types {
+		if s, ok := t.(string); ok && (strings.HasSuffix(s, "Article") || s == "BlogPosting") {
+			return true
+		}
+	}
+	return false
+}
+
+func jsonLDText(v any) string {
+	s, _ := v.(string)
+	return strings.TrimSpace(s)
+}
+
+func jsonLDFirst(v any) string {
+	if list, ok := v.([]any); ok && len(list) > 0 {
+		return jsonLDText(list[0])
+	}
+	return jsonLDText(v)
+}
+
+// jsonLDNames - имена авторов: строка, объект Person с name или их список, через запятую.
+func jsonLDNames(v any) string {
+	switch v := v.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case map[string]any:
+		return jsonLDText(v["name"])
+	case []any:
+		var names []string
+		for _, author := range v {
+			if name := jsonLDNames(author); name != "" {
+				names = append(names, name)
+			}
+		}
+		return strings.Join(names, ", ")
+	}
+	return ""
+}
+
+// jsonLDImage - адрес картинки: строка, ImageObject с url или первая из списка.
+func jsonLDImage(v any) string {
+	switch v := v.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case map[string]any:
+		return jsonLDText(v["url"])
+	case []any:
+		for _, image := range v {
+			if url := jsonLDImage(image); url != "" {
+				return url
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,251 @@
+package feed
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RobotsAgent - имя, под которым rssgram ищет свою группу в robots.txt; без неё действует группа *.
+// С enrich.user_agent группа ищется по имени продукта из него.
+const RobotsAgent = "rssgram"
+
+const (
+	// robotsTTL - сколько помнить robots.txt сайта
+	robotsTTL = 24 * time.Hour
+	// robotsErrorTTL - сколько помнить, что robots.txt не загрузился: сайт может скоро ожить
+	robotsErrorTTL = 10 * time.Minute
+	// robotsMaxSize - больше robots.txt не читается, как и у поисковиков
+	robotsMaxSize = 500 << 10
+	// robotsFetchTimeout - сколько ждать robots.txt вместе с очередью к хосту
+	robotsFetchTimeout = 30 * time.Second
+)
+
+type robotsRule struct {
+	allow   bool
+	pattern string
+}
+
+// robotsRules - правила robots.txt для одного агента. Пустые - можно всё.
+type robotsRules []robotsRule
+
+// robotsDisallowAll - правила сайта, robots.txt которого недоступен (RFC 9309, 2.3.1.4).
+var robotsDisallowAll = robotsRules{{allow: false, pattern: "/"}}
+
+// robotsAgent - имя продукта из User-Agent, по нему ищется группа в robots.txt:
+// "rssgram/1.0 (+https://example.com/bot)" - rssgram.
+func robotsAgent(userAgent string) string {
+	agent, _, _ := strings.Cut(strings.TrimSpace(userAgent), " ")
+	agent, _, _ = strings.Cut(agent, "/")
+	if agent == "" {
+		return RobotsAgent
+	}
+	return agent
+}
+
+// parseRobots выбирает из robots.txt группы agent, а если их нет - группы *.
+func parseRobots(r io.Reader, agent string) robotsRules {
+	type group struct {
+		agents []string
+		rules  robotsRules
+	}
+
+	var groups []*group
+	var current *group
+	scanner := bufio.NewScanner(io.LimitReader(r, robotsMaxSize))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			// несколько user-agent подряд - одна группа
+			if current == nil || len(current.rules) > 0 {
+				current = &group{}
+				groups = append(groups, current)
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+		case "allow", "disallow":
+			// пустой disallow ничего не запрещает
+			if current == nil || value == "" {
+				continue
+			}
+			current.rules = append(current.rules, robotsRule{allow: key == "allow", pattern: value})
+		}
+	}
+
+	agent = strings.ToLower(agent)
+	var own, all robotsRules
+	found := false
+	for _, g := range groups {
+		for _, a := range g.agents {
+			switch a {
+			case agent:
+				own = append(own, g.rules...)
+				found = true
+			case "*":
+				all = append(all, g.rules...)
+			}
+		}
+	}
+
+	if found {
+		return own
+	}
+	return all
+}
+
+// Allowed решает по самому длинному подходящему правилу; при равной длине allow важнее.
+func (r robotsRules) Allowed(u *url.URL) bool {
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	if path == "/robots.txt" {
+		return true
+	}
+
+	allowed, length := true, -1
+	for _, rule := range r {
+		if !robotsMatch(rule.pattern, path) {
+			continue
+		}
+		if len(rule.pattern) > length || (len(rule.pattern) == length && rule.allow) {
+			allowed, length = rule.allow, len(rule.pattern)
+		}
+	}
+	return allowed
+}
+
+// robotsMatch сравнивает путь с шаблоном robots.txt: * - любые символы, $ в конце - конец пути.
+func robotsMatch(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	parts := strings.Split(strings.TrimSuffix(pattern, "$"), "*")
+
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	rest := path[len(parts[0]):]
+	if len(parts) == 1 {
+		return !anchored || rest == ""
+	}
+
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(rest, part)
+		if i < 0 {
+			return false
+		}
+		rest = rest[i+len(part):]
+	}
+
+	last := parts[len(parts)-1]
+	if anchored {
+		return strings.HasSuffix(rest, last)
+	}
+	return strings.Contains(rest, last)
+}
+
+// robotsCache помнит robots.txt сайтов между проходами. Пока robots.txt загружается,
+// остальные новости того же сайта ждут его, а не загружают ещё раз.
+type robotsCache struct {
+	mu      sync.Mutex
+	entries map[string]*robotsEntry
+}
+
+type robotsEntry struct {
+	once sync.Once
+	// expires меняется под robotsCache.mu
+	expires time.Time
+	rules   robotsRules
+	err     error
+}
+
+func newRobotsCache() *robotsCache {
+	return &robotsCache{entries: make(map[string]*robotsEntry)}
+}
+
+// allowed проверяет адрес по robots.txt его сайта. Если robots.txt нет (4xx), можно всё;
+// если сайт не ответил или вернул 5xx - ничего, пока не пройдёт robotsErrorTTL.
+// Новость без страницы всё равно уйдёт с данными фида.
+func (c *robotsCache) allowed(ctx context.Context, p *SiteParser, link string) (bool, error) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse url %s: %w", link, err)
+	}
+	origin := u.Scheme + "://" + u.Host
+
+	c.mu.Lock()
+	e, ok := c.entries[origin]
+	if !ok || time.Now().After(e.expires) {
+		e = &robotsEntry{expires: time.Now().Add(robotsTTL)}
+		c.entries[origin] = e
+	}
+	c.mu.Unlock()
+
+	e.once.Do(func() {
+		// результат общий для всех проходов: отменённый проход не должен закрыть сайт
+		// на robotsErrorTTL, поэтому robots.txt загружается со своим таймаутом
+		fetchCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), robotsFetchTimeout)
+		defer cancel()
+
+		e.rules, e.err = p.getRobots(fetchCtx, origin)
+		if e.err != nil {
+			e.rules = robotsDisallowAll
+
+			c.mu.Lock()
+			e.expires = time.Now().Add(robotsErrorTTL)
+			c.mu.Unlock()
+		}
+	})
+
+	return e.rules.Allowed(u), e.err
+}
+
+func (p *SiteParser) getRobots(ctx context.Context, origin string) (robotsRules, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, origin+"/robots.txt", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if err = p.wait(ctx, req.URL.Host); err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", p.robotsUserAgent())
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get robots.txt of %s: %w", origin, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= http.StatusInternalServerError:
+		return nil, fmt.Errorf("failed to get robots.txt of %s: status %d", origin, resp.StatusCode)
+	case resp.StatusCode != http.StatusOK:
+		return nil, nil
+	}
+
+	return parseRobots(resp.Body, robotsAgent(req.UserAgent())), nil
+}
+
+// robotsUserAgent - User-Agent запросов к robots.txt: браузерный для них не годится,
+// по его имени продукта ищется группа правил.
+func (p *SiteParser) robotsUserAgent() string {
+	if p.userAgent != "" {
+		return p.userAgent
+	}
+	return DefaultUserAgent
+}
@@ -0,0 +1,188 @@
+package feed
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRobotsRules_Allowed(t *testing.T) {
+	robots := `
+# comment
+User-agent: Googlebot
+Disallow: /
+
+User-agent: *
+Disallow: /private
+Allow: /private/public
+Disallow: /*.pdf$
+Disallow: /search*q=
+
+user-agent: rssgram
+user-agent: otherbot
+disallow: /drafts/ # черновики
+allow: /drafts/published
+`
+
+	testCases := []struct {
+		name     string
+		agent    string
+		path     string
+		expected bool
+	}{
+		{name: "own group", agent: "rssgram", path: "/drafts/1", expected: false},
+		{name: "own group longer allow", agent: "rssgram", path: "/drafts/published/1", expected: true},
+		{name: "own group replaces star", agent: "rssgram", path: "/private/1", expected: true},
+		{name: "agent case", agent: "RSSGram", path: "/drafts/1", expected: false},
+		{name: "star group", agent: "somebot", path: "/private/1", expected: false},
+		{name: "star group allow", agent: "somebot", path: "/private/public/1", expected: true},
+		{name: "anchored wildcard", agent: "somebot", path: "/files/doc.pdf", expected: false},
+		{name: "anchored wildcard not at end", agent: "somebot", path: "/files/doc.pdf?x=1", expected: true},
+		{name: "wildcard with query", agent: "somebot", path: "/search?page=2&q=go", expected: false},
+		{name: "not matched", agent: "somebot", path: "/news/1", expected: true},
+		{name: "robots.txt itself", agent: "googlebot", path: "/robots.txt", expected: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			u, err := url.Parse("https://example.com" + tc.path)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, parseRobots(strings.NewReader(robots), tc.agent).Allowed(u))
+		})
+	}
+}
+
+func TestRobotsRules_Empty(t *testing.T) {
+	u, err := url.Parse("https://example.com/anything")
+	require.NoError(t, err)
+
+	assert.True(t, parseRobots(strings.NewReader(""), RobotsAgent).Allowed(u))
+	// пустой disallow ничего не запрещает
+	assert.True(t, parseRobots(strings.NewReader("User-agent: *\nDisallow:\n"), RobotsAgent).Allowed(u))
+	// своя группа без правил важнее запретов для всех
+	assert.True(t, parseRobots(strings.NewReader("User-agent: *\nDisallow: /\n\nUser-agent: rssgram\nDisallow:\n"), RobotsAgent).Allowed(u))
+}
+
+func TestHostLimiter(t *testing.T) {
+	l := newHostLimiter()
+	ctx := context.Background()
+	delay := 50 * time.Millisecond
+
+	start := time.Now()
+	require.NoError(t, l.wait(ctx, "a.example.com", delay))
+	require.NoError(t, l.wait(ctx, "b.example.com", delay))
+	assert.Less(t, time.Since(start), delay, "different hosts don't wait for each other")
+
+	require.NoError(t, l.wait(ctx, "a.example.com", delay))
+	assert.GreaterOrEqual(t, time.Since(start), delay)
+
+	// хосты, пауза которых прошла, не копятся
+	time.Sleep(2 * delay)
+	require.NoError(t, l.wait(ctx, "c.example.com", delay))
+	l.mu.Lock()
+	assert.Len(t, l.next, 1)
+	l.mu.Unlock()
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.ErrorIs(t, l.wait(cancelled, "c.example.com", time.Hour), context.Canceled)
+}
+
+func TestRobotsAgent(t *testing.T) {
+	assert.Equal(t, "rssgram", robotsAgent(DefaultUserAgent))
+	assert.Equal(t, "mybot", robotsAgent("mybot/2.1 (+https://example.com/bot)"))
+	assert.Equal(t, "mybot", robotsAgent("mybot"))
+	assert.Equal(t, RobotsAgent, robotsAgent(""))
+}
+
+func TestRobotsCache_Allowed(t *testing.T) {
+	var status atomic.Int32
+	var hits atomic.Int32
+	var userAgent atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		userAgent.Store(r.UserAgent())
+		w.WriteHeader(int(status.Load()))
+		w.Write([]byte("User-agent: *\nDisallow: /\n\nUser-agent: rssgram\nDisallow: /private\n"))
+	}))
+	defer server.Close()
+
+	p := NewSiteParser()
+	ctx := context.Background()
+
+	testCases := []struct {
+		name    string
+		status  int
+		path    string
+		allowed bool
+		err     bool
+	}{
+		{name: "own group", status: http.StatusOK, path: "/news/1", allowed: true},
+		{name: "own group disallow", status: http.StatusOK, path: "/private/1", allowed: false},
+		{name: "no robots.txt", status: http.StatusNotFound, path: "/private/1", allowed: true},
+		{name: "server error", status: http.StatusServiceUnavailable, path: "/news/1", allowed: false, err: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			status.Store(int32(tc.status))
+			c := newRobotsCache()
+
+			allowed, err := c.allowed(ctx, p, server.URL+tc.path)
+			assert.Equal(t, tc.allowed, allowed)
+			if tc.err {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, DefaultUserAgent, userAgent.Load())
+		})
+	}
+
+	// ошибка помнится недолго: после robotsErrorTTL robots.txt загружается снова
+	status.Store(http.StatusInternalServerError)
+	c := newRobotsCache()
+	allowed, _ := c.allowed(ctx, p, server.URL+"/news/1")
+	assert.False(t, allowed)
+
+	origin := server.URL
+	c.mu.Lock()
+	assert.WithinDuration(t, time.Now().Add(robotsErrorTTL), c.entries[origin].expires, time.Minute)
+	c.entries[origin].expires = time.Now().Add(-time.Second)
+	c.mu.Unlock()
+
+	status.Store(http.StatusOK)
+	before := hits.Load()
+	allowed, err := c.allowed(ctx, p, server.URL+"/news/1")
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	assert.Equal(t, before+1, hits.Load())
+}
+
+func TestRobotsCache_Allowed_CancelledPass(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("User-agent: *\nDisallow: /private\n"))
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// robots.txt общий для проходов: отменённый проход не запоминает ошибку
+	c := newRobotsCache()
+	allowed, err := c.allowed(ctx, NewSiteParser(), server.URL+"/news/1")
+	require.NoError(t, err)
+	assert.True(t, allowed)
+
+	allowed, err = c.allowed(context.Background(), NewSiteParser(), server.URL+"/private/1")
+	require.NoError(t, err)
+	assert.False(t, allowed)
+}
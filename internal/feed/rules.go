@@ -0,0 +1,177 @@
+package feed
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"slices"
+	"time"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+)
+
+// DefaultOutput - выход из секции telegram, куда уходят новости без правил маршрутизации.
+const DefaultOutput = "default"
+
+// RoutingRule - правило маршрутизации. When - выражение expr (https://expr-lang.org)
+// над item, feed и metadata, которое должно вернуть bool. Применяется первое сработавшее
+// правило: drop отбрасывает новость, outputs заменяет выход default, silent включает
+// или выключает уведомление вместо тихого режима выхода.
+type RoutingRule struct {
+	// имя правила в метриках и логах; по умолчанию - выражение when
+	Name    string   `yaml:"name"`
+	When    string   `yaml:"when"`
+	Drop    bool     `yaml:"drop"`
+	Outputs []string `yaml:"outputs"`
+	Silent  *bool    `yaml:"silent"`
+}
+
+func (r RoutingRule) name() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return r.When
+}
+
+// ruleEnv - переменные, доступные в выражениях правил.
+type ruleEnv struct {
+	Item     ruleItem       `expr:"item"`
+	Feed     ruleFeed       `expr:"feed"`
+	Metadata map[string]any `expr:"metadata"`
+}
+
+type ruleItem struct {
+	Title string `expr:"title"`
+	// описание без HTML, как в фильтрах
+	Description string    `expr:"description"`
+	Link        string    `expr:"link"`
+	ImageURL    string    `expr:"image_url"`
+	Author      string    `expr:"author"`
+	Tags        []string  `expr:"tags"`
+	FeedTitle   string    `expr:"feed_title"`
+	PublishedAt time.Time `expr:"published_at"`
+}
+
+type ruleFeed struct {
+	Name            string   `expr:"name"`
+	URL             string   `expr:"url"`
+	Key             string   `expr:"key"`
+	DescriptionType string   `expr:"description_type"`
+	Tags            []string `expr:"tags"`
+}
+
+func newRuleEnv(f FeedConfig, item *FeedItem) ruleEnv {
+	metadata := item.Metadata
+	if metadata == nil {
+		metadata = map[string]any{}
+	}
+
+	return ruleEnv{
+		Item: ruleItem{
+			Title:       item.Title,
+			Description: html.UnescapeString(stripTags.Sanitize(item.Description)),
+			Link:        item.Link,
+			ImageURL:    item.ImageURL,
+			Author:      item.Author,
+			Tags:        item.Tags,
+			FeedTitle:   item.FeedTitle,
+			PublishedAt: item.GetPublishedAt(time.Now()),
+		},
+		Feed: ruleFeed{
+			Name:            f.Name,
+			URL:             f.URL,
+			Key:             f.Key,
+			DescriptionType: f.DescriptionType,
+			Tags:            f.Tags,
+		},
+		Metadata: metadata,
+	}
+}
+
+type compiledRoutingRule struct {
+	RoutingRule
+	program *vm.Program
+}
+
+func compileRoutingRule(rule RoutingRule) (compiledRoutingRule, error) {
+	if rule.When == "" {
+		return compiledRoutingRule{}, fmt.Errorf("when is required")
+	}
+	if rule.Drop && (len(rule.Outputs) > 0 || rule.Silent != nil) {
+		return compiledRoutingRule{}, fmt.Errorf("drop can't be combined with outputs or silent")
+	}
+	if slices.Contains(rule.Outputs, "") {
+		return compiledRoutingRule{}, fmt.Errorf("output name can't be empty")
+	}
+
+	program, err := expr.Compile(rule.When, expr.Env(ruleEnv{}), expr.AsBool())
+	if err != nil {
+		return compiledRoutingRule{}, fmt.Errorf("invalid expression: %w", err)
+	}
+
+	return compiledRoutingRule{RoutingRule: rule, program: program}, nil
+}
+
+// ValidateRoutingRule проверяет правило и компилирует выражение, для сообщений об ошибках конфига.
+// Существование выходов проверяет конфиг.
+func ValidateRoutingRule(rule RoutingRule) error {
+	_, err := compileRoutingRule(rule)
+	return err
+}
+
+// Router - скомпилированные правила маршрутизации.
+type Router struct {
+	rules []compiledRoutingRule
+}
+
+func NewRouter(rules []RoutingRule) (*Router, error) {
+	compiled := make([]compiledRoutingRule, 0, len(rules))
+	for i, rule := range rules {
+		r, err := compileRoutingRule(rule)
+		if err != nil {
+			return nil, fmt.Errorf("rules[%d]: %w", i, err)
+		}
+		compiled = append(compiled, r)
+	}
+	return &Router{rules: compiled}, nil
+}
+
+// Route - решение правила для новости. Пустой Rule - ни одно правило не сработало.
+type Route struct {
+	Rule    string
+	Drop    bool
+	Outputs []string
+	Silent  *bool
+}
+
+// Route находит первое сработавшее правило. Правила, выражение которых упало
+// на этой новости, пропускаются, их ошибки возвращаются вместе с решением.
+func (r *Router) Route(f FeedConfig, item *FeedItem) (Route, error) {
+	if r == nil || len(r.rules) == 0 {
+		return Route{}, nil
+	}
+
+	env := newRuleEnv(f, item)
+
+	var errs []error
+	for _, rule := range r.rules {
+		out, err := expr.Run(rule.program, env)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("rule %s: %w", rule.name(), err))
+			continue
+		}
+		if matched, _ := out.(bool); !matched {
+			continue
+		}
+
+		return Route{
+			Rule:    rule.name(),
+			Drop:    rule.Drop,
+			Outputs: rule.Outputs,
+			Silent:  rule.Silent,
+		}, errors.Join(errs...)
+	}
+
+	return Route{}, errors.Join(errs...)
+}
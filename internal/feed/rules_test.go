@@ -0,0 +1,151 @@
+package feed
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestRouter_Route(t *testing.T) {
+	silent := true
+	published := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	feedA := FeedConfig{Name: "A", URL: "https://a.example.com/rss", Tags: []string{"infosec"}}
+	item := FeedItem{
+		Title:       "Critical vulnerability in OpenSSH",
+		Description: "<p>Patch <b>now</b></p>",
+		Link:        "https://a.example.com/cve",
+		Author:      "Alice",
+		Tags:        []string{"security", "linux"},
+		PublishedAt: &published,
+		Metadata:    map[string]any{"score": 9.8},
+	}
+
+	tests := []struct {
+		name     string
+		rules    []RoutingRule
+		feed     FeedConfig
+		expected Route
+		err      bool
+	}{
+		{
+			name:     "no rules",
+			feed:     feedA,
+			expected: Route{},
+		},
+		{
+			name: "route by feed, tag and title length",
+			rules: []RoutingRule{{
+				Name:    "security",
+				When:    `feed.name == "A" && "security" in item.tags && len(item.title) > 20`,
+				Outputs: []string{"security"},
+				Silent:  &silent,
+			}},
+			feed:     feedA,
+			expected: Route{Rule: "security", Outputs: []string{"security"}, Silent: &silent},
+		},
+		{
+			name:     "other feed",
+			rules:    []RoutingRule{{When: `feed.name == "A"`, Outputs: []string{"security"}}},
+			feed:     FeedConfig{Name: "B"},
+			expected: Route{},
+		},
+		{
+			name: "first matching rule wins",
+			rules: []RoutingRule{
+				{Name: "linux", When: `"linux" in item.tags`, Outputs: []string{"linux"}},
+				{Name: "security", When: `"security" in item.tags`, Outputs: []string{"security"}},
+			},
+			feed:     feedA,
+			expected: Route{Rule: "linux", Outputs: []string{"linux"}},
+		},
+		{
+			name:     "drop by metadata and description text",
+			rules:    []RoutingRule{{When: `metadata.score > 9 && item.description contains "Patch now"`, Drop: true}},
+			feed:     feedA,
+			expected: Route{Rule: `metadata.score > 9 && item.description contains "Patch now"`, Drop: true},
+		},
+		{
+			name:     "dates and functions",
+			rules:    []RoutingRule{{Name: "old", When: `item.published_at < now() && lower(item.author) == "alice" && item.link startsWith "https://a."`, Silent: &silent}},
+			feed:     feedA,
+			expected: Route{Rule: "old", Silent: &silent},
+		},
+		{
+			name: "failed rule is skipped",
+			rules: []RoutingRule{
+				{Name: "broken", When: `metadata.missing.value > 1`, Drop: true},
+				{Name: "default", When: `true`, Outputs: []string{"default"}},
+			},
+			feed:     feedA,
+			expected: Route{Rule: "default", Outputs: []string{"default"}},
+			err:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, err := NewRouter(tt.rules)
+			require.NoError(t, err)
+
+			route, err := router.Route(tt.feed, &item)
+			if tt.err {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.expected, route)
+		})
+	}
+}
+
+func TestValidateRoutingRule(t *testing.T) {
+	silent := false
+
+	tests := []struct {
+		name string
+		rule RoutingRule
+		err  string
+	}{
+		{name: "valid", rule: RoutingRule{When: `"go" in feed.tags`, Outputs: []string{"go"}}},
+		{name: "empty when", rule: RoutingRule{Drop: true}, err: "when is required"},
+		{name: "syntax error", rule: RoutingRule{When: `item.title ==`, Drop: true}, err: "invalid expression"},
+		{name: "unknown field", rule: RoutingRule{When: `item.body contains "x"`, Drop: true}, err: "invalid expression"},
+		{name: "not bool", rule: RoutingRule{When: `item.title`, Drop: true}, err: "invalid expression"},
+		{name: "drop with outputs", rule: RoutingRule{When: `true`, Drop: true, Silent: &silent}, err: "drop can't be combined"},
+		{name: "empty output", rule: RoutingRule{When: `true`, Outputs: []string{""}}, err: "output name can't be empty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateRoutingRule(tt.rule)
+			if tt.err == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+}
+
+func TestFeedConfig_Compile_Rules(t *testing.T) {
+	conf, err := FeedConfig{
+		URL:   "https://example.com/rss",
+		Rules: []RoutingRule{{When: `item.title contains "Ads"`, Drop: true}},
+	}.Compile()
+	require.NoError(t, err)
+
+	// проход берёт собранные правила, а не компилирует Rules заново
+	conf.Rules = []RoutingRule{{When: `item.title contains "News"`, Drop: true}}
+	feed := &Feed{Config: conf, Items: []FeedItem{{Title: "News"}, {Title: "Ads"}}}
+	require.NoError(t, NewManager(nil).applyRules(feed, zap.NewNop()))
+	require.Len(t, feed.Items, 1)
+	assert.Equal(t, "News", feed.Items[0].Title)
+
+	_, err = FeedConfig{Rules: []RoutingRule{{When: "item.title +"}}}.Compile()
+	assert.ErrorContains(t, err, "invalid rules")
+}
@@ -0,0 +1,95 @@
+package feed
+
+import (
+	"hash/fnv"
+	"html"
+	"math/bits"
+	"strings"
+	"unicode"
+)
+
+const (
+	// shingleSize - длина шингла в рунах: шинглы внутри слова сглаживают разницу
+	// в окончаниях (в русском особенно), а сами слова - перестановки
+	shingleSize = 3
+	// описание переписывают сильнее заголовка, поэтому берётся только его начало и с меньшим весом
+	fingerprintDescriptionRunes = 300
+	titleWeight                 = 2
+	descriptionWeight           = 1
+)
+
+// normalizeText оставляет буквы и цифры в нижнем регистре, разделённые одним пробелом; ё приводится к е.
+func normalizeText(s string) string {
+	var b strings.Builder
+	space := true
+	for _, r := range strings.ToLower(s) {
+		if r == 'ё' {
+			r = 'е'
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			space = false
+			continue
+		}
+		if !space {
+			b.WriteRune(' ')
+			space = true
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// addShingles добавляет признаки текста: каждое слово и шинглы слова с пробелами по краям.
+func addShingles(weights *[64]int, text string, weight int) {
+	for _, word := range strings.Fields(text) {
+		addFeature(weights, word, weight)
+
+		runes := []rune(" " + word + " ")
+		for i := 0; i+shingleSize <= len(runes); i++ {
+			addFeature(weights, string(runes[i:i+shingleSize]), weight)
+		}
+	}
+}
+
+func addFeature(weights *[64]int, feature string, weight int) {
+	h := fnv.New64a()
+	h.Write([]byte(feature))
+	sum := h.Sum64()
+	for i := range weights {
+		if sum&(1<<i) != 0 {
+			weights[i] += weight
+		} else {
+			weights[i] -= weight
+		}
+	}
+}
+
+// Fingerprint - SimHash заголовка и начала описания новости. У переписанных версий одной
+// новости отпечатки отличаются в немногих битах. 0 - текста нет, сравнивать не с чем.
+func Fingerprint(title, description string) uint64 {
+	title = normalizeText(title)
+	description = normalizeText(html.UnescapeString(stripTags.Sanitize(description)))
+	if runes := []rune(description); len(runes) > fingerprintDescriptionRunes {
+		description = string(runes[:fingerprintDescriptionRunes])
+	}
+	if title == "" && description == "" {
+		return 0
+	}
+
+	var weights [64]int
+	addShingles(&weights, title, titleWeight)
+	addShingles(&weights, description, descriptionWeight)
+
+	var fingerprint uint64
+	for i, w := range weights {
+		if w > 0 {
+			fingerprint |= 1 << i
+		}
+	}
+	return fingerprint
+}
+
+// Similarity - доля совпадающих битов двух отпечатков, от 0 до 1.
+func Similarity(a, b uint64) float64 {
+	return 1 - float64(bits.OnesCount64(a^b))/64
+}
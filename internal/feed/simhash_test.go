@@ -0,0 +1,95 @@
+package feed
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeText(t *testing.T) {
+	assert.Equal(t, "еще нет", normalizeText("ЕЩЁ, нет!"))
+	assert.Equal(t, "go 1 23 вышел", normalizeText("  Go 1.23 — вышел!!! "))
+	assert.Equal(t, "", normalizeText(" «» ... "))
+}
+
+// TestFingerprint_Similarity проверяет, что переписанные версии одной новости похожи сильнее порога
+// из примера конфига, а разные новости, в том числе с общими словами, - слабее.
+func TestFingerprint_Similarity(t *testing.T) {
+	const threshold = 0.85
+
+	tests := []struct {
+		name    string
+		a, b    [2]string
+		similar bool
+	}{
+		{
+			name:    "cyrillic rewrite",
+			a:       [2]string{"ЦБ сохранил ключевую ставку на уровне 16%", "Банк России оставил ключевую ставку без изменений."},
+			b:       [2]string{"Банк России сохранил ключевую ставку на уровне 16%", "Совет директоров Банка России оставил ключевую ставку без изменений."},
+			similar: true,
+		},
+		{
+			name:    "latin reordered",
+			a:       [2]string{"Apple unveils new MacBook Pro with M4 chip", ""},
+			b:       [2]string{"New MacBook Pro with M4 chip unveiled by Apple", ""},
+			similar: true,
+		},
+		{
+			name:    "same title, different description",
+			a:       [2]string{"Go 1.23 is released", "<p>The Go team is happy to announce Go 1.23.</p>"},
+			b:       [2]string{"Go 1.23 is released", "Iterators, new packages and toolchain improvements."},
+			similar: true,
+		},
+		{
+			name: "cyrillic different stories",
+			a:    [2]string{"ЦБ сохранил ключевую ставку на уровне 16%", ""},
+			b:    [2]string{"В Москве открыли новую станцию метро", ""},
+		},
+		{
+			name: "latin different stories",
+			a:    [2]string{"Apple unveils new MacBook Pro with M4 chip", ""},
+			b:    [2]string{"Google announces Gemini update for Android", ""},
+		},
+		{
+			name:    "cyrillic reordered",
+			a:       [2]string{"Apple представила новый MacBook Pro с чипом M4", ""},
+			b:       [2]string{"Новый MacBook Pro с чипом M4 представлен Apple", ""},
+			similar: true,
+		},
+		{
+			name: "cyrillic same topic, different fact",
+			a:    [2]string{"ЦБ сохранил ключевую ставку на уровне 16%", ""},
+			b:    [2]string{"ЦБ повысил ключевую ставку до 18%", ""},
+		},
+		{
+			name: "latin same template, different product",
+			a:    [2]string{"Apple unveils new MacBook Pro with M4 chip", ""},
+			b:    [2]string{"Apple unveils new iPad Air with M3 chip", ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			similarity := Similarity(Fingerprint(tt.a[0], tt.a[1]), Fingerprint(tt.b[0], tt.b[1]))
+			if tt.similar {
+				assert.GreaterOrEqual(t, similarity, threshold)
+			} else {
+				assert.Less(t, similarity, threshold)
+			}
+		})
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	// регистр, ё, пунктуация и разметка не влияют на отпечаток
+	assert.Equal(t,
+		Fingerprint("Ёлки: всё о праздниках", "<b>Подробности</b>"),
+		Fingerprint("елки — все о праздниках!", "Подробности"),
+	)
+	assert.Equal(t, uint64(0), Fingerprint("", ""))
+	assert.Equal(t, uint64(0), Fingerprint(" — ", "<br/>"))
+	assert.NotEqual(t, uint64(0), Fingerprint("Go", ""))
+
+	assert.Equal(t, 1.0, Similarity(42, 42))
+	assert.Equal(t, 0.0, Similarity(0, ^uint64(0)))
+}
@@ -1,16 +1,19 @@
 package feed
 
 import (
+	"context"
 	"fmt"
 	"math/rand/v2"
 	"mime"
 	"net/http"
+	nurl "net/url"
 	"strings"
 	"time"
 
 	"golang.org/x/net/html"
 
 	"github.com/go-shiori/dom"
+	"github.com/go-shiori/go-readability"
 )
 
 var userAgents = []string{
@@ -18,35 +21,92 @@ var userAgents = []string{
 }
 
 type SiteDescription struct {
-	Title       string
-	Description string
-	Image       string
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Image       string `json:"image,omitempty"`
+	// Canonical - адрес страницы из <link rel="canonical"> или og:url
+	Canonical string `json:"canonical,omitempty"`
+	// Text - основной текст статьи без разметки, абзацы через пустую строку; только в GetFulltext
+	Text string `json:"text,omitempty"`
+	// Page - остальная разметка страницы, см. Metadata
+	Page PageMetadata `json:"page"`
 }
 
 type SiteParser struct {
 	client *http.Client
+	// userAgent заменяет браузерный User-Agent
+	userAgent string
+	// hosts разносит запросы к одному хосту на hostDelay
+	hosts     *hostLimiter
+	hostDelay time.Duration
 }
 
 func (p *SiteParser) setUA(req *http.Request) {
+	if p.userAgent != "" {
+		req.Header.Set("User-Agent", p.userAgent)
+		return
+	}
 	if len(userAgents) > 0 {
 		req.Header.Set("User-Agent", userAgents[rand.IntN(len(userAgents))])
 	}
 }
 
 func (p *SiteParser) GetDescription(url string) (SiteDescription, error) {
+	return p.describe(context.Background(), url, false)
+}
+
+// GetFulltext, кроме описания из meta, выделяет основной текст статьи (readability)
+// и первую картинку в нём. Если текст выделить не удалось, Text остаётся пустым.
+func (p *SiteParser) GetFulltext(url string) (SiteDescription, error) {
+	return p.describe(context.Background(), url, true)
+}
+
+func (p *SiteParser) describe(ctx context.Context, url string, fulltext bool) (SiteDescription, error) {
+	doc, pageURL, err := p.getDocument(ctx, url)
+	if err != nil || doc == nil {
+		return SiteDescription{}, err
+	}
+
+	result := p.parseDescription(doc, pageURL)
+	if !fulltext {
+		return result, nil
+	}
+
+	// readability работает с копией документа
+	article, err := readability.FromDocument(doc, pageURL)
+	if err != nil || article.Node == nil {
+		return result, nil
+	}
+
+	result.Text = articleText(article.Node)
+	if img := dom.QuerySelector(article.Node, "img[src]"); img != nil {
+		image := dom.GetAttribute(img, "src")
+		if isValid, _ := p.isImageURLValid(image); isValid {
+			result.Image = image
+		}
+	}
+
+	return result, nil
+}
 
-	req, err := http.NewRequest("GET", url, nil)
+// getDocument загружает страницу. Для ответа не в HTML документ nil без ошибки.
+func (p *SiteParser) getDocument(ctx context.Context, url string) (*html.Node, *nurl.URL, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return SiteDescription{}, fmt.Errorf("failed to  create request: %w", err)
+		return nil, nil, fmt.Errorf("failed to  create request: %w", err)
+	}
+	if err = p.wait(ctx, req.URL.Host); err != nil {
+		return nil, nil, err
 	}
 
 	p.setUA(req)
 
 	resp, err := p.client.Do(req)
 	if err != nil {
-		return SiteDescription{}, fmt.Errorf("failed to get content by url %s: %w", url, err)
+		return nil, nil, fmt.Errorf("failed to get content by url %s: %w", url, err)
 	} else if resp.StatusCode > http.StatusPermanentRedirect {
-		return SiteDescription{}, fmt.Errorf("failed to get content by url %s: status %s", url, resp.Status)
+		resp.Body.Close()
+		return nil, nil, fmt.Errorf("failed to get content by url %s: status %s", url, resp.Status)
 	}
 
 	defer func() {
@@ -57,22 +117,27 @@ func (p *SiteParser) GetDescription(url string) (SiteDescription, error) {
 
 	contentTypes := resp.Header["Content-Type"]
 	if len(contentTypes) == 0 {
-		return SiteDescription{}, fmt.Errorf("Content-Type header is missing")
+		return nil, nil, fmt.Errorf("Content-Type header is missing")
 	}
 	mediaType, _, err := mime.ParseMediaType(contentTypes[0])
 	if err != nil {
-		return SiteDescription{}, fmt.Errorf("failed to parse media type: %w", err)
+		return nil, nil, fmt.Errorf("failed to parse media type: %w", err)
 	}
 
 	if mediaType != "text/html" {
-		return SiteDescription{}, nil
+		return nil, nil, nil
 	}
 
 	doc, err := html.Parse(resp.Body)
 	if err != nil {
-		return SiteDescription{}, fmt.Errorf("failed to parse html: %w", err)
+		return nil, nil, fmt.Errorf("failed to parse html: %w", err)
 	}
 
+	// относительные адреса считаются от страницы после редиректов
+	return doc, resp.Request.URL, nil
+}
+
+func (p *SiteParser) parseDescription(doc *html.Node, pageURL *nurl.URL) SiteDescription {
 	var title, description, image string
 
 	// get site title
@@ -80,6 +145,9 @@ func (p *SiteParser) GetDescription(url string) (SiteDescription, error) {
 	if titleItem != nil {
 		title = dom.InnerText(titleItem)
 	}
+	if title == "" {
+		title = metaContent(doc, "twitter:title")
+	}
 
 	// get site description
 	regularDescriptionItem := dom.QuerySelector(doc, "meta[name=description]")
@@ -93,10 +161,30 @@ func (p *SiteParser) GetDescription(url string) (SiteDescription, error) {
 			description = dom.GetAttribute(ogDescriptionItem, "content")
 		}
 	}
+	if description == "" {
+		description = metaContent(doc, "twitter:description")
+	}
 
 	result := SiteDescription{
 		Title:       title,
 		Description: description,
+		Page:        parsePageMetadata(doc),
+	}
+
+	// get canonical url
+	var canonical string
+	if canonicalItem := dom.QuerySelector(doc, `link[rel="canonical"]`); canonicalItem != nil {
+		canonical = dom.GetAttribute(canonicalItem, "href")
+	}
+	if canonical == "" {
+		if ogURLItem := dom.QuerySelector(doc, `meta[property="og:url"]`); ogURLItem != nil {
+			canonical = dom.GetAttribute(ogURLItem, "content")
+		}
+	}
+	if canonical != "" {
+		if u, err := pageURL.Parse(strings.TrimSpace(canonical)); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+			result.Canonical = u.String()
+		}
 	}
 
 	// get image
@@ -111,6 +199,9 @@ func (p *SiteParser) GetDescription(url string) (SiteDescription, error) {
 			image = dom.GetAttribute(ogImageItem, "content")
 		}
 	}
+	if image == "" {
+		image = result.Page.Image
+	}
 
 	if image != "" {
 		isValid, _ := p.isImageURLValid(image)
@@ -121,7 +212,69 @@ func (p *SiteParser) GetDescription(url string) (SiteDescription, error) {
 
 	}
 
-	return result, nil
+	return result
+}
+
+// articleBlocks - элементы, каждый из которых становится абзацем текста статьи.
+var articleBlocks = map[string]bool{
+	"p": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"li": true, "blockquote": true, "pre": true, "figcaption": true, "dt": true, "dd": true, "td": true,
+}
+
+// articleInline - элементы внутри абзаца.
+var articleInline = map[string]bool{
+	"a": true, "span": true, "b": true, "strong": true, "i": true, "em": true, "u": true, "s": true,
+	"code": true, "mark": true, "small": true, "sub": true, "sup": true, "abbr": true, "time": true,
+}
+
+// articleText собирает текст статьи по абзацам, разделённым пустой строкой. Текст вне
+// абзацев (например, прямо в div) тоже становится отдельным абзацем.
+func articleText(root *html.Node) string {
+	var paragraphs []string
+	var inline strings.Builder
+
+	flush := func() {
+		if text := strings.Join(strings.Fields(inline.String()), " "); text != "" {
+			paragraphs = append(paragraphs, text)
+		}
+		inline.Reset()
+	}
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			switch {
+			case c.Type == html.TextNode:
+				inline.WriteString(c.Data)
+			case c.Type != html.ElementNode:
+			case c.Data == "br":
+				inline.WriteString(" ")
+			case articleBlocks[c.Data] && !hasBlock(c):
+				flush()
+				inline.WriteString(dom.TextContent(c))
+				flush()
+			case articleInline[c.Data]:
+				walk(c)
+			default:
+				flush()
+				walk(c)
+				flush()
+			}
+		}
+	}
+	walk(root)
+	flush()
+
+	return strings.Join(paragraphs, "\n\n")
+}
+
+func hasBlock(n *html.Node) bool {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && (articleBlocks[c.Data] || hasBlock(c)) {
+			return true
+		}
+	}
+	return false
 }
 
 func (p *SiteParser) isImageURLValid(url string) (bool, error) {
@@ -185,3 +338,11 @@ func NewSiteParser() *SiteParser {
 		client: &client,
 	}
 }
+
+// wait ждёт очереди запроса к host, если задан hostDelay.
+func (p *SiteParser) wait(ctx context.Context, host string) error {
+	if p.hosts == nil || p.hostDelay <= 0 {
+		return nil
+	}
+	return p.hosts.wait(ctx, host, p.hostDelay)
+}
@@ -4,9 +4,11 @@ package feed
 import (
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestSiteParser_GetDescription(t *testing.T) {
@@ -45,6 +47,35 @@ func TestSiteParser_GetDescription(t *testing.T) {
 	assert.Equal(t, "", description.Image)
 }
 
+func TestSiteParser_GetDescription_Canonical(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/relative":
+			w.Write([]byte(`<html><head><link rel="canonical" href="/posts/1"></head></html>`))
+		case "/og":
+			w.Write([]byte(`<html><head><meta property="og:url" content="https://origin.example.com/story"></head></html>`))
+		default:
+			w.Write([]byte(`<html><head><title>No canonical</title></head></html>`))
+		}
+	}))
+	defer server.Close()
+
+	parser := NewSiteParser()
+
+	description, err := parser.GetDescription(server.URL + "/relative")
+	assert.NoError(t, err)
+	assert.Equal(t, server.URL+"/posts/1", description.Canonical)
+
+	description, err = parser.GetDescription(server.URL + "/og")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://origin.example.com/story", description.Canonical)
+
+	description, err = parser.GetDescription(server.URL + "/none")
+	assert.NoError(t, err)
+	assert.Empty(t, description.Canonical)
+}
+
 func TestSiteParser_GetDescription_NoMeta(t *testing.T) {
 	// Create a test server without meta-tags
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -193,3 +224,118 @@ func TestSiteParser_GetDescription_OpenGraph(t *testing.T) {
 	assert.Equal(t, "Open Graph Description", description.Description) // if the parser supports og:description, otherwise empty
 	assert.Equal(t, "", description.Image)
 }
+
+func TestSiteParser_GetFulltext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/lead.jpg":
+			w.Header().Set("Content-Type", "image/jpeg")
+		case "/empty":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><head><title>Empty</title><meta name="description" content="Teaser"></head><body></body></html>`))
+		default:
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<!DOCTYPE html>
+<html>
+<head>
+	<title>Article Title</title>
+	<meta name="description" content="Teaser">
+</head>
+<body>
+	<nav><a href="/">Home</a> <a href="/about">About</a></nav>
+	<article>
+		<h1>Article Title</h1>
+		<img src="/lead.jpg">
+		<p>Первый абзац статьи, в котором   достаточно текста, чтобы readability посчитал его
+		основным содержимым страницы, а не навигацией или рекламой.</p>
+		<p>Второй абзац со <a href="/link">ссылкой</a> и <b>выделением</b>, он тоже длинный,
+		потому что короткие абзацы алгоритм может отбросить как мусор, а нам нужен весь текст.</p>
+		<ul><li>Пункт списка</li></ul>
+	</article>
+	<footer>Copyright</footer>
+</body>
+</html>`))
+		}
+	}))
+	defer server.Close()
+
+	parser := NewSiteParser()
+
+	description, err := parser.GetFulltext(server.URL + "/article")
+	require.NoError(t, err)
+	assert.Equal(t, "Teaser", description.Description)
+	assert.Equal(t, server.URL+"/lead.jpg", description.Image)
+
+	paragraphs := strings.Split(description.Text, "\n\n")
+	require.Len(t, paragraphs, 3, description.Text)
+	assert.Equal(t, "Первый абзац статьи, в котором достаточно текста, чтобы readability посчитал его основным содержимым страницы, а не навигацией или рекламой.", paragraphs[0])
+	assert.Contains(t, paragraphs[1], "со ссылкой и выделением")
+	assert.Equal(t, "Пункт списка", paragraphs[2])
+	assert.NotContains(t, description.Text, "Home")
+	assert.NotContains(t, description.Text, "Copyright")
+
+	// без текста остаётся описание из meta
+	description, err = parser.GetFulltext(server.URL + "/empty")
+	require.NoError(t, err)
+	assert.Equal(t, "Teaser", description.Description)
+	assert.Empty(t, description.Text)
+}
+
+func TestSiteParser_GetDescription_Metadata(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/jsonld":
+			w.Write([]byte(`<html><head>
+				<meta property="og:site_name" content="Example News">
+				<meta property="article:tag" content="go">
+				<meta property="article:tag" content="release">
+				<meta name="author" content="Meta Author">
+				<meta name="twitter:card" content="summary_large_image">
+				<meta name="twitter:site" content="@example">
+				<meta name="twitter:description" content="Twitter description">
+				<script type="application/ld+json">{broken</script>
+				<script type="application/ld+json">
+				{"@context": "https://schema.org", "@graph": [
+					{"@type": "WebSite", "name": "Example"},
+					{"@type": ["NewsArticle"], "headline": "Go 1.24 released",
+					 "author": [{"@type": "Person", "name": "Jane Doe"}, "John Roe"],
+					 "datePublished": "2025-02-11T10:00:00Z",
+					 "image": {"@type": "ImageObject", "url": "https://example.com/cover.jpg"}}
+				]}
+				</script>
+			</head></html>`))
+		default:
+			w.Write([]byte(`<html><head>
+				<meta name="twitter:title" content="Twitter title">
+				<meta property="article:author" content="Article Author">
+				<meta property="article:published_time" content="2025-02-11T09:00:00+03:00">
+			</head></html>`))
+		}
+	}))
+	defer server.Close()
+
+	parser := NewSiteParser()
+
+	description, err := parser.GetDescription(server.URL + "/jsonld")
+	require.NoError(t, err)
+	assert.Equal(t, "Twitter description", description.Description)
+	assert.Equal(t, map[string]any{
+		MetadataSiteName:    "Example News",
+		MetadataAuthor:      "Jane Doe, John Roe",
+		MetadataPublishedAt: "2025-02-11T10:00:00Z",
+		MetadataTags:        []string{"go", "release"},
+		MetadataSchemaType:  "NewsArticle",
+		MetadataHeadline:    "Go 1.24 released",
+		MetadataTwitterCard: "summary_large_image",
+		MetadataTwitterSite: "@example",
+	}, description.Metadata())
+
+	description, err = parser.GetDescription(server.URL + "/meta")
+	require.NoError(t, err)
+	assert.Equal(t, "Twitter title", description.Title)
+	assert.Equal(t, map[string]any{
+		MetadataAuthor:      "Article Author",
+		MetadataPublishedAt: "2025-02-11T09:00:00+03:00",
+	}, description.Metadata())
+}
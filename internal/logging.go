@@ -0,0 +1,51 @@
+package internal
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	LogFormatJSON    = "json"
+	LogFormatConsole = "console"
+)
+
+type LogConfig struct {
+	// debug, info, warn, error. По умолчанию info
+	Level string `yaml:"level"`
+	// json или console. По умолчанию json
+	Format string `yaml:"format"`
+}
+
+func (c LogConfig) ParseLevel() (zapcore.Level, error) {
+	if c.Level == "" {
+		return zapcore.InfoLevel, nil
+	}
+	return zapcore.ParseLevel(c.Level)
+}
+
+// NewLogger собирает логгер по конфигу. Уровень задаётся через level,
+// чтобы его можно было поменять при перечитывании конфига без пересоздания логгера.
+func NewLogger(conf LogConfig, level zap.AtomicLevel) (*zap.Logger, error) {
+	lvl, err := conf.ParseLevel()
+	if err != nil {
+		return nil, fmt.Errorf("invalid log level: %w", err)
+	}
+	level.SetLevel(lvl)
+
+	cfg := zap.NewProductionConfig()
+	cfg.Level = level
+
+	switch conf.Format {
+	case "", LogFormatJSON:
+	case LogFormatConsole:
+		cfg.Encoding = LogFormatConsole
+		cfg.EncoderConfig = zap.NewDevelopmentEncoderConfig()
+	default:
+		return nil, fmt.Errorf("unknown log format %q", conf.Format)
+	}
+
+	return cfg.Build()
+}
@@ -0,0 +1,86 @@
+package internal
+
+import (
+	"path/filepath"
+	"testing"
+
+	"rssgram/internal/outputs/telegram"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestNewLogger(t *testing.T) {
+	level := zap.NewAtomicLevel()
+
+	_, err := NewLogger(LogConfig{}, level)
+	require.NoError(t, err)
+	assert.Equal(t, zapcore.InfoLevel, level.Level())
+
+	_, err = NewLogger(LogConfig{Level: "debug", Format: LogFormatConsole}, level)
+	require.NoError(t, err)
+	assert.Equal(t, zapcore.DebugLevel, level.Level())
+
+	_, err = NewLogger(LogConfig{Level: "verbose"}, level)
+	assert.Error(t, err)
+
+	_, err = NewLogger(LogConfig{Format: "xml"}, level)
+	assert.Error(t, err)
+}
+
+func TestConfig_Validate_Logging(t *testing.T) {
+	tests := []struct {
+		name  string
+		log   LogConfig
+		trace telegram.TraceConfig
+		paths []string
+	}{
+		{"defaults", LogConfig{}, telegram.TraceConfig{}, nil},
+		{"valid", LogConfig{Level: "warn", Format: "console"}, telegram.TraceConfig{Enabled: true, SampleRate: 0.1}, nil},
+		{"trace by item", LogConfig{}, telegram.TraceConfig{Enabled: true, ItemIDs: []string{"id"}}, nil},
+		{"unknown level and format", LogConfig{Level: "verbose", Format: "xml"}, telegram.TraceConfig{}, []string{"log.level", "log.format"}},
+		{"unlimited trace", LogConfig{}, telegram.TraceConfig{Enabled: true}, []string{"telegram.trace"}},
+		{"sample rate out of range", LogConfig{}, telegram.TraceConfig{Enabled: true, SampleRate: 2}, []string{"telegram.trace.sample_rate"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cnf := &Config{Log: tt.log}
+			cnf.Telegram.ChannelName = "@test_channel"
+			cnf.Telegram.BotToken = "test_token"
+			cnf.Telegram.Trace = tt.trace
+
+			err := cnf.Validate()
+			if tt.paths == nil {
+				assert.NoError(t, err)
+				return
+			}
+
+			var verrs ValidationErrors
+			require.ErrorAs(t, err, &verrs)
+			var paths []string
+			for _, e := range verrs {
+				paths = append(paths, e.Path)
+			}
+			assert.Equal(t, tt.paths, paths)
+		})
+	}
+}
+
+func TestConfigHolder_Reload_LogLevel(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeTestConfig(t, path, reloadTestConfig)
+
+	cnf, err := ParseConfigFile(path)
+	require.NoError(t, err)
+
+	level := zap.NewAtomicLevel()
+	holder := NewConfigHolder(ConfigSource{Path: path}, cnf, zap.NewNop())
+	holder.WatchLogLevel(level)
+
+	writeTestConfig(t, path, reloadTestConfig+"log:\n  level: debug\n")
+	require.NoError(t, holder.Reload())
+	assert.Equal(t, zapcore.DebugLevel, level.Level())
+}
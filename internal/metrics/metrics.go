@@ -62,6 +62,16 @@ var ItemsEnrichTimeSec = promauto.NewHistogramVec(
 	[]string{"feed_name"},
 )
 
+// ItemsEnrichCount - обогащённые новости по источнику данных: cache - кэш в базе, page - страница,
+// robots - страница закрыта robots.txt, error - страница не загрузилась.
+var ItemsEnrichCount = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: metricNamespace,
+		Name:      "items_enrich_count",
+	},
+	[]string{"feed_name", "result"},
+)
+
 var FeedGetSuccess = promauto.NewCounterVec(
 	prometheus.CounterOpts{
 		Namespace: metricNamespace,
@@ -70,6 +80,24 @@ var FeedGetSuccess = promauto.NewCounterVec(
 	[]string{"feed_name"},
 )
 
+// ItemsDeferredCount - новости, оставленные в очереди на последнем проходе отправителя:
+// reason quota - квота фида, spacing - min_interval канала, quiet_hours - тихие часы с defer.
+var ItemsDeferredCount = promauto.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Namespace: metricNamespace,
+		Name:      "items_deferred_count",
+	},
+	[]string{"reason"},
+)
+
+var ItemsFilteredCount = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: metricNamespace,
+		Name:      "items_filtered_count",
+	},
+	[]string{"feed_name", "reason"},
+)
+
 var FeedGetError = promauto.NewCounterVec(
 	prometheus.CounterOpts{
 		Namespace: metricNamespace,
@@ -77,3 +105,16 @@ var FeedGetError = promauto.NewCounterVec(
 	},
 	[]string{"feed_name"},
 )
+
+var DatabaseSizeBytes = promauto.NewGauge(prometheus.GaugeOpts{
+	Namespace: metricNamespace,
+	Name:      "database_size_bytes",
+})
+
+var ItemsPrunedCount = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: metricNamespace,
+		Name:      "items_pruned_count",
+	},
+	[]string{"reason"},
+)
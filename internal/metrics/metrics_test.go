@@ -20,6 +20,8 @@ func TestMetrics_Initialization(t *testing.T) {
 	assert.NotNil(t, ItemsSentFailedCount)
 	assert.NotNil(t, ItemsSentErrorCount)
 	assert.NotNil(t, ItemsSentSuccessCount)
+	assert.NotNil(t, DatabaseSizeBytes)
+	assert.NotNil(t, ItemsPrunedCount)
 }
 
 func TestMetrics_FeedsCount(t *testing.T) {
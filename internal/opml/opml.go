@@ -0,0 +1,166 @@
+// Package opml читает и пишет списки подписок в формате OPML,
+// которым обмениваются RSS-читалки.
+package opml
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+)
+
+type Document struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    Head     `xml:"head"`
+	Body    Body     `xml:"body"`
+}
+
+type Head struct {
+	Title       string `xml:"title,omitempty"`
+	DateCreated string `xml:"dateCreated,omitempty"`
+}
+
+type Body struct {
+	Outlines []Outline `xml:"outline"`
+}
+
+// Outline - подписка (есть xmlUrl) или папка с вложенными outline.
+type Outline struct {
+	Text     string    `xml:"text,attr"`
+	Title    string    `xml:"title,attr,omitempty"`
+	Type     string    `xml:"type,attr,omitempty"`
+	XMLURL   string    `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL  string    `xml:"htmlUrl,attr,omitempty"`
+	Category string    `xml:"category,attr,omitempty"`
+	Outlines []Outline `xml:"outline"`
+}
+
+func (o Outline) title() string {
+	if o.Title != "" {
+		return o.Title
+	}
+	return o.Text
+}
+
+// Feed - подписка из OPML. Tags - папки, в которых она лежит, и её category.
+type Feed struct {
+	Title   string
+	URL     string
+	SiteURL string
+	Tags    []string
+}
+
+func Parse(r io.Reader) (*Document, error) {
+	var doc Document
+
+	dec := xml.NewDecoder(r)
+	// экспорт некоторых читалок объявляет кодировку, отличную от UTF-8,
+	// хотя пишет в UTF-8 - читаем как есть
+	dec.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) { return input, nil }
+
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to parse opml: %w", err)
+	}
+
+	return &doc, nil
+}
+
+// Feeds возвращает подписки документа в порядке следования, вложенность папок раскрывается в теги.
+func (d *Document) Feeds() []Feed {
+	var feeds []Feed
+
+	var walk func(outlines []Outline, folders []string)
+	walk = func(outlines []Outline, folders []string) {
+		for _, o := range outlines {
+			if o.XMLURL == "" {
+				// папка: у подписок внутри появляется её тег
+				if title := strings.TrimSpace(o.title()); title != "" {
+					walk(o.Outlines, append(folders[:len(folders):len(folders)], title))
+				} else {
+					walk(o.Outlines, folders)
+				}
+				continue
+			}
+
+			tags := slices.Clone(folders)
+			for _, c := range strings.Split(o.Category, ",") {
+				// category задаётся путём вида /tech/go
+				for _, tag := range strings.Split(c, "/") {
+					if tag = strings.TrimSpace(tag); tag != "" && !slices.Contains(tags, tag) {
+						tags = append(tags, tag)
+					}
+				}
+			}
+
+			feeds = append(feeds, Feed{
+				Title:   strings.TrimSpace(o.title()),
+				URL:     strings.TrimSpace(o.XMLURL),
+				SiteURL: o.HTMLURL,
+				Tags:    tags,
+			})
+		}
+	}
+	walk(d.Body.Outlines, nil)
+
+	return feeds
+}
+
+// New собирает документ из подписок. Подписка кладётся в папку по первому тегу,
+// все теги сохраняются в category.
+func New(title string, feeds []Feed, created time.Time) *Document {
+	doc := &Document{
+		Version: "2.0",
+		Head: Head{
+			Title:       title,
+			DateCreated: created.UTC().Format(time.RFC1123Z),
+		},
+	}
+
+	folders := make(map[string]int)
+	for _, f := range feeds {
+		o := Outline{
+			Text:     f.Title,
+			Title:    f.Title,
+			Type:     "rss",
+			XMLURL:   f.URL,
+			HTMLURL:  f.SiteURL,
+			Category: strings.Join(f.Tags, ","),
+		}
+		if o.Text == "" {
+			o.Text = f.URL
+		}
+
+		if len(f.Tags) == 0 {
+			doc.Body.Outlines = append(doc.Body.Outlines, o)
+			continue
+		}
+
+		i, ok := folders[f.Tags[0]]
+		if !ok {
+			i = len(doc.Body.Outlines)
+			folders[f.Tags[0]] = i
+			doc.Body.Outlines = append(doc.Body.Outlines, Outline{Text: f.Tags[0], Title: f.Tags[0]})
+		}
+		doc.Body.Outlines[i].Outlines = append(doc.Body.Outlines[i].Outlines, o)
+	}
+
+	return doc
+}
+
+func (d *Document) Write(w io.Writer) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(d); err != nil {
+		return fmt.Errorf("failed to write opml: %w", err)
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,63 @@
+package opml
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testOPML = `<?xml version="1.0" encoding="ISO-8859-1"?>
+<opml version="1.0">
+  <head><title>Subscriptions</title></head>
+  <body>
+    <outline text="Go Blog" type="rss" xmlUrl="https://go.dev/blog/feed.atom" htmlUrl="https://go.dev/blog"/>
+    <outline text="Tech" title="Tech">
+      <outline text="Хабр" xmlUrl=" https://habr.com/ru/rss/all/ " category="/news/it,habr"/>
+      <outline text="Nested">
+        <outline title="LWN" text="lwn" xmlUrl="https://lwn.net/headlines/rss"/>
+      </outline>
+    </outline>
+    <outline text="">
+      <outline text="No folder title" xmlUrl="https://example.com/rss"/>
+    </outline>
+  </body>
+</opml>`
+
+func TestDocument_Feeds(t *testing.T) {
+	doc, err := Parse(strings.NewReader(testOPML))
+	require.NoError(t, err)
+
+	assert.Equal(t, []Feed{
+		{Title: "Go Blog", URL: "https://go.dev/blog/feed.atom", SiteURL: "https://go.dev/blog", Tags: nil},
+		{Title: "Хабр", URL: "https://habr.com/ru/rss/all/", Tags: []string{"Tech", "news", "it", "habr"}},
+		{Title: "LWN", URL: "https://lwn.net/headlines/rss", Tags: []string{"Tech", "Nested"}},
+		{Title: "No folder title", URL: "https://example.com/rss", Tags: nil},
+	}, doc.Feeds())
+}
+
+func TestParse_Invalid(t *testing.T) {
+	_, err := Parse(strings.NewReader("<opml><body>"))
+	assert.Error(t, err)
+}
+
+func TestNew_RoundTrip(t *testing.T) {
+	feeds := []Feed{
+		{Title: "Go Blog", URL: "https://go.dev/blog/feed.atom"},
+		{Title: "Хабр", URL: "https://habr.com/ru/rss/all/", Tags: []string{"it", "news"}},
+		{Title: "LWN", URL: "https://lwn.net/headlines/rss", Tags: []string{"it"}},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, New("rssgram feeds", feeds, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)).Write(&buf))
+	assert.Contains(t, buf.String(), `<outline text="it" title="it">`)
+	assert.Contains(t, buf.String(), "<dateCreated>Tue, 02 Jan 2024 03:04:05 +0000</dateCreated>")
+
+	doc, err := Parse(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, "rssgram feeds", doc.Head.Title)
+	assert.Equal(t, feeds, doc.Feeds())
+}
@@ -0,0 +1,179 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"rssgram/internal/feed"
+	"rssgram/internal/utils"
+)
+
+// MessageLimit - максимальная длина текста сообщения Telegram.
+const MessageLimit = 4096
+
+// digestTitleLimit - длина заголовка новости в строке дайджеста, чтобы одна строка не заняла всё сообщение.
+const digestTitleLimit = 300
+
+// DigestConfig - расписание дайджеста: вместо отдельных сообщений новости копятся и уходят
+// одним списком ссылок. Дайджест отправляется, когда сработало любое из условий.
+type DigestConfig struct {
+	// раз в every после предыдущего дайджеста
+	Every time.Duration `yaml:"every"`
+	// каждый день в это время, "09:00"
+	At       []string `yaml:"at"`
+	Timezone string   `yaml:"timezone"`
+	// как только набралось столько новостей
+	EveryItems int `yaml:"every_items"`
+	// заголовок сообщения; по умолчанию "Digest" или "Digest: <фид>"
+	Title string `yaml:"title"`
+}
+
+func (c DigestConfig) Enabled() bool {
+	return c.Every > 0 || len(c.At) > 0 || c.EveryItems > 0
+}
+
+// DigestTimeLayout - формат времени в at.
+const DigestTimeLayout = "15:04"
+
+// Due решает, пора ли отправить дайджест из pending новостей, если предыдущий ушёл в last.
+func (c DigestConfig) Due(last, now time.Time, pending int) (bool, error) {
+	if pending == 0 {
+		return false, nil
+	}
+	if c.EveryItems > 0 && pending >= c.EveryItems {
+		return true, nil
+	}
+	if c.Every > 0 && now.Sub(last) >= c.Every {
+		return true, nil
+	}
+
+	if len(c.At) == 0 {
+		return false, nil
+	}
+	loc, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return false, fmt.Errorf("error loading location: %w", err)
+	}
+	now = now.In(loc)
+	for _, at := range c.At {
+		t, err := time.Parse(DigestTimeLayout, at)
+		if err != nil {
+			return false, fmt.Errorf("error parsing digest time: %w", err)
+		}
+
+		// последний момент по расписанию, не позже now
+		scheduled := time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, loc)
+		if scheduled.After(now) {
+			scheduled = scheduled.AddDate(0, 0, -1)
+		}
+		if scheduled.After(last) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// messageLen - длина текста так, как её считает Telegram, в UTF-16. Считается по HTML
+// целиком, разметка тоже учитывается: так сообщение гарантированно влезает в лимит.
+func messageLen(s string) int {
+	return len(utf16.Encode([]rune(s)))
+}
+
+// escapeLimited экранирует s для HTML и, если нужно, обрезает с многоточием так,
+// чтобы результат занял не больше limit по messageLen.
+func escapeLimited(s string, limit int) string {
+	escaped := html.EscapeString(s)
+	if messageLen(escaped) <= limit {
+		return escaped
+	}
+
+	const ellipsis = "..."
+	var b strings.Builder
+	size := len(ellipsis)
+	for _, r := range s {
+		e := html.EscapeString(string(r))
+		if size+messageLen(e) > limit {
+			break
+		}
+		b.WriteString(e)
+		size += messageLen(e)
+	}
+	return b.String() + ellipsis
+}
+
+// RenderDigest собирает дайджест: заголовок и по строке со ссылкой на каждую новость.
+// Если текст не влезает в limit, он делится на несколько сообщений с номером части в заголовке.
+// Название фида пишется в строке, только если новости из разных фидов.
+func RenderDigest(title string, items []*feed.FeedItem, limit int) []string {
+	withFeed := false
+	for _, item := range items {
+		if item.FeedTitle != items[0].FeedTitle {
+			withFeed = true
+			break
+		}
+	}
+
+	header := fmt.Sprintf("<b>%s</b>", escapeLimited(title, digestTitleLimit))
+	// место под " (12/34)" в заголовке частей
+	const partSuffixLen = 16
+	// столько остаётся строке, которая одна в сообщении
+	lineLimit := limit - messageLen(header) - partSuffixLen - 2
+
+	lines := make([]string, 0, len(items))
+	for _, item := range items {
+		line := fmt.Sprintf("• <a href=\"%s\">%s</a>", html.EscapeString(item.Link), html.EscapeString(utils.EllipsisString(item.Title, digestTitleLimit)))
+		if withFeed {
+			line += fmt.Sprintf(" <i>(%s)</i>", html.EscapeString(item.FeedTitle))
+		}
+		// строку длиннее сообщения Telegram не примет, и дайджест не уйдёт никогда:
+		// без ссылки остаётся хотя бы заголовок
+		if messageLen(line) > lineLimit {
+			line = "• " + escapeLimited(item.Title, lineLimit-messageLen("• "))
+		}
+		lines = append(lines, line)
+	}
+
+	var parts [][]string
+	size := 0
+	for _, line := range lines {
+		if len(parts) == 0 || size+1+messageLen(line) > limit {
+			parts = append(parts, nil)
+			size = messageLen(header) + partSuffixLen + 1
+		}
+		parts[len(parts)-1] = append(parts[len(parts)-1], line)
+		size += 1 + messageLen(line)
+	}
+
+	messages := make([]string, 0, len(parts))
+	for i, part := range parts {
+		h := header
+		if len(parts) > 1 {
+			h += fmt.Sprintf(" (%d/%d)", i+1, len(parts))
+		}
+		messages = append(messages, h+"\n\n"+strings.Join(part, "\n"))
+	}
+	return messages
+}
+
+// PushDigest отправляет дайджест с тихим режимом канала. Если дайджест разбит на несколько
+// сообщений и одно из них не ушло, при повторе уже отправленные части придут ещё раз.
+func (o *TelegramChannelOutput) PushDigest(ctx context.Context, title string, items []*feed.FeedItem) error {
+	disableNotification, err := o.config.SilentMode.Active(time.Now())
+	if err != nil {
+		return fmt.Errorf("error checking silent mode: %w", err)
+	}
+
+	for _, msg := range RenderDigest(title, items, MessageLimit) {
+		err = o.client.SendMessage(ctx, msg, TelegramMessageOptions{LinkPreview: false, DisableNotification: disableNotification})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
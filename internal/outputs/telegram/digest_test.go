@@ -0,0 +1,122 @@
+package telegram
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"rssgram/internal/feed"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDigestConfig_Due(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	require.NoError(t, err)
+
+	// 2024-05-01 12:30 по Москве
+	now := time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		conf    DigestConfig
+		last    time.Time
+		pending int
+		want    bool
+	}{
+		{"no items", DigestConfig{Every: time.Hour}, now.Add(-2 * time.Hour), 0, false},
+		{"every passed", DigestConfig{Every: time.Hour}, now.Add(-time.Hour), 1, true},
+		{"every not passed", DigestConfig{Every: time.Hour}, now.Add(-59 * time.Minute), 5, false},
+		{"every items reached", DigestConfig{Every: time.Hour, EveryItems: 5}, now.Add(-time.Minute), 5, true},
+		{"every items not reached", DigestConfig{EveryItems: 5}, now.Add(-48 * time.Hour), 4, false},
+		{"at passed today", DigestConfig{At: []string{"12:00"}, Timezone: "Europe/Moscow"}, time.Date(2024, 5, 1, 11, 0, 0, 0, moscow), 1, true},
+		{"at already sent today", DigestConfig{At: []string{"12:00"}, Timezone: "Europe/Moscow"}, time.Date(2024, 5, 1, 12, 0, 1, 0, moscow), 1, false},
+		{"at not yet today", DigestConfig{At: []string{"13:00"}, Timezone: "Europe/Moscow"}, time.Date(2024, 5, 1, 11, 0, 0, 0, moscow), 1, false},
+		{"at yesterday missed", DigestConfig{At: []string{"13:00"}, Timezone: "Europe/Moscow"}, time.Date(2024, 4, 30, 12, 0, 0, 0, moscow), 1, true},
+		{"at in utc", DigestConfig{At: []string{"09:00", "21:00"}}, time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC), 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			due, err := tt.conf.Due(tt.last, now, tt.pending)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, due)
+		})
+	}
+
+	_, err = DigestConfig{At: []string{"9am"}}.Due(now.Add(-time.Hour), now, 1)
+	assert.Error(t, err)
+}
+
+func TestRenderDigest(t *testing.T) {
+	items := []*feed.FeedItem{
+		{FeedTitle: "Go Blog", Title: "Go 1.23 <released>", Link: "https://go.dev/blog/go1.23?a=1&b=2"},
+		{FeedTitle: "Хабр", Title: "Новости", Link: "https://habr.com/1"},
+	}
+
+	messages := RenderDigest("Digest", items, MessageLimit)
+	require.Len(t, messages, 1)
+	assert.Equal(t, "<b>Digest</b>\n\n"+
+		"• <a href=\"https://go.dev/blog/go1.23?a=1&amp;b=2\">Go 1.23 &lt;released&gt;</a> <i>(Go Blog)</i>\n"+
+		"• <a href=\"https://habr.com/1\">Новости</a> <i>(Хабр)</i>", messages[0])
+
+	// у новостей одного фида название не повторяется
+	messages = RenderDigest("Digest: Go Blog", items[:1], MessageLimit)
+	require.Len(t, messages, 1)
+	assert.NotContains(t, messages[0], "<i>")
+}
+
+func TestRenderDigest_Split(t *testing.T) {
+	var items []*feed.FeedItem
+	for i := 0; i < 100; i++ {
+		items = append(items, &feed.FeedItem{
+			FeedTitle: "Хабр",
+			Title:     fmt.Sprintf("Длинный заголовок новости номер %d про 🚀 и всё остальное", i),
+			Link:      fmt.Sprintf("https://habr.com/ru/articles/%d/", i),
+		})
+	}
+
+	messages := RenderDigest("Digest", items, MessageLimit)
+	require.Greater(t, len(messages), 1)
+
+	total := 0
+	for i, msg := range messages {
+		assert.LessOrEqual(t, messageLen(msg), MessageLimit)
+		assert.True(t, strings.HasPrefix(msg, fmt.Sprintf("<b>Digest</b> (%d/%d)\n\n", i+1, len(messages))), msg[:40])
+		total += strings.Count(msg, "• ")
+	}
+	assert.Equal(t, len(items), total)
+}
+
+func TestRenderDigest_LongLine(t *testing.T) {
+	items := []*feed.FeedItem{
+		{FeedTitle: "Go Blog", Title: "Go 1.23", Link: "https://go.dev/blog/go1.23"},
+		{FeedTitle: "Хабр", Title: "Огромная <ссылка>", Link: "https://habr.com/?q=" + strings.Repeat("&", 1000)},
+		{FeedTitle: strings.Repeat("Хабр ", 1000), Title: strings.Repeat("<>", 1000), Link: "https://habr.com/2"},
+	}
+
+	messages := RenderDigest(strings.Repeat("Digest ", 1000), items, MessageLimit)
+	require.NotEmpty(t, messages)
+
+	total := 0
+	for _, msg := range messages {
+		assert.LessOrEqual(t, messageLen(msg), MessageLimit)
+		total += strings.Count(msg, "• ")
+	}
+	assert.Equal(t, len(items), total)
+
+	text := strings.Join(messages, "\n")
+	assert.Contains(t, text, `<a href="https://go.dev/blog/go1.23">Go 1.23</a>`)
+	// без ссылки, но с заголовком
+	assert.Contains(t, text, "• Огромная &lt;ссылка&gt;\n")
+}
+
+func TestEscapeLimited(t *testing.T) {
+	assert.Equal(t, "a &amp; b", escapeLimited("a & b", 9))
+	// сущность не разрезается
+	assert.Equal(t, "a ...", escapeLimited("a & b", 8))
+	// 🚀 - две единицы UTF-16
+	assert.Equal(t, "🚀...", escapeLimited("🚀🚀🚀", 5))
+}
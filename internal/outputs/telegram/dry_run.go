@@ -0,0 +1,119 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DryRunConfig - режим, в котором сообщения пишутся в лог или JSONL-файл вместо Telegram API.
+type DryRunConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// JSONL-файл для сообщений; пусто - сообщения пишутся в лог
+	File string `yaml:"file"`
+}
+
+// DryRunMessage - строка JSONL-файла: запрос, который ушёл бы в Telegram.
+type DryRunMessage struct {
+	Time                time.Time `json:"time"`
+	ItemID              string    `json:"item_id,omitempty"`
+	ChatID              string    `json:"chat_id"`
+	Method              string    `json:"method"`
+	Text                string    `json:"text"`
+	Photo               string    `json:"photo,omitempty"`
+	DisableNotification bool      `json:"disable_notification"`
+}
+
+// DryRunClient реализует TelegramClient без обращения к Telegram API.
+type DryRunClient struct {
+	chatID string
+	file   io.WriteCloser
+	logger *zap.Logger
+}
+
+func (c *DryRunClient) write(ctx context.Context, m DryRunMessage) error {
+	m.Time = time.Now().UTC()
+	m.ChatID = c.chatID
+	if v := ctx.Value("item_id"); v != nil {
+		if itemID, ok := v.(string); ok {
+			m.ItemID = itemID
+		}
+	}
+
+	if c.file == nil {
+		c.logger.Info("dry run message",
+			zap.String("item_id", m.ItemID),
+			zap.String("method", m.Method),
+			zap.String("text", m.Text),
+			zap.String("photo", m.Photo),
+			zap.Bool("disable_notification", m.DisableNotification),
+		)
+		return nil
+	}
+
+	line, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dry run message: %w", err)
+	}
+	if _, err = c.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write dry run message: %w", err)
+	}
+	return nil
+}
+
+func (c *DryRunClient) SendMessage(ctx context.Context, msg string, options TelegramMessageOptions) error {
+	return c.write(ctx, DryRunMessage{
+		Method:              "sendMessage",
+		Text:                msg,
+		DisableNotification: options.DisableNotification,
+	})
+}
+
+func (c *DryRunClient) SendPhoto(ctx context.Context, msg, photoUrl string, disableNotification bool) error {
+	return c.write(ctx, DryRunMessage{
+		Method:              "sendPhoto",
+		Text:                msg,
+		Photo:               photoUrl,
+		DisableNotification: disableNotification,
+	})
+}
+
+// WithChatID возвращает клиент для другого канала, пишущий в тот же файл или лог.
+// Закрывать его не нужно: файлом владеет исходный клиент.
+func (c *DryRunClient) WithChatID(chatID string) *DryRunClient {
+	return &DryRunClient{
+		chatID: chatID,
+		file:   c.file,
+		logger: c.logger,
+	}
+}
+
+func (c *DryRunClient) Close() error {
+	if c.file == nil {
+		return nil
+	}
+	return c.file.Close()
+}
+
+// NewDryRunClient открывает JSONL-файл из конфига на дозапись.
+func NewDryRunClient(conf DryRunConfig, chatID string, logger *zap.Logger) (*DryRunClient, error) {
+	c := &DryRunClient{
+		chatID: chatID,
+		logger: logger,
+	}
+
+	if conf.File != "" {
+		f, err := os.OpenFile(conf.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open dry run file: %w", err)
+		}
+		c.file = f
+	}
+
+	return c, nil
+}
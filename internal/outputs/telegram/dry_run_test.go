@@ -0,0 +1,85 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"rssgram/internal/feed"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// TestDryRunClient_File проверяет, что Push через DryRunClient пишет в JSONL по строке на сообщение.
+func TestDryRunClient_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "messages.jsonl")
+
+	client, err := NewDryRunClient(DryRunConfig{Enabled: true, File: path}, "@test_channel", zap.NewNop())
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	output := NewTelegramChannelOutputWithClient(TelegramChannelOutputConfig{}, client, false)
+
+	items := []*feed.FeedItem{
+		{FeedTitle: "TestFeed", Title: "Text", Link: "https://example.com/1"},
+		{FeedTitle: "TestFeed", Title: "Photo", Link: "https://example.com/2", ImageURL: "https://example.com/2.png"},
+	}
+	for i, item := range items {
+		ctx := context.WithValue(context.Background(), "item_id", []string{"id-1", "id-2"}[i])
+		if ok, err := output.Push(ctx, item); !ok || err != nil {
+			t.Fatalf("push failed: %v", err)
+		}
+	}
+	if err = client.Close(); err != nil {
+		t.Fatalf("failed to close client: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d: %s", len(lines), data)
+	}
+
+	var first, second DryRunMessage
+	if err = json.Unmarshal([]byte(lines[0]), &first); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	if err = json.Unmarshal([]byte(lines[1]), &second); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+
+	if first.Method != "sendMessage" || first.ItemID != "id-1" || first.ChatID != "@test_channel" || !strings.Contains(first.Text, "Text") {
+		t.Errorf("unexpected first message: %+v", first)
+	}
+	if second.Method != "sendPhoto" || second.Photo != "https://example.com/2.png" || first.Time.IsZero() {
+		t.Errorf("unexpected second message: %+v", second)
+	}
+}
+
+// TestDryRunClient_Log проверяет, что без файла сообщения пишутся в лог.
+func TestDryRunClient_Log(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+
+	client, err := NewDryRunClient(DryRunConfig{Enabled: true}, "@test_channel", zap.New(core))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	if err = client.SendMessage(context.Background(), "hello", TelegramMessageOptions{}); err != nil {
+		t.Fatalf("send failed: %v", err)
+	}
+
+	entries := logs.FilterMessage("dry run message").All()
+	if len(entries) != 1 || entries[0].ContextMap()["text"] != "hello" {
+		t.Errorf("expected one dry run log entry, got %+v", entries)
+	}
+}
@@ -16,6 +16,34 @@ import (
 
 type TelegramChannelOutputConfig struct {
 	TelegramChannelClientConfig `yaml:",inline"`
+	// Digest собирает новости канала в периодические дайджесты
+	Digest DigestConfig `yaml:"digest"`
+	// MinInterval - наименьший промежуток между сообщениями в канал; новости ждут в очереди
+	MinInterval time.Duration `yaml:"min_interval"`
+}
+
+// OutputConfig - именованный канал, в который новости направляют правила маршрутизации.
+// Незаданные bot_token, silent_mode и trace берутся из секции telegram, digest и min_interval - нет.
+type OutputConfig struct {
+	Name                        string `yaml:"name"`
+	TelegramChannelClientConfig `yaml:",inline"`
+	Digest                      DigestConfig  `yaml:"digest"`
+	MinInterval                 time.Duration `yaml:"min_interval"`
+}
+
+// WithDefaults дополняет канал настройками основного.
+func (c OutputConfig) WithDefaults(main TelegramChannelClientConfig) TelegramChannelOutputConfig {
+	conf := c.TelegramChannelClientConfig
+	if conf.BotToken == "" {
+		conf.BotToken = main.BotToken
+	}
+	if conf.SilentMode.IsZero() {
+		conf.SilentMode = main.SilentMode
+	}
+	if !conf.Trace.Enabled {
+		conf.Trace = main.Trace
+	}
+	return TelegramChannelOutputConfig{TelegramChannelClientConfig: conf, Digest: c.Digest, MinInterval: c.MinInterval}
 }
 
 type TelegramChannelOutput struct {
@@ -26,58 +54,67 @@ type TelegramChannelOutput struct {
 }
 
 func (o *TelegramChannelOutput) IsSilentMode(startTimeStr, finishTimeStr, tzStr string, refTime time.Time) (bool, error) {
+	return SilentModeConfig{Start: startTimeStr, Finish: finishTimeStr, Timezone: tzStr}.Active(refTime)
+}
 
-	if startTimeStr == "" || finishTimeStr == "" {
-		return false, nil
-	}
-
-	startTime, err := time.Parse(time.TimeOnly, startTimeStr)
-	if err != nil {
-		return false, fmt.Errorf("error parsing start time: %w", err)
-	}
-
-	finishTime, err := time.Parse(time.TimeOnly, finishTimeStr)
-	if err != nil {
-		return false, fmt.Errorf("error parsing finish time: %w", err)
-	}
-
-	loc, err := time.LoadLocation(tzStr)
-	if err != nil {
-		return false, fmt.Errorf("error loading location: %w", err)
-	}
-
-	refTime = refTime.In(loc)
-	onlyRefTime := time.Date(startTime.Year(), startTime.Month(), startTime.Day(), refTime.Hour(), refTime.Minute(), refTime.Second(), refTime.Nanosecond(), loc)
+// inSilentWindow - попадает ли время суток refTime в окно от startTime до finishTime,
+// заданных через time.Parse. Окно может переходить через полночь; start = finish - весь день.
+func inSilentWindow(startTime, finishTime, refTime time.Time) bool {
+	onlyRefTime := time.Date(startTime.Year(), startTime.Month(), startTime.Day(), refTime.Hour(), refTime.Minute(), refTime.Second(), refTime.Nanosecond(), startTime.Location())
 
 	if finishTime.After(startTime) {
 		// one day
-		if onlyRefTime.After(startTime) && onlyRefTime.Before(finishTime) {
-			return true, nil
-		}
-		return false, nil
+		return onlyRefTime.After(startTime) && onlyRefTime.Before(finishTime)
 	} else if finishTime.Before(startTime) {
 		// crossday
-		if onlyRefTime.After(finishTime) && onlyRefTime.Before(startTime) {
-			return false, nil
-		}
-		return true, nil
+		return !(onlyRefTime.After(finishTime) && onlyRefTime.Before(startTime))
 	}
 
-	return true, nil
+	return true
 }
 
 func (o *TelegramChannelOutput) Push(ctx context.Context, item *feed.FeedItem) (bool, error) {
 
-	disableNotification, err := o.IsSilentMode(
-		o.config.SilentMode.Start,
-		o.config.SilentMode.Finish,
-		o.config.SilentMode.Timezone,
-		time.Now(),
-	)
+	disableNotification, err := o.config.SilentMode.Active(time.Now())
 	if err != nil {
 		return false, fmt.Errorf("error checking silent mode: %w", err)
 	}
+	// правило маршрутизации важнее тихого режима канала
+	if item.Silent != nil {
+		disableNotification = *item.Silent
+	}
+
+	msg := RenderMessage(item, o.enableTags)
 
+	var sendErr error
+
+	if msg.ImageURL == "" {
+		sendErr = o.client.SendMessage(
+			ctx,
+			msg.Text,
+			TelegramMessageOptions{LinkPreview: false, DisableNotification: disableNotification},
+		)
+	} else {
+		sendErr = o.client.SendPhoto(ctx, msg.Text, msg.ImageURL, disableNotification)
+	}
+
+	if sendErr != nil {
+		return false, sendErr
+	}
+
+	return true, nil
+}
+
+// RenderedMessage - сообщение в том виде, в котором оно уходит в Telegram.
+type RenderedMessage struct {
+	// HTML для parse_mode=HTML
+	Text string `json:"text"`
+	// если задана, сообщение отправляется через sendPhoto с Text в подписи
+	ImageURL string `json:"image_url,omitempty"`
+}
+
+// RenderMessage собирает текст сообщения: фид, ссылка, описание без HTML и теги.
+func RenderMessage(item *feed.FeedItem, enableTags bool) RenderedMessage {
 	feedTitle := fmt.Sprintf("<b>[%s]</b>", item.FeedTitle)
 	itemTitle := fmt.Sprintf("<a href=\"%s\">%s</a>", item.Link, html.EscapeString(item.Title))
 
@@ -93,8 +130,17 @@ func (o *TelegramChannelOutput) Push(ctx context.Context, item *feed.FeedItem) (
 		fmt.Sprintf("<blockquote>%s</blockquote>", shortDescription),
 	)
 
+	// та же новость пришла из других фидов
+	if len(item.ExtraSources) > 0 {
+		sources := make([]string, 0, len(item.ExtraSources))
+		for _, source := range item.ExtraSources {
+			sources = append(sources, html.EscapeString(source))
+		}
+		msg += fmt.Sprintf("\n\n<i>Also in: %s</i>", strings.Join(sources, ", "))
+	}
+
 	// Добавляем теги, если включено
-	if o.enableTags && len(item.Tags) > 0 {
+	if enableTags && len(item.Tags) > 0 {
 		tags := ""
 		for _, tag := range item.Tags {
 			tag = strings.ReplaceAll(tag, " ", "_")
@@ -104,23 +150,7 @@ func (o *TelegramChannelOutput) Push(ctx context.Context, item *feed.FeedItem) (
 		msg += "\n\n" + tags
 	}
 
-	var sendErr error
-
-	if item.ImageURL == "" {
-		sendErr = o.client.SendMessage(
-			ctx,
-			msg,
-			TelegramMessageOptions{LinkPreview: false, DisableNotification: disableNotification},
-		)
-	} else {
-		sendErr = o.client.SendPhoto(ctx, msg, item.ImageURL, disableNotification)
-	}
-
-	if sendErr != nil {
-		return false, sendErr
-	}
-
-	return true, nil
+	return RenderedMessage{Text: msg, ImageURL: item.ImageURL}
 }
 
 func NewTelegramChannelOutput(conf TelegramChannelOutputConfig, logger *zap.Logger, enableTags bool) *TelegramChannelOutput {
@@ -131,6 +161,16 @@ func NewTelegramChannelOutput(conf TelegramChannelOutputConfig, logger *zap.Logg
 	}
 }
 
+// NewTelegramChannelOutputWithClient - то же, что NewTelegramChannelOutput, но с другим клиентом,
+// например DryRunClient.
+func NewTelegramChannelOutputWithClient(conf TelegramChannelOutputConfig, client TelegramClient, enableTags bool) *TelegramChannelOutput {
+	return &TelegramChannelOutput{
+		config:     conf,
+		client:     client,
+		enableTags: enableTags,
+	}
+}
+
 // Интерфейс для клиента Telegram
 
 type TelegramClient interface {
@@ -1,11 +1,15 @@
 package telegram
 
 import (
+	"strings"
 	"testing"
 	"time"
 
 	"context"
 	"rssgram/internal/feed"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // TestTelegramChannelOutput_IsSilentMode проверяет различные сценарии работы тихого режима (silent mode) для Telegram.
@@ -274,3 +278,93 @@ func TestTelegramChannelOutput_Push_Tags(t *testing.T) {
 func contains(s, substr string) bool {
 	return substr == "" || (len(substr) > 0 && (len(s) >= len(substr)) && (s == substr || (len(s) > len(substr) && (s[len(s)-len(substr):] == substr || s[len(s)-len(substr)-1:] == "\n"+substr)))) || (len(substr) > 0 && (len(s) > len(substr)) && (s[len(s)-len(substr)-2:] == "\n\n"+substr)) || (len(substr) > 0 && (len(s) > len(substr)) && (s[len(s)-len(substr)-1:] == " "+substr))
 }
+
+// TestRenderMessage проверяет, что описание очищается от HTML, заголовок экранируется, а картинка передаётся отдельно.
+func TestRenderMessage(t *testing.T) {
+	item := &feed.FeedItem{
+		FeedTitle:   "TestFeed",
+		Title:       "Go <1.23> & generics",
+		Link:        "https://example.com/post",
+		Description: "<p>Первый <b>абзац</b></p>",
+		ImageURL:    "https://example.com/image.png",
+		Tags:        []string{"go lang"},
+	}
+
+	msg := RenderMessage(item, true)
+
+	expected := "<b>[TestFeed]</b>\n\n" +
+		"<a href=\"https://example.com/post\">Go &lt;1.23&gt; &amp; generics</a>\n\n" +
+		"<blockquote>Первый абзац</blockquote>\n\n" +
+		"#go_lang"
+	if msg.Text != expected {
+		t.Errorf("unexpected text:\n%q\nexpected:\n%q", msg.Text, expected)
+	}
+	if msg.ImageURL != item.ImageURL {
+		t.Errorf("expected image %q, got %q", item.ImageURL, msg.ImageURL)
+	}
+}
+
+func TestRenderMessage_ExtraSources(t *testing.T) {
+	item := &feed.FeedItem{
+		FeedTitle:    "Origin",
+		Title:        "Title",
+		Link:         "https://example.com/post",
+		ExtraSources: []string{"Hacker News", "R&D digest"},
+	}
+
+	msg := RenderMessage(item, false)
+	assert.True(t, strings.HasSuffix(msg.Text, "\n\n<i>Also in: Hacker News, R&amp;D digest</i>"), msg.Text)
+}
+
+// TestTelegramChannelOutput_Push_SilentOverride проверяет, что silent из правила маршрутизации важнее тихого режима канала.
+func TestTelegramChannelOutput_Push_SilentOverride(t *testing.T) {
+	// тихий режим канала действует круглые сутки
+	conf := TelegramChannelOutputConfig{TelegramChannelClientConfig: TelegramChannelClientConfig{
+		SilentMode: SilentModeConfig{Start: "00:00:00", Finish: "23:59:59", Timezone: "UTC"},
+	}}
+
+	loud, silent := false, true
+	testCases := []struct {
+		name     string
+		override *bool
+		expected bool
+	}{
+		{"без правила", nil, true},
+		{"правило включает уведомление", &loud, false},
+		{"правило выключает уведомление", &silent, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var disableNotification bool
+			client := &mockTelegramChannelClient{
+				sendMessageFunc: func(ctx context.Context, msg string, options TelegramMessageOptions) error {
+					disableNotification = options.DisableNotification
+					return nil
+				},
+			}
+			output := NewTelegramChannelOutputWithClient(conf, client, false)
+
+			ok, err := output.Push(context.Background(), &feed.FeedItem{Title: "Title", Silent: tc.override})
+			require.NoError(t, err)
+			assert.True(t, ok)
+			assert.Equal(t, tc.expected, disableNotification)
+		})
+	}
+}
+
+func TestOutputConfig_WithDefaults(t *testing.T) {
+	main := TelegramChannelClientConfig{
+		ChannelName: "@main",
+		BotToken:    "main-token",
+		SilentMode:  SilentModeConfig{Start: "23:00:00", Finish: "08:00:00"},
+	}
+
+	conf := OutputConfig{Name: "security", TelegramChannelClientConfig: TelegramChannelClientConfig{ChannelName: "@security"}}.WithDefaults(main)
+	assert.Equal(t, "@security", conf.ChannelName)
+	assert.Equal(t, "main-token", conf.BotToken)
+	assert.Equal(t, main.SilentMode, conf.SilentMode)
+
+	conf = OutputConfig{TelegramChannelClientConfig: TelegramChannelClientConfig{ChannelName: "@other", BotToken: "other-token"}}.WithDefaults(main)
+	assert.Equal(t, "other-token", conf.BotToken)
+}
@@ -0,0 +1,136 @@
+package telegram
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Режимы тихих часов.
+const (
+	// SilentModeMute - новости уходят сразу, но без уведомления
+	SilentModeMute = "mute"
+	// SilentModeDefer - новости ждут в очереди до конца окна
+	SilentModeDefer = "defer"
+)
+
+// Как отправить новости, накопленные за окно в режиме defer.
+const (
+	// SilentReleaseGradual - по одной, как обычная очередь, с min_interval канала
+	SilentReleaseGradual = "gradual"
+	// SilentReleaseDigest - одним дайджестом
+	SilentReleaseDigest = "digest"
+)
+
+// SilentWindowDays - дни недели в days, по порядку time.Weekday.
+var SilentWindowDays = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// SilentWindow - окно тихих часов. Окно через полночь относится к дню, в который
+// начинается: {days: [fri], start: "23:00:00", finish: "08:00:00"} - с вечера пятницы до утра субботы.
+// start = finish - весь день, пустые days - каждый день.
+type SilentWindow struct {
+	Days   []string `yaml:"days"`
+	Start  string   `yaml:"start"`
+	Finish string   `yaml:"finish"`
+}
+
+// active - попадает ли refTime, уже переведённое в часовой пояс канала, в окно.
+func (w SilentWindow) active(refTime time.Time) (bool, error) {
+	startTime, err := time.Parse(time.TimeOnly, w.Start)
+	if err != nil {
+		return false, fmt.Errorf("error parsing start time: %w", err)
+	}
+	finishTime, err := time.Parse(time.TimeOnly, w.Finish)
+	if err != nil {
+		return false, fmt.Errorf("error parsing finish time: %w", err)
+	}
+
+	if !inSilentWindow(startTime, finishTime, refTime) {
+		return false, nil
+	}
+	if len(w.Days) == 0 {
+		return true, nil
+	}
+
+	day := refTime.Weekday()
+	// утренняя часть окна через полночь началась накануне
+	onlyRefTime := time.Date(startTime.Year(), startTime.Month(), startTime.Day(), refTime.Hour(), refTime.Minute(), refTime.Second(), refTime.Nanosecond(), startTime.Location())
+	if finishTime.Before(startTime) && onlyRefTime.Before(startTime) {
+		day = (day + 6) % 7
+	}
+
+	for _, d := range w.Days {
+		if strings.ToLower(d) == SilentWindowDays[day] {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (c SilentModeConfig) IsZero() bool {
+	return c.Start == "" && c.Finish == "" && c.Timezone == "" && len(c.Windows) == 0 && c.Mode == "" && c.Release == ""
+}
+
+// Defer - новости канала ждут конца тихих часов.
+func (c SilentModeConfig) Defer() bool {
+	return c.Mode == SilentModeDefer
+}
+
+// Active - идут ли тихие часы в refTime: окно start-finish или любое из windows.
+func (c SilentModeConfig) Active(refTime time.Time) (bool, error) {
+	if c.Start != "" && c.Finish != "" {
+		startTime, err := time.Parse(time.TimeOnly, c.Start)
+		if err != nil {
+			return false, fmt.Errorf("error parsing start time: %w", err)
+		}
+		finishTime, err := time.Parse(time.TimeOnly, c.Finish)
+		if err != nil {
+			return false, fmt.Errorf("error parsing finish time: %w", err)
+		}
+		loc, err := time.LoadLocation(c.Timezone)
+		if err != nil {
+			return false, fmt.Errorf("error loading location: %w", err)
+		}
+		if inSilentWindow(startTime, finishTime, refTime.In(loc)) {
+			return true, nil
+		}
+	}
+
+	if len(c.Windows) == 0 {
+		return false, nil
+	}
+	loc, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return false, fmt.Errorf("error loading location: %w", err)
+	}
+	for i, w := range c.Windows {
+		active, err := w.active(refTime.In(loc))
+		if err != nil {
+			return false, fmt.Errorf("windows[%d]: %w", i, err)
+		}
+		if active {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ValidateSilentWindow проверяет окно, для сообщений об ошибках конфига.
+func ValidateSilentWindow(w SilentWindow) error {
+	if w.Start == "" || w.Finish == "" {
+		return fmt.Errorf("start and finish are required")
+	}
+	if _, err := time.Parse(time.TimeOnly, w.Start); err != nil {
+		return fmt.Errorf("invalid start %q, expected HH:MM:SS", w.Start)
+	}
+	if _, err := time.Parse(time.TimeOnly, w.Finish); err != nil {
+		return fmt.Errorf("invalid finish %q, expected HH:MM:SS", w.Finish)
+	}
+	for _, d := range w.Days {
+		if !slices.Contains(SilentWindowDays, strings.ToLower(d)) {
+			return fmt.Errorf("unknown day %q, expected one of %s", d, strings.Join(SilentWindowDays, ", "))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package telegram
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSilentModeConfig_Active(t *testing.T) {
+	weekend := SilentWindow{Days: []string{"sat", "Sun"}, Start: "00:00:00", Finish: "00:00:00"}
+	fridayNight := SilentWindow{Days: []string{"fri"}, Start: "23:00:00", Finish: "08:00:00"}
+
+	// 17.10.2025 - пятница
+	testCases := []struct {
+		name     string
+		config   SilentModeConfig
+		refTime  time.Time
+		expected bool
+	}{
+		{
+			name:     "no windows",
+			config:   SilentModeConfig{},
+			refTime:  time.Date(2025, 10, 18, 12, 0, 0, 0, time.UTC),
+			expected: false,
+		},
+		{
+			name:     "weekend whole day",
+			config:   SilentModeConfig{Windows: []SilentWindow{weekend}},
+			refTime:  time.Date(2025, 10, 18, 12, 0, 0, 0, time.UTC),
+			expected: true,
+		},
+		{
+			name:     "weekday is not weekend",
+			config:   SilentModeConfig{Windows: []SilentWindow{weekend}},
+			refTime:  time.Date(2025, 10, 20, 12, 0, 0, 0, time.UTC),
+			expected: false,
+		},
+		{
+			name:     "crossday window evening",
+			config:   SilentModeConfig{Windows: []SilentWindow{fridayNight}},
+			refTime:  time.Date(2025, 10, 17, 23, 30, 0, 0, time.UTC),
+			expected: true,
+		},
+		{
+			name:     "crossday window morning belongs to previous day",
+			config:   SilentModeConfig{Windows: []SilentWindow{fridayNight}},
+			refTime:  time.Date(2025, 10, 18, 7, 0, 0, 0, time.UTC),
+			expected: true,
+		},
+		{
+			name:     "crossday window morning of start day",
+			config:   SilentModeConfig{Windows: []SilentWindow{fridayNight}},
+			refTime:  time.Date(2025, 10, 17, 7, 0, 0, 0, time.UTC),
+			expected: false,
+		},
+		{
+			name:     "window without days",
+			config:   SilentModeConfig{Windows: []SilentWindow{{Start: "13:00:00", Finish: "14:00:00"}}},
+			refTime:  time.Date(2025, 10, 21, 13, 30, 0, 0, time.UTC),
+			expected: true,
+		},
+		{
+			name: "second window",
+			config: SilentModeConfig{Windows: []SilentWindow{
+				{Start: "13:00:00", Finish: "14:00:00"},
+				{Days: []string{"tue"}, Start: "18:00:00", Finish: "20:00:00"},
+			}},
+			refTime:  time.Date(2025, 10, 21, 19, 0, 0, 0, time.UTC),
+			expected: true,
+		},
+		{
+			name: "legacy pair with windows",
+			config: SilentModeConfig{
+				Start:   "01:00:00",
+				Finish:  "06:00:00",
+				Windows: []SilentWindow{weekend},
+			},
+			refTime:  time.Date(2025, 10, 21, 3, 0, 0, 0, time.UTC),
+			expected: true,
+		},
+		{
+			name: "timezone",
+			config: SilentModeConfig{
+				Timezone: "Europe/Moscow",
+				Windows:  []SilentWindow{weekend},
+			},
+			// в Москве уже суббота
+			refTime:  time.Date(2025, 10, 17, 22, 0, 0, 0, time.UTC),
+			expected: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			active, err := tc.config.Active(tc.refTime)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, active)
+		})
+	}
+}
+
+func TestValidateSilentWindow(t *testing.T) {
+	assert.NoError(t, ValidateSilentWindow(SilentWindow{Days: []string{"Sat", "sun"}, Start: "00:00:00", Finish: "00:00:00"}))
+	assert.Error(t, ValidateSilentWindow(SilentWindow{Start: "23:00:00"}))
+	assert.Error(t, ValidateSilentWindow(SilentWindow{Start: "23:00", Finish: "08:00:00"}))
+	assert.Error(t, ValidateSilentWindow(SilentWindow{Days: []string{"saturday"}, Start: "23:00:00", Finish: "08:00:00"}))
+}
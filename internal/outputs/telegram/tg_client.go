@@ -6,8 +6,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"math/rand/v2"
 	"net/http"
 	"net/http/httputil"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
 
 	"go.uber.org/zap"
 )
@@ -42,16 +48,44 @@ type Photo struct {
 	DisableNotification bool   `json:"disable_notification"`
 }
 
+// SilentModeConfig - тихие часы канала: окно start-finish и окна windows, в том числе
+// по дням недели. В режиме mute в них отключаются уведомления, в режиме defer новости
+// ждут конца окна и уходят по очереди (release: gradual) или одним дайджестом (release: digest).
 type SilentModeConfig struct {
-	Start    string `json:"start"`
-	Finish   string `json:"finish"`
-	Timezone string `json:"timezone"`
+	Start    string         `json:"start"`
+	Finish   string         `json:"finish"`
+	Timezone string         `json:"timezone"`
+	Windows  []SilentWindow `json:"windows"`
+	Mode     string         `json:"mode"`
+	Release  string         `json:"release"`
 }
 
 type TelegramChannelClientConfig struct {
-	ChannelName string           `yaml:"channel_name"`
-	BotToken    string           `yaml:"bot_token"`
-	SilentMode  SilentModeConfig `yaml:"silent_mode"`
+	ChannelName  string           `yaml:"channel_name"`
+	BotToken     string           `yaml:"bot_token"`
+	BotTokenFile string           `yaml:"bot_token_file"`
+	SilentMode   SilentModeConfig `yaml:"silent_mode"`
+	Trace        TraceConfig      `yaml:"trace"`
+}
+
+// TraceConfig включает дампы запросов и ответов Telegram API в лог.
+// Дампы большие и содержат текст сообщений, поэтому пишутся только для части запросов:
+// для item_ids или для доли sample_rate (от 0 до 1) всех отправок.
+type TraceConfig struct {
+	Enabled    bool     `yaml:"enabled"`
+	SampleRate float64  `yaml:"sample_rate"`
+	ItemIDs    []string `yaml:"item_ids"`
+}
+
+// traced решает, писать ли дамп для отправки новости itemID.
+func (c TraceConfig) traced(itemID string) bool {
+	if !c.Enabled {
+		return false
+	}
+	if itemID != "" && slices.Contains(c.ItemIDs, itemID) {
+		return true
+	}
+	return c.SampleRate > 0 && rand.Float64() < c.SampleRate
 }
 
 type TelegramChannelClient struct {
@@ -62,16 +96,6 @@ type TelegramChannelClient struct {
 }
 
 func (c *TelegramChannelClient) SendPhoto(ctx context.Context, msg string, photoUrl string, disableNotification bool) error {
-
-	ctxLogger := c.logger
-	if v := ctx.Value("item_id"); v != nil {
-		if itemID, ok := v.(string); ok {
-			ctxLogger = c.logger.With(zap.String("item_id", itemID))
-		}
-	}
-
-	url := fmt.Sprintf("https://api.telegram.org/bot%s/sendPhoto", c.conf.BotToken)
-
 	m := Photo{
 		ChatID:              c.conf.ChannelName,
 		Photo:               photoUrl,
@@ -80,52 +104,19 @@ func (c *TelegramChannelClient) SendPhoto(ctx context.Context, msg string, photo
 		DisableNotification: disableNotification,
 	}
 
-	jsonBytes, err := json.Marshal(m)
-	if err != nil {
-		return fmt.Errorf("failed to marshal request body: %w", err)
-	}
-
-	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(jsonBytes))
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-	req.Header.Add("Content-Type", "application/json")
-
-	reqDump, err := httputil.DumpRequest(req, true)
-	if err != nil {
-		return fmt.Errorf("failed to dump request: %w", err)
-	}
-	ctxLogger.Debug("request dump", zap.String("request", string(reqDump)))
-
-	res, err := c.httpClient.Do(req)
+	statusCode, err := c.post(ctx, "sendPhoto", m)
 	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
+		return err
 	}
 
-	respDump, err := httputil.DumpResponse(res, true)
-	if err != nil {
-		return fmt.Errorf("failed to dump response: %w", err)
-	}
-
-	ctxLogger.Debug("response dump", zap.String("response", string(respDump)))
-
-	if res.StatusCode != http.StatusOK {
-		return fmt.Errorf("return status code: %d", res.StatusCode)
+	if statusCode != http.StatusOK {
+		return fmt.Errorf("return status code: %d", statusCode)
 	}
 
 	return nil
 }
 
 func (c *TelegramChannelClient) SendMessage(ctx context.Context, msg string, options TelegramMessageOptions) error {
-	ctxLogger := c.logger
-	if v := ctx.Value("item_id"); v != nil {
-		if itemID, ok := v.(string); ok {
-			ctxLogger = c.logger.With(zap.String("item_id", itemID))
-		}
-	}
-
-	url := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", c.conf.BotToken)
-
 	m := Message{
 		ChatID:               c.conf.ChannelName,
 		ParseMode:            "HTML",
@@ -134,44 +125,104 @@ func (c *TelegramChannelClient) SendMessage(ctx context.Context, msg string, opt
 		DisableNotifications: options.DisableNotification,
 	}
 
-	jsonBytes, err := json.Marshal(m)
+	statusCode, err := c.post(ctx, "sendMessage", m)
 	if err != nil {
-		return fmt.Errorf("failed to marshal request body: %w", err)
+		return err
 	}
 
-	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(jsonBytes))
+	switch statusCode {
+	case http.StatusTooManyRequests:
+		return ErrTooManyRequests
+	case http.StatusBadRequest:
+		return ErrBadRequest
+	case http.StatusOK:
+		return nil
+	default:
+		return fmt.Errorf("return status code: %d", statusCode)
+	}
+}
+
+// post вызывает метод Bot API и возвращает код ответа. Дампы запроса и ответа
+// пишутся только в режиме trace, токен и chat_id из них вырезаются.
+func (c *TelegramChannelClient) post(ctx context.Context, method string, body any) (int, error) {
+	itemID, _ := ctx.Value("item_id").(string)
+
+	ctxLogger := c.logger.With(zap.String("method", method))
+	if itemID != "" {
+		ctxLogger = ctxLogger.With(zap.String("item_id", itemID))
+	}
+
+	url := fmt.Sprintf("https://api.telegram.org/bot%s/%s", c.conf.BotToken, method)
+
+	jsonBytes, err := json.Marshal(body)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return 0, fmt.Errorf("failed to marshal request body: %w", err)
 	}
-	req.Header.Add("Content-Type", "application/json")
 
-	reqDump, err := httputil.DumpRequest(req, true)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(jsonBytes))
 	if err != nil {
-		return fmt.Errorf("failed to dump request: %w", err)
+		return 0, fmt.Errorf("failed to create request: %w", c.redactError(err))
+	}
+	req.Header.Add("Content-Type", "application/json")
+
+	trace := c.conf.Trace.traced(itemID)
+	if trace {
+		reqDump, err := httputil.DumpRequest(req, true)
+		if err != nil {
+			return 0, fmt.Errorf("failed to dump request: %w", err)
+		}
+		ctxLogger.Info("request dump", zap.String("request", c.redact(string(reqDump))))
 	}
-	ctxLogger.Debug("request dump", zap.String("request", string(reqDump)))
 
+	started := time.Now()
 	res, err := c.httpClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
+		return 0, fmt.Errorf("failed to send request: %w", c.redactError(err))
 	}
+	defer res.Body.Close()
 
-	respDump, err := httputil.DumpResponse(res, true)
-	if err != nil {
-		return fmt.Errorf("failed to dump response: %w", err)
+	if trace {
+		respDump, err := httputil.DumpResponse(res, true)
+		if err != nil {
+			return 0, fmt.Errorf("failed to dump response: %w", err)
+		}
+		ctxLogger.Info("response dump", zap.String("response", c.redact(string(respDump))))
+	} else {
+		// тело нужно дочитать, чтобы соединение вернулось в пул
+		io.Copy(io.Discard, res.Body)
 	}
-	ctxLogger.Debug("response dump", zap.String("response", string(respDump)))
 
-	switch res.StatusCode {
-	case http.StatusTooManyRequests:
-		return ErrTooManyRequests
-	case http.StatusBadRequest:
-		return ErrBadRequest
-	case http.StatusOK:
-		return nil
-	default:
-		return fmt.Errorf("return status code: %d", res.StatusCode)
+	ctxLogger.Debug("telegram request",
+		zap.Int("status", res.StatusCode),
+		zap.Duration("duration", time.Since(started)),
+	)
+
+	return res.StatusCode, nil
+}
+
+const (
+	redactedToken  = "<redacted>"
+	redactedChatID = "<chat_id>"
+)
+
+// redact убирает из строк, которые попадают в лог, токен бота (он входит в URL запроса) и chat_id канала.
+func (c *TelegramChannelClient) redact(s string) string {
+	if c.conf.BotToken != "" {
+		s = strings.ReplaceAll(s, c.conf.BotToken, redactedToken)
+	}
+	if c.conf.ChannelName != "" {
+		s = strings.ReplaceAll(s, c.conf.ChannelName, redactedChatID)
+	}
+	return s
+}
+
+// redactError убирает токен из URL в ошибке http.Client, она логируется при неудачной отправке.
+func (c *TelegramChannelClient) redactError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		urlErr.URL = c.redact(urlErr.URL)
 	}
+	return err
 }
 
 func NewTelegramChannelClient(conf TelegramChannelClientConfig, logger *zap.Logger) *TelegramChannelClient {
@@ -3,6 +3,8 @@ package telegram
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
@@ -10,7 +12,9 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 type roundTripperFunc func(*http.Request) *http.Response
@@ -191,3 +195,75 @@ func TestTelegramChannelClient_NewTelegramChannelClient(t *testing.T) {
 	assert.Equal(t, config, client.conf)
 	assert.NotNil(t, client.logger)
 }
+
+func TestTelegramChannelClient_Trace(t *testing.T) {
+	conf := TelegramChannelClientConfig{
+		ChannelName: "@secret_channel",
+		BotToken:    "123:secret_token",
+	}
+	itemCtx := context.WithValue(context.Background(), "item_id", "item-1")
+	otherCtx := context.WithValue(context.Background(), "item_id", "item-2")
+
+	send := func(t *testing.T, conf TelegramChannelClientConfig, ctx context.Context) *observer.ObservedLogs {
+		core, logs := observer.New(zap.DebugLevel)
+		client := NewTelegramChannelClient(conf, zap.New(core))
+		client.httpClient = newTestClient(200, `{"ok": true, "result": {"chat": {"username": "secret_channel"}}}`)
+
+		require.NoError(t, client.SendMessage(ctx, "Test message", TelegramMessageOptions{}))
+		require.NoError(t, client.SendPhoto(ctx, "Test message", "https://example.com/image.jpg", false))
+
+		for _, entry := range logs.All() {
+			for _, v := range entry.ContextMap() {
+				assert.NotContains(t, fmt.Sprint(v), "secret_token")
+				assert.NotContains(t, fmt.Sprint(v), "@secret_channel")
+			}
+		}
+		return logs
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		logs := send(t, conf, itemCtx)
+		assert.Zero(t, logs.FilterMessage("request dump").Len())
+		assert.Equal(t, 2, logs.FilterMessage("telegram request").Len())
+	})
+
+	t.Run("sample rate", func(t *testing.T) {
+		conf := conf
+		conf.Trace = TraceConfig{Enabled: true, SampleRate: 1}
+
+		logs := send(t, conf, otherCtx)
+		dumps := logs.FilterMessage("request dump").All()
+		require.Len(t, dumps, 2)
+		assert.Equal(t, zap.InfoLevel, dumps[0].Level)
+		assert.Contains(t, dumps[0].ContextMap()["request"], "/bot<redacted>/sendMessage")
+		assert.Contains(t, dumps[0].ContextMap()["request"], `"chat_id":"<chat_id>"`)
+		assert.Equal(t, 2, logs.FilterMessage("response dump").Len())
+	})
+
+	t.Run("item ids", func(t *testing.T) {
+		conf := conf
+		conf.Trace = TraceConfig{Enabled: true, ItemIDs: []string{"item-1"}}
+
+		assert.Equal(t, 2, send(t, conf, itemCtx).FilterMessage("request dump").Len())
+		assert.Zero(t, send(t, conf, otherCtx).FilterMessage("request dump").Len())
+	})
+}
+
+func TestTelegramChannelClient_RedactsError(t *testing.T) {
+	client := NewTelegramChannelClient(TelegramChannelClientConfig{
+		ChannelName: "@test_channel",
+		BotToken:    "123:secret_token",
+	}, zap.NewNop())
+	client.httpClient = &http.Client{Transport: errorRoundTripper{}}
+
+	err := client.SendMessage(context.Background(), "Test message", TelegramMessageOptions{})
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "secret_token")
+	assert.Contains(t, err.Error(), "connection refused")
+}
+
+type errorRoundTripper struct{}
+
+func (errorRoundTripper) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, errors.New("connection refused")
+}
@@ -0,0 +1,180 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+)
+
+// reloadDebounce - редакторы пишут файл в несколько приёмов, перечитываем после паузы.
+const reloadDebounce = 500 * time.Millisecond
+
+// ConfigHolder хранит текущий конфиг и атомарно подменяет его при изменении файла.
+// Фоновые задачи берут снимок через Load в начале каждой итерации,
+// поэтому уже начатая работа доделывается со старыми настройками.
+type ConfigHolder struct {
+	source ConfigSource
+	config atomic.Pointer[Config]
+	logger *zap.Logger
+
+	// logLevel - уровень логгера сервиса, меняется при перечитывании конфига
+	logLevel *zap.AtomicLevel
+}
+
+func NewConfigHolder(source ConfigSource, cnf *Config, logger *zap.Logger) *ConfigHolder {
+	if source.Path == "" {
+		source.Path = DefaultConfigPath
+	}
+
+	h := &ConfigHolder{
+		source: source,
+		logger: logger,
+	}
+	h.config.Store(cnf)
+	return h
+}
+
+// WatchLogLevel применяет log.level из перечитанного конфига к level.
+func (h *ConfigHolder) WatchLogLevel(level zap.AtomicLevel) {
+	h.logLevel = &level
+}
+
+func (h *ConfigHolder) Load() *Config {
+	return h.config.Load()
+}
+
+// Reload перечитывает файл конфига. Если новый конфиг не читается или не проходит
+// проверку, остаётся прежний.
+func (h *ConfigHolder) Reload() error {
+	cnf, err := h.source.Load()
+	if err != nil {
+		return fmt.Errorf("failed to parse config %s: %w", h.source.Path, err)
+	}
+
+	if err = cnf.Validate(); err != nil {
+		return fmt.Errorf("invalid config %s: %w", h.source.Path, err)
+	}
+
+	old := h.Load()
+	if !reflect.DeepEqual(old.Database, cnf.Database) {
+		h.logger.Warn("database settings changed, restart is required to apply them")
+	}
+	if old.Metrics != cnf.Metrics {
+		h.logger.Warn("metrics settings changed, restart is required to apply them")
+	}
+	if old.DryRun != cnf.DryRun {
+		h.logger.Warn("dry_run settings changed, restart is required to apply them")
+	}
+	if old.Log.Format != cnf.Log.Format {
+		h.logger.Warn("log format changed, restart is required to apply it")
+	}
+	if h.logLevel != nil && old.Log.Level != cnf.Log.Level {
+		// уровень уже проверен в Validate
+		level, _ := cnf.Log.ParseLevel()
+		h.logLevel.SetLevel(level)
+		h.logger.Info("log level changed", zap.Stringer("level", level))
+	}
+
+	h.config.Store(cnf)
+	h.logger.Info("config reloaded", zap.Int("feeds", len(cnf.Feeds)))
+
+	return nil
+}
+
+// Watch перечитывает конфиг по SIGHUP и при изменении файла, пока не отменён ctx.
+// Если за файлом следить не получилось, SIGHUP всё равно обрабатывается: иначе
+// systemctl reload завершил бы процесс.
+func (h *ConfigHolder) Watch(ctx context.Context) error {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	// без watcher'а каналы nil и в select никогда не срабатывают
+	var events <-chan fsnotify.Event
+	var watchErrors <-chan error
+
+	watcher, absPath, err := h.watchConfigDir()
+	if err != nil {
+		h.logger.Error("config file watching disabled, use SIGHUP to reload", zap.Error(err))
+	} else {
+		defer watcher.Close()
+		events, watchErrors = watcher.Events, watcher.Errors
+	}
+
+	// в ConfigMap файл - симлинк на ..data/<файл>, и kubelet атомарно подменяет сам ..data:
+	// событий по пути конфига нет, поэтому на любое событие в каталоге проверяем, куда он ведёт
+	target, _ := filepath.EvalSymlinks(absPath)
+
+	debounce := time.NewTimer(reloadDebounce)
+	debounce.Stop()
+
+	reload := func() {
+		if err := h.Reload(); err != nil {
+			h.logger.Error("config reload rejected, keeping previous config", zap.Error(err))
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case <-hup:
+			h.logger.Info("received SIGHUP, reloading config")
+			reload()
+
+		case event, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if event.Op == fsnotify.Chmod {
+				continue
+			}
+			if filepath.Clean(event.Name) != absPath {
+				current, _ := filepath.EvalSymlinks(absPath)
+				if current == target {
+					continue
+				}
+				target = current
+			}
+			debounce.Reset(reloadDebounce)
+
+		case <-debounce.C:
+			reload()
+
+		case err, ok := <-watchErrors:
+			if !ok {
+				return nil
+			}
+			h.logger.Error("config watcher error", zap.Error(err))
+		}
+	}
+}
+
+// watchConfigDir следит за каталогом конфига: редакторы заменяют файл, а не пишут в него.
+func (h *ConfigHolder) watchConfigDir() (*fsnotify.Watcher, string, error) {
+	absPath, err := filepath.Abs(h.source.Path)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to resolve config path: %w", err)
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, absPath, fmt.Errorf("failed to create config watcher: %w", err)
+	}
+	if err = watcher.Add(filepath.Dir(absPath)); err != nil {
+		watcher.Close()
+		return nil, absPath, fmt.Errorf("failed to watch config dir: %w", err)
+	}
+
+	return watcher, absPath, nil
+}
@@ -0,0 +1,172 @@
+package internal
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const reloadTestConfig = `
+telegram:
+  channel_name: "@test_channel"
+  bot_token: "test_token"
+feeds:
+  - name: "First"
+    url: "https://example.com/rss"
+`
+
+func writeTestConfig(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+}
+
+func TestConfigHolder_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeTestConfig(t, path, reloadTestConfig)
+
+	cnf, err := ParseConfigFile(path)
+	require.NoError(t, err)
+
+	holder := NewConfigHolder(ConfigSource{Path: path}, cnf, zap.NewNop())
+	assert.Same(t, cnf, holder.Load())
+
+	// новый фид подхватывается
+	writeTestConfig(t, path, reloadTestConfig+`
+  - name: "Second"
+    url: "https://another.com/rss"
+`)
+	require.NoError(t, holder.Reload())
+	require.Len(t, holder.Load().Feeds, 2)
+	assert.Equal(t, "Second", holder.Load().Feeds[1].Name)
+
+	reloaded := holder.Load()
+
+	// битый YAML отклоняется, старый конфиг остаётся
+	writeTestConfig(t, path, "feeds: [unclosed")
+	assert.Error(t, holder.Reload())
+	assert.Same(t, reloaded, holder.Load())
+
+	// невалидный конфиг отклоняется
+	writeTestConfig(t, path, `
+telegram:
+  channel_name: "@test_channel"
+feeds:
+  - url: "not-a-url"
+`)
+	assert.Error(t, holder.Reload())
+	assert.Same(t, reloaded, holder.Load())
+}
+
+func TestConfigHolder_Watch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeTestConfig(t, path, reloadTestConfig)
+
+	cnf, err := ParseConfigFile(path)
+	require.NoError(t, err)
+
+	holder := NewConfigHolder(ConfigSource{Path: path}, cnf, zap.NewNop())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error)
+	go func() { done <- holder.Watch(ctx) }()
+
+	// даём watcher'у подписаться на каталог
+	time.Sleep(100 * time.Millisecond)
+
+	// замена файла через rename, как делают редакторы
+	tmpPath := path + ".tmp"
+	writeTestConfig(t, tmpPath, reloadTestConfig+`
+  - name: "Second"
+    url: "https://another.com/rss"
+`)
+	require.NoError(t, os.Rename(tmpPath, path))
+
+	assert.Eventually(t, func() bool {
+		return len(holder.Load().Feeds) == 2
+	}, 3*time.Second, 50*time.Millisecond)
+
+	cancel()
+	assert.NoError(t, <-done)
+}
+
+// TestConfigHolder_Watch_ConfigMap проверяет раскладку k8s ConfigMap: config.yaml -> ..data/config.yaml,
+// а при обновлении kubelet подменяет симлинк ..data, не трогая config.yaml.
+func TestConfigHolder_Watch_ConfigMap(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "..v1"), 0755))
+	writeTestConfig(t, filepath.Join(dir, "..v1", "config.yaml"), reloadTestConfig)
+	require.NoError(t, os.Symlink("..v1", filepath.Join(dir, "..data")))
+	require.NoError(t, os.Symlink(filepath.Join("..data", "config.yaml"), path))
+
+	cnf, err := ParseConfigFile(path)
+	require.NoError(t, err)
+
+	holder := NewConfigHolder(ConfigSource{Path: path}, cnf, zap.NewNop())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error)
+	go func() { done <- holder.Watch(ctx) }()
+
+	time.Sleep(100 * time.Millisecond)
+
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "..v2"), 0755))
+	writeTestConfig(t, filepath.Join(dir, "..v2", "config.yaml"), reloadTestConfig+`
+  - name: "Second"
+    url: "https://another.com/rss"
+`)
+	require.NoError(t, os.Symlink("..v2", filepath.Join(dir, "..data_tmp")))
+	require.NoError(t, os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data")))
+
+	assert.Eventually(t, func() bool {
+		return len(holder.Load().Feeds) == 2
+	}, 3*time.Second, 50*time.Millisecond)
+
+	cancel()
+	assert.NoError(t, <-done)
+}
+
+// TestConfigHolder_Watch_Unwatchable проверяет, что без слежения за каталогом SIGHUP
+// по-прежнему перечитывает конфиг, а не завершает процесс.
+func TestConfigHolder_Watch_Unwatchable(t *testing.T) {
+	source := ConfigSource{
+		Path:     filepath.Join(t.TempDir(), "missing", "config.yaml"),
+		Optional: true,
+		LookupEnv: mapEnv(map[string]string{
+			"RSSGRAM_TELEGRAM_CHANNEL":   "@test_channel",
+			"RSSGRAM_TELEGRAM_BOT_TOKEN": "test_token",
+		}),
+	}
+	cnf, err := source.Load()
+	require.NoError(t, err)
+
+	holder := NewConfigHolder(source, cnf, zap.NewNop())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error)
+	go func() { done <- holder.Watch(ctx) }()
+
+	time.Sleep(100 * time.Millisecond)
+	require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGHUP))
+
+	assert.Eventually(t, func() bool {
+		return holder.Load() != cnf
+	}, 3*time.Second, 50*time.Millisecond)
+
+	cancel()
+	assert.NoError(t, <-done)
+}
@@ -0,0 +1,145 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// expandEnv подставляет ${NAME} и ${NAME:-default} в строковые значения конфига.
+// Подстановка идёт по разобранному YAML, поэтому значение переменной не может
+// сломать разметку файла, а номера строк для ошибок сохраняются.
+// $${NAME} оставляет в значении ${NAME} как есть.
+func expandEnv(node *yaml.Node, lookupEnv func(string) (string, bool), v *validator) {
+	var walk func(node *yaml.Node, path []any)
+	walk = func(node *yaml.Node, path []any) {
+		switch node.Kind {
+		case yaml.DocumentNode:
+			for _, n := range node.Content {
+				walk(n, path)
+			}
+		case yaml.MappingNode:
+			// ключи не раскрываются, только значения
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				walk(node.Content[i+1], append(path[:len(path):len(path)], node.Content[i].Value))
+			}
+		case yaml.SequenceNode:
+			for i, n := range node.Content {
+				walk(n, append(path[:len(path):len(path)], i))
+			}
+		case yaml.ScalarNode:
+			if !strings.Contains(node.Value, "${") {
+				return
+			}
+
+			value, missing := expandValue(node.Value, lookupEnv)
+			for _, name := range missing {
+				v.errs = append(v.errs, ValidationError{
+					Path:    formatPath(path),
+					Line:    node.Line,
+					Message: fmt.Sprintf("environment variable %s is not set", name),
+				})
+			}
+
+			node.Value = value
+			if node.Style == 0 {
+				// тип обычного скаляра определяется заново: port: ${PORT} должен стать числом
+				node.Tag = ""
+			}
+		}
+	}
+
+	walk(node, nil)
+}
+
+// expandValue возвращает строку с подставленными переменными и имена незаданных переменных без значения по умолчанию.
+func expandValue(s string, lookupEnv func(string) (string, bool)) (string, []string) {
+	var (
+		b       strings.Builder
+		missing []string
+	)
+
+	for {
+		i := strings.Index(s, "${")
+		if i < 0 {
+			break
+		}
+
+		if i > 0 && s[i-1] == '$' {
+			b.WriteString(s[:i-1] + "${")
+			s = s[i+2:]
+			continue
+		}
+
+		end := strings.IndexByte(s[i:], '}')
+		if end < 0 {
+			break
+		}
+
+		b.WriteString(s[:i])
+		expr := s[i+2 : i+end]
+		s = s[i+end+1:]
+
+		name, def, hasDefault := strings.Cut(expr, ":-")
+		if value, ok := lookupEnv(name); ok && (value != "" || !hasDefault) {
+			b.WriteString(value)
+		} else if hasDefault {
+			b.WriteString(def)
+		} else {
+			missing = append(missing, name)
+		}
+	}
+	b.WriteString(s)
+
+	return b.String(), missing
+}
+
+// readSecretFile читает секрет из файла (Docker/Kubernetes secrets), перевод строки в конце отбрасывается.
+func readSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// resolveSecretFiles заменяет *_file ссылки на содержимое файлов.
+func (c *Config) resolveSecretFiles(v *validator) {
+	secrets := []struct {
+		path  []any
+		value *string
+		file  string
+	}{
+		{[]any{"telegram", "bot_token"}, &c.Telegram.BotToken, c.Telegram.BotTokenFile},
+		{[]any{"database", "dsn"}, &c.Database.DSN, c.Database.DSNFile},
+	}
+	for i := range c.Outputs {
+		o := &c.Outputs[i]
+		secrets = append(secrets, struct {
+			path  []any
+			value *string
+			file  string
+		}{[]any{"outputs", i, "bot_token"}, &o.BotToken, o.BotTokenFile})
+	}
+
+	for _, s := range secrets {
+		if s.file == "" {
+			continue
+		}
+
+		filePath := append(s.path[:len(s.path)-1:len(s.path)-1], s.path[len(s.path)-1].(string)+"_file")
+		if *s.value != "" {
+			v.addf(filePath, "only one of %s and %s can be set", formatPath(s.path), formatPath(filePath))
+			continue
+		}
+
+		value, err := readSecretFile(s.file)
+		if err != nil {
+			v.addf(filePath, "failed to read secret: %v", err)
+			continue
+		}
+		*s.value = value
+	}
+}
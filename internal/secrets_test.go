@@ -0,0 +1,106 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandValue(t *testing.T) {
+	env := mapEnv(map[string]string{"TOKEN": "secret", "EMPTY": ""})
+
+	tests := []struct {
+		name     string
+		value    string
+		expected string
+		missing  []string
+	}{
+		{"whole value", "${TOKEN}", "secret", nil},
+		{"inside string", "bot-${TOKEN}-x", "bot-secret-x", nil},
+		{"default", "${UNSET:-fallback}", "fallback", nil},
+		{"empty uses default", "${EMPTY:-fallback}", "fallback", nil},
+		{"empty without default", "${EMPTY}", "", nil},
+		{"missing", "a${UNSET}b", "ab", []string{"UNSET"}},
+		{"escaped", "$${TOKEN}", "${TOKEN}", nil},
+		{"unterminated", "${TOKEN", "${TOKEN", nil},
+		{"plain dollar", "price $5", "price $5", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, missing := expandValue(tt.value, env)
+			assert.Equal(t, tt.expected, value)
+			assert.Equal(t, tt.missing, missing)
+		})
+	}
+}
+
+func TestConfigSource_Load_Secrets(t *testing.T) {
+	dir := t.TempDir()
+
+	tokenFile := filepath.Join(dir, "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("file_token\n"), 0600))
+
+	path := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`telegram:
+  channel_name: "${CHANNEL}"
+  bot_token_file: ${SECRETS}/token
+metrics:
+  enabled: true
+  port: ${PORT:-2112}
+`), 0644))
+
+	env := mapEnv(map[string]string{"CHANNEL": "@from_env", "SECRETS": dir})
+
+	cnf, err := ConfigSource{Path: path, LookupEnv: env}.Load()
+	require.NoError(t, err)
+	require.NoError(t, cnf.Validate())
+
+	assert.Equal(t, "@from_env", cnf.Telegram.ChannelName)
+	assert.Equal(t, "file_token", cnf.Telegram.BotToken)
+	assert.Equal(t, 2112, cnf.Metrics.Port)
+
+	// переменная окружения важнее файла из конфига
+	env = mapEnv(map[string]string{"CHANNEL": "@from_env", "SECRETS": dir, "RSSGRAM_TELEGRAM_BOT_TOKEN": "env_token"})
+	cnf, err = ConfigSource{Path: path, LookupEnv: env}.Load()
+	require.NoError(t, err)
+	assert.Equal(t, "env_token", cnf.Telegram.BotToken)
+
+	env = mapEnv(map[string]string{"CHANNEL": "@from_env", "SECRETS": dir, "RSSGRAM_DB_FILE": tokenFile})
+	cnf, err = ConfigSource{Path: path, LookupEnv: env}.Load()
+	require.NoError(t, err)
+	assert.Equal(t, "file_token", cnf.Database.DSN)
+}
+
+func TestConfigSource_Load_SecretErrors(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`telegram:
+  channel_name: "${CHANNEL}"
+  bot_token: token
+  bot_token_file: /run/secrets/token
+database:
+  dsn_file: `+filepath.Join(dir, "missing")+`
+`), 0644))
+
+	cnf, err := ConfigSource{Path: path, LookupEnv: mapEnv(nil)}.Load()
+	require.NoError(t, err)
+
+	var errs ValidationErrors
+	require.ErrorAs(t, cnf.Validate(), &errs)
+
+	got := make(map[string]int, len(errs))
+	for _, e := range errs {
+		got[e.Path] = e.Line
+	}
+	assert.Equal(t, map[string]int{
+		"telegram.channel_name":   2,
+		"telegram.bot_token_file": 4,
+		"database.dsn_file":       6,
+	}, got)
+	assert.Contains(t, errs.Error(), "environment variable CHANNEL is not set")
+	assert.Contains(t, errs.Error(), "only one of telegram.bot_token and telegram.bot_token_file can be set")
+}
@@ -0,0 +1,73 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"rssgram/internal/feed"
+	"rssgram/internal/storage"
+	"rssgram/internal/storage/postgres"
+	"rssgram/internal/storage/sqlite"
+)
+
+// Storage - общий интерфейс хранилища фидов, новостей и состояния их доставки.
+type Storage interface {
+	Migrate(ctx context.Context) error
+	MigrateDown(ctx context.Context, steps int) error
+	MigrationVersion(ctx context.Context) (storage.MigrationVersion, error)
+	Close() error
+
+	GetFeedByURL(ctx context.Context, url string) (*storage.StoredFeed, error)
+	DeleteFeed(ctx context.Context, url string) error
+	UpsertFeed(ctx context.Context, url string, lastChecked, lastPost time.Time) error
+	SetFeedResolvedURL(ctx context.Context, url, resolvedURL string) error
+	ListFeedStats(ctx context.Context) ([]storage.FeedStats, error)
+
+	InsertItem(ctx context.Context, item *feed.FeedItem) error
+	GetItemsReadyToSend(ctx context.Context, limit int) ([]feed.FeedItem, error)
+	GetCountItemsReadyToSend(ctx context.Context) (int, error)
+	GetCountItemsSendFailed(ctx context.Context) (int, error)
+	CountSentItems(ctx context.Context, sentAfter time.Time) (map[string]int, error)
+	ListItems(ctx context.Context, filter storage.ItemFilter) ([]storage.StoredItem, error)
+	FindDuplicateItem(ctx context.Context, normalizedLink string, sentAfter time.Time) (*storage.StoredItem, error)
+	AddItemSource(ctx context.Context, itemID, source string) error
+	ListFingerprints(ctx context.Context, sentAfter time.Time) ([]storage.ItemFingerprint, error)
+
+	SetItemIsSent(ctx context.Context, itemID string, status storage.DeliveryStatus) error
+	SetItemOutputSent(ctx context.Context, itemID, output string) error
+	IncrementItemFailedCounter(ctx context.Context, itemID string) error
+	RequeueItems(ctx context.Context, filter storage.ItemFilter) (int64, error)
+
+	GetDigestSentAt(ctx context.Context, key string) (time.Time, error)
+	SetDigestSent(ctx context.Context, digest storage.SentDigest) error
+
+	GetEnrichCache(ctx context.Context, key string, fetchedAfter time.Time) (*feed.SiteDescription, error)
+	SetEnrichCache(ctx context.Context, key string, description feed.SiteDescription, fetchedAt time.Time) error
+	DeleteEnrichCache(ctx context.Context, fetchedBefore time.Time) (int64, error)
+
+	Search(ctx context.Context, query storage.SearchQuery) ([]storage.SearchResult, error)
+
+	PruneItems(ctx context.Context, policy storage.PrunePolicy) (storage.PruneResult, error)
+	Vacuum(ctx context.Context) error
+	Size(ctx context.Context) (int64, error)
+}
+
+var (
+	_ Storage = (*sqlite.Storage)(nil)
+	_ Storage = (*postgres.Storage)(nil)
+)
+
+// Open открывает хранилище с драйвером из конфига.
+func Open(ctx context.Context, conf storage.Config) (Storage, error) {
+	conf = conf.WithDefaults()
+
+	switch conf.Driver {
+	case storage.DriverSQLite:
+		return sqlite.New(ctx, conf)
+	case storage.DriverPostgres:
+		return postgres.New(ctx, conf)
+	default:
+		return nil, fmt.Errorf("unknown database driver %q", conf.Driver)
+	}
+}
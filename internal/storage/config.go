@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+)
+
+const DefaultDSN = "file:data.db"
+
+type Config struct {
+	Driver          string        `yaml:"driver"`
+	DSN             string        `yaml:"dsn"`
+	DSNFile         string        `yaml:"dsn_file"`
+	BusyTimeout     time.Duration `yaml:"busy_timeout"`
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+}
+
+// WithDefaults заполняет незаданные параметры значениями по умолчанию.
+func (c Config) WithDefaults() Config {
+	if c.Driver == "" {
+		// --db postgres://... должно работать без отдельного указания драйвера
+		if strings.HasPrefix(c.DSN, "postgres://") || strings.HasPrefix(c.DSN, "postgresql://") {
+			c.Driver = DriverPostgres
+		} else {
+			c.Driver = DriverSQLite
+		}
+	}
+	if c.DSN == "" && c.Driver == DriverSQLite {
+		c.DSN = DefaultDSN
+	}
+	if c.BusyTimeout <= 0 {
+		c.BusyTimeout = 5 * time.Second
+	}
+	if c.MaxOpenConns <= 0 {
+		// feedGetter, itemSender и обогащение пишут параллельно,
+		// но писатель в SQLite всё равно один - больше соединений не нужно.
+		c.MaxOpenConns = 4
+	}
+	if c.MaxIdleConns <= 0 || c.MaxIdleConns > c.MaxOpenConns {
+		c.MaxIdleConns = c.MaxOpenConns
+	}
+	return c
+}
+
+type RetentionConfig struct {
+	Enabled        bool          `yaml:"enabled"`
+	SentItemsDays  int           `yaml:"sent_items_days"`
+	KeepPerFeed    int           `yaml:"keep_per_feed"`
+	Interval       time.Duration `yaml:"interval"`
+	VacuumInterval time.Duration `yaml:"vacuum_interval"`
+}
+
+func (c RetentionConfig) WithDefaults() RetentionConfig {
+	if c.SentItemsDays <= 0 {
+		c.SentItemsDays = 30
+	}
+	if c.KeepPerFeed <= 0 {
+		// последние новости фида нужны для дедупликации по id
+		c.KeepPerFeed = 100
+	}
+	if c.Interval <= 0 {
+		c.Interval = time.Hour
+	}
+	if c.VacuumInterval <= 0 {
+		c.VacuumInterval = 24 * time.Hour
+	}
+	return c
+}
+
+// Policy строит политику очистки на момент now для списка активных фидов.
+func (c RetentionConfig) Policy(now time.Time, activeFeeds []string) PrunePolicy {
+	return PrunePolicy{
+		SentBefore:  now.AddDate(0, 0, -c.SentItemsDays),
+		KeepPerFeed: c.KeepPerFeed,
+		ActiveFeeds: activeFeeds,
+	}
+}
@@ -1,6 +1,8 @@
 package storage
 
 import (
+	"html"
+	"strings"
 	"time"
 )
 
@@ -8,4 +10,171 @@ type StoredFeed struct {
 	URL         string
 	LastChecked time.Time
 	LastPosted  time.Time
+	// ResolvedURL - адрес фида, найденный на странице URL; пустой, если URL сам отдаёт фид
+	ResolvedURL string
+}
+
+type PrunePolicy struct {
+	// отправленные раньше этого времени новости удаляются
+	SentBefore time.Time
+	// сколько последних новостей каждого фида сохранять в любом случае
+	KeepPerFeed int
+	// новости и фиды, которых нет в этом списке, удаляются; пустой список - не удалять
+	ActiveFeeds []string
+}
+
+type PruneResult struct {
+	ExpiredItems     int64
+	RemovedFeedItems int64
+	RemovedFeeds     int64
+}
+
+type SearchQuery struct {
+	Query string
+	// URL или название фида
+	Feed   string
+	From   time.Time
+	To     time.Time
+	IsSent *bool
+	// не больше MaxSearchLimit
+	Limit int
+
+	HighlightStart string
+	HighlightEnd   string
+	// HTML - сниппеты экранируются как HTML, совпадения выделяются HighlightStart/HighlightEnd
+	HTML bool
+}
+
+// MaxSearchLimit - больше результатов поиск не возвращает.
+const MaxSearchLimit = 100
+
+// Метки совпадений, которые база ставит в сниппеты; Highlight заменяет их на HighlightStart/HighlightEnd.
+const (
+	SearchMarkStart = "\x02"
+	SearchMarkEnd   = "\x03"
+)
+
+// WithDefaults по умолчанию выделяет совпадения тегом <b> в экранированном HTML.
+func (q SearchQuery) WithDefaults() SearchQuery {
+	if q.Limit <= 0 {
+		q.Limit = 20
+	}
+	if q.Limit > MaxSearchLimit {
+		q.Limit = MaxSearchLimit
+	}
+	if q.HighlightStart == "" && q.HighlightEnd == "" {
+		q.HighlightStart, q.HighlightEnd = "<b>", "</b>"
+		q.HTML = true
+	}
+	return q
+}
+
+// Highlight превращает сниппет из базы с метками SearchMarkStart/SearchMarkEnd в текст результата.
+func (q SearchQuery) Highlight(snippet string) string {
+	if q.HTML {
+		snippet = html.EscapeString(snippet)
+	}
+	return strings.NewReplacer(SearchMarkStart, q.HighlightStart, SearchMarkEnd, q.HighlightEnd).Replace(snippet)
+}
+
+type SearchResult struct {
+	ItemID      string    `json:"item_id"`
+	FeedURL     string    `json:"feed_url"`
+	FeedTitle   string    `json:"feed_title"`
+	Title       string    `json:"title"`
+	Link        string    `json:"link"`
+	PublishedAt time.Time `json:"published_at"`
+	IsSent      bool      `json:"is_sent"`
+	// чем больше, тем релевантнее
+	Score              float64 `json:"score"`
+	TitleSnippet       string  `json:"title_snippet"`
+	DescriptionSnippet string  `json:"description_snippet"`
+}
+
+// ItemStatus - состояние доставки новости.
+type ItemStatus string
+
+const (
+	// ещё не отправлялась
+	ItemStatusPending ItemStatus = "pending"
+	// не отправлена, были неудачные попытки
+	ItemStatusFailed ItemStatus = "failed"
+	ItemStatusSent   ItemStatus = "sent"
+	// «отправлена» в режиме dry-run, в Telegram не ушла
+	ItemStatusDryRun ItemStatus = "dry_run"
+)
+
+// DeliveryStatus - чем закончилась отправка новости.
+type DeliveryStatus string
+
+const (
+	DeliveryStatusSent   DeliveryStatus = "sent"
+	DeliveryStatusDryRun DeliveryStatus = "dry_run"
+)
+
+// ItemFilter отбирает новости для просмотра и повторной отправки. Пустые поля не фильтруют.
+type ItemFilter struct {
+	IDs []string
+	// URL или название фида
+	Feed   string
+	Status ItemStatus
+	Limit  int
+}
+
+func (f ItemFilter) WithDefaults() ItemFilter {
+	if f.Limit <= 0 {
+		f.Limit = 50
+	}
+	return f
+}
+
+type StoredItem struct {
+	ID          string     `json:"id"`
+	FeedURL     string     `json:"feed_url"`
+	FeedTitle   string     `json:"feed_title"`
+	Title       string     `json:"title"`
+	Link        string     `json:"link"`
+	PublishedAt time.Time  `json:"published_at"`
+	Status      ItemStatus `json:"status"`
+	SentAt      *time.Time `json:"sent_at,omitempty"`
+	FailedCount int        `json:"failed_count"`
+}
+
+// ItemFingerprint - отпечаток текста новости для поиска почти дублей.
+type ItemFingerprint struct {
+	ItemID      string
+	FeedURL     string
+	Fingerprint uint64
+	Status      ItemStatus
+}
+
+// SentDigest - отправленный дайджест. Отметки новостей и время отправки записываются
+// одной транзакцией, чтобы очередь и расписание не разошлись.
+type SentDigest struct {
+	Key    string
+	SentAt time.Time
+	Status DeliveryStatus
+	// новости, для которых дайджест был последним неотправленным выходом
+	ItemIDs []string
+	// новости, которые ещё ждут других выходов: у них отмечается только Output
+	Output         string
+	PartialItemIDs []string
+}
+
+// FeedStats - состояние фида в базе. Фиды без записи в feeds имеют нулевые LastChecked/LastPosted.
+type FeedStats struct {
+	URL         string    `json:"url"`
+	ResolvedURL string    `json:"resolved_url,omitempty"`
+	LastChecked time.Time `json:"last_checked"`
+	LastPosted  time.Time `json:"last_posted"`
+	Pending     int       `json:"pending"`
+	Failed      int       `json:"failed"`
+	Sent        int       `json:"sent"`
+	DryRun      int       `json:"dry_run"`
+}
+
+// MigrationVersion - текущая версия схемы; Version 0 - миграции не применялись.
+type MigrationVersion struct {
+	Version uint `json:"version"`
+	Dirty   bool `json:"dirty"`
 }
@@ -0,0 +1,65 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"rssgram/internal/storage"
+)
+
+// GetDigestSentAt возвращает время отправки последнего дайджеста key; нулевое, если дайджестов ещё не было.
+func (s *Storage) GetDigestSentAt(ctx context.Context, key string) (time.Time, error) {
+	var sentAt time.Time
+	err := s.db.QueryRowContext(ctx, "SELECT sent_at FROM digests WHERE key = $1", key).Scan(&sentAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get digest %s: %w", key, err)
+	}
+
+	return sentAt.UTC(), nil
+}
+
+// SetDigestSent запоминает отправку дайджеста и отмечает его новости. Без новостей
+// только запоминает время - так начинается расписание нового дайджеста.
+func (s *Storage) SetDigestSent(ctx context.Context, digest storage.SentDigest) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO digests (key, sent_at) VALUES ($1, $2)
+	ON CONFLICT (key) DO UPDATE SET sent_at = excluded.sent_at`, digest.Key, digest.SentAt.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to update digest %s: %w", digest.Key, err)
+	}
+
+	if len(digest.ItemIDs) > 0 {
+		_, err = tx.ExecContext(ctx, `UPDATE items SET is_sent = TRUE, delivery_status = $1, sent_at = $2, updated_at = $3 WHERE id = ANY($4)`,
+			digest.Status, digest.SentAt.UTC(), now, digest.ItemIDs)
+		if err != nil {
+			return fmt.Errorf("failed to update digest items: %w", err)
+		}
+	}
+
+	if len(digest.PartialItemIDs) > 0 {
+		_, err = tx.ExecContext(ctx, `UPDATE items SET sent_outputs = sent_outputs || to_jsonb($1::text), updated_at = $2 WHERE id = ANY($3)`,
+			digest.Output, now, digest.PartialItemIDs)
+		if err != nil {
+			return fmt.Errorf("failed to update digest items sent outputs: %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit digest: %w", err)
+	}
+
+	return nil
+}
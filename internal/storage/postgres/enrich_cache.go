@@ -0,0 +1,57 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"rssgram/internal/feed"
+)
+
+// GetEnrichCache возвращает сохранённое обогащение страницы key, полученное не раньше fetchedAfter; nil, если его нет.
+func (s *Storage) GetEnrichCache(ctx context.Context, key string, fetchedAfter time.Time) (*feed.SiteDescription, error) {
+	var data []byte
+	err := s.db.QueryRowContext(ctx, "SELECT data FROM enrich_cache WHERE key = $1 AND fetched_at >= $2", key, fetchedAfter.UTC()).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get enrich cache %s: %w", key, err)
+	}
+
+	var description feed.SiteDescription
+	if err = json.Unmarshal(data, &description); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal enrich cache %s: %w", key, err)
+	}
+
+	return &description, nil
+}
+
+// SetEnrichCache сохраняет обогащение страницы key, заменяя прежнее.
+func (s *Storage) SetEnrichCache(ctx context.Context, key string, description feed.SiteDescription, fetchedAt time.Time) error {
+	data, err := json.Marshal(description)
+	if err != nil {
+		return fmt.Errorf("failed to marshal enrich cache %s: %w", key, err)
+	}
+
+	_, err = s.db.ExecContext(ctx, `INSERT INTO enrich_cache (key, data, fetched_at) VALUES ($1, $2, $3)
+	ON CONFLICT (key) DO UPDATE SET data = excluded.data, fetched_at = excluded.fetched_at`, key, string(data), fetchedAt.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to set enrich cache %s: %w", key, err)
+	}
+
+	return nil
+}
+
+// DeleteEnrichCache удаляет обогащения, полученные раньше fetchedBefore, и возвращает их число.
+func (s *Storage) DeleteEnrichCache(ctx context.Context, fetchedBefore time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM enrich_cache WHERE fetched_at < $1", fetchedBefore.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete enrich cache: %w", err)
+	}
+
+	return res.RowsAffected()
+}
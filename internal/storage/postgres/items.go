@@ -0,0 +1,234 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"rssgram/internal/storage"
+)
+
+// itemFilterWhere собирает условие WHERE по фильтру; номера параметров начинаются с len(args)+1.
+func itemFilterWhere(filter storage.ItemFilter, args []any) (string, []any, error) {
+	conds := []string{"TRUE"}
+
+	if len(filter.IDs) > 0 {
+		args = append(args, filter.IDs)
+		conds = append(conds, fmt.Sprintf("id = ANY($%d)", len(args)))
+	}
+
+	if filter.Feed != "" {
+		args = append(args, filter.Feed)
+		conds = append(conds, fmt.Sprintf("(feed_url = $%d OR feed_title = $%d)", len(args), len(args)))
+	}
+
+	switch filter.Status {
+	case "":
+	case storage.ItemStatusPending:
+		conds = append(conds, "NOT is_sent AND failed_count = 0")
+	case storage.ItemStatusFailed:
+		conds = append(conds, "NOT is_sent AND failed_count > 0")
+	case storage.ItemStatusSent:
+		conds = append(conds, "is_sent AND delivery_status <> 'dry_run'")
+	case storage.ItemStatusDryRun:
+		conds = append(conds, "is_sent AND delivery_status = 'dry_run'")
+	default:
+		return "", nil, fmt.Errorf("unknown item status %q", filter.Status)
+	}
+
+	return strings.Join(conds, " AND "), args, nil
+}
+
+func (s *Storage) ListItems(ctx context.Context, filter storage.ItemFilter) ([]storage.StoredItem, error) {
+	filter = filter.WithDefaults()
+
+	where, args, err := itemFilterWhere(filter, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.queryItems(ctx, where, args, filter.Limit)
+}
+
+// FindDuplicateItem ищет новость с той же нормализованной ссылкой: ещё не отправленную
+// или отправленную после sentAfter.
+func (s *Storage) FindDuplicateItem(ctx context.Context, normalizedLink string, sentAfter time.Time) (*storage.StoredItem, error) {
+	items, err := s.queryItems(ctx, "normalized_link = $1 AND (NOT is_sent OR sent_at >= $2)", []any{normalizedLink, sentAfter.UTC()}, 1)
+	if err != nil || len(items) == 0 {
+		return nil, err
+	}
+	return &items[0], nil
+}
+
+// AddItemSource дописывает к новости название ещё одного фида, где она встретилась.
+func (s *Storage) AddItemSource(ctx context.Context, itemID, source string) error {
+	stmt := `UPDATE items SET extra_sources = extra_sources || to_jsonb($1::text), updated_at = $2
+	WHERE id = $3 AND NOT extra_sources @> jsonb_build_array($1::text)`
+	_, err := s.db.ExecContext(ctx, stmt, source, time.Now().UTC(), itemID)
+	if err != nil {
+		return fmt.Errorf("failed to add item source: %w", err)
+	}
+	return nil
+}
+
+// ListFingerprints возвращает отпечатки неотправленных новостей и отправленных после sentAfter.
+func (s *Storage) ListFingerprints(ctx context.Context, sentAfter time.Time) ([]storage.ItemFingerprint, error) {
+	stmt := `SELECT id, feed_url, fingerprint, is_sent, delivery_status, failed_count
+	FROM items WHERE fingerprint <> 0 AND (NOT is_sent OR sent_at >= $1)`
+
+	rows, err := s.db.QueryContext(ctx, stmt, sentAfter.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list fingerprints: %w", err)
+	}
+	defer rows.Close()
+
+	var result []storage.ItemFingerprint
+	for rows.Next() {
+		var f storage.ItemFingerprint
+		var fingerprint int64
+		var isSent bool
+		var deliveryStatus string
+		var failedCount int
+
+		if err = rows.Scan(&f.ItemID, &f.FeedURL, &fingerprint, &isSent, &deliveryStatus, &failedCount); err != nil {
+			return nil, fmt.Errorf("failed to fetch fingerprints: %w", err)
+		}
+
+		f.Fingerprint = uint64(fingerprint)
+		f.Status = itemStatus(isSent, storage.DeliveryStatus(deliveryStatus), failedCount)
+		result = append(result, f)
+	}
+
+	return result, rows.Err()
+}
+
+// queryItems выбирает новости по условию; номер параметра limit - len(args)+1.
+func (s *Storage) queryItems(ctx context.Context, where string, args []any, limit int) ([]storage.StoredItem, error) {
+	args = append(args, limit)
+	stmt := fmt.Sprintf(`SELECT id, feed_url, feed_title, title, link, published_at, is_sent, delivery_status, sent_at, failed_count
+	FROM items WHERE %s ORDER BY published_at DESC LIMIT $%d`, where, len(args))
+
+	rows, err := s.db.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list items: %w", err)
+	}
+	defer rows.Close()
+
+	var items []storage.StoredItem
+	for rows.Next() {
+		var item storage.StoredItem
+		var sentAt *time.Time
+		var isSent bool
+		var deliveryStatus string
+
+		err = rows.Scan(&item.ID, &item.FeedURL, &item.FeedTitle, &item.Title, &item.Link, &item.PublishedAt, &isSent, &deliveryStatus, &sentAt, &item.FailedCount)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch items: %w", err)
+		}
+
+		item.PublishedAt = item.PublishedAt.UTC()
+		if sentAt != nil {
+			t := sentAt.UTC()
+			item.SentAt = &t
+		}
+
+		item.Status = itemStatus(isSent, storage.DeliveryStatus(deliveryStatus), item.FailedCount)
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+func itemStatus(isSent bool, deliveryStatus storage.DeliveryStatus, failedCount int) storage.ItemStatus {
+	switch {
+	case isSent && deliveryStatus == storage.DeliveryStatusDryRun:
+		return storage.ItemStatusDryRun
+	case isSent:
+		return storage.ItemStatusSent
+	case failedCount > 0:
+		return storage.ItemStatusFailed
+	default:
+		return storage.ItemStatusPending
+	}
+}
+
+// RequeueItems возвращает новости в очередь отправки: сбрасывает is_sent и счётчик ошибок.
+// Limit фильтра не учитывается.
+func (s *Storage) RequeueItems(ctx context.Context, filter storage.ItemFilter) (int64, error) {
+	where, args, err := itemFilterWhere(filter, []any{time.Now().UTC()})
+	if err != nil {
+		return 0, err
+	}
+
+	stmt := `UPDATE items SET is_sent = FALSE, delivery_status = '', sent_at = NULL, sent_outputs = '[]', failed_count = 0, updated_at = $1 WHERE ` + where
+
+	res, err := s.db.ExecContext(ctx, stmt, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to requeue items: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+func (s *Storage) ListFeedStats(ctx context.Context) ([]storage.FeedStats, error) {
+	stats := make(map[string]*storage.FeedStats)
+
+	rows, err := s.db.QueryContext(ctx, "SELECT url, last_checked, last_post, resolved_url FROM feeds")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch feeds: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		st := &storage.FeedStats{}
+		if err = rows.Scan(&st.URL, &st.LastChecked, &st.LastPosted, &st.ResolvedURL); err != nil {
+			return nil, fmt.Errorf("failed to fetch feeds: %w", err)
+		}
+		st.LastChecked, st.LastPosted = st.LastChecked.UTC(), st.LastPosted.UTC()
+		stats[st.URL] = st
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	stmt := `
+	SELECT feed_url,
+		count(*) FILTER (WHERE NOT is_sent AND failed_count = 0),
+		count(*) FILTER (WHERE NOT is_sent AND failed_count > 0),
+		count(*) FILTER (WHERE is_sent AND delivery_status <> 'dry_run'),
+		count(*) FILTER (WHERE is_sent AND delivery_status = 'dry_run')
+	FROM items GROUP BY feed_url`
+
+	rows, err = s.db.QueryContext(ctx, stmt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count feed items: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var url string
+		var pending, failed, sent, dryRun int
+		if err = rows.Scan(&url, &pending, &failed, &sent, &dryRun); err != nil {
+			return nil, fmt.Errorf("failed to count feed items: %w", err)
+		}
+
+		st, ok := stats[url]
+		if !ok {
+			st = &storage.FeedStats{URL: url}
+			stats[url] = st
+		}
+		st.Pending, st.Failed, st.Sent, st.DryRun = pending, failed, sent, dryRun
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result := make([]storage.FeedStats, 0, len(stats))
+	for _, st := range stats {
+		result = append(result, *st)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].URL < result[j].URL })
+
+	return result, nil
+}
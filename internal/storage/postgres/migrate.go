@@ -0,0 +1,115 @@
+package postgres
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+
+	"rssgram/internal/storage"
+
+	"github.com/golang-migrate/migrate/v4"
+	pgxmigrate "github.com/golang-migrate/migrate/v4/database/pgx/v5"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// newMigrate создаёт migrate поверх отдельного подключения с настройками основного пула.
+// Драйвер migrate держит соединение до Close и закрывает вместе с ним свой *sql.DB,
+// поэтому соединения общего пула ему не отдаются. Вызывающий обязан закрыть migrate.
+func (s *Storage) newMigrate(ctx context.Context) (*migrate.Migrate, error) {
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection: %w", err)
+	}
+
+	var config *pgx.ConnConfig
+	err = conn.Raw(func(driverConn any) error {
+		c, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return fmt.Errorf("unexpected driver connection %T", driverConn)
+		}
+		config = c.Conn().Config()
+		return nil
+	})
+	conn.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection config: %w", err)
+	}
+
+	db := stdlib.OpenDB(*config)
+	instance, err := pgxmigrate.WithInstance(db, &pgxmigrate.Config{})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed create postgres instance: %w", err)
+	}
+
+	d, err := iofs.New(migrations, "migrations")
+	if err != nil {
+		instance.Close()
+		return nil, fmt.Errorf("failed to init iofs: %w", err)
+	}
+
+	m, err := migrate.NewWithInstance("iofs", d, "pgx5", instance)
+	if err != nil {
+		instance.Close()
+		return nil, fmt.Errorf("failed to init migrate: %w", err)
+	}
+
+	return m, nil
+}
+
+// Migrate применяет все миграции схемы PostgreSQL.
+func (s *Storage) Migrate(ctx context.Context) error {
+	m, err := s.newMigrate(ctx)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	err = m.Up()
+	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("failed to migrate: %w", err)
+	}
+	return nil
+}
+
+// MigrateDown откатывает steps последних миграций, steps <= 0 - все.
+func (s *Storage) MigrateDown(ctx context.Context, steps int) error {
+	m, err := s.newMigrate(ctx)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	if steps > 0 {
+		err = m.Steps(-steps)
+	} else {
+		err = m.Down()
+	}
+	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("failed to migrate down: %w", err)
+	}
+	return nil
+}
+
+func (s *Storage) MigrationVersion(ctx context.Context) (storage.MigrationVersion, error) {
+	m, err := s.newMigrate(ctx)
+	if err != nil {
+		return storage.MigrationVersion{}, err
+	}
+	defer m.Close()
+
+	version, dirty, err := m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return storage.MigrationVersion{}, nil
+	}
+	if err != nil {
+		return storage.MigrationVersion{}, fmt.Errorf("failed to get migration version: %w", err)
+	}
+	return storage.MigrationVersion{Version: version, Dirty: dirty}, nil
+}
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS feeds;
+DROP TABLE IF EXISTS items;
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS feeds (
+     url TEXT NOT NULL PRIMARY KEY,
+     last_checked TIMESTAMPTZ NOT NULL,
+     last_post TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS items (
+     id TEXT NOT NULL PRIMARY KEY,
+     feed_title TEXT NOT NULL,
+     title TEXT NOT NULL,
+     link TEXT NOT NULL DEFAULT '',
+     description TEXT NOT NULL,
+     image_url TEXT,
+     tags JSONB NOT NULL DEFAULT '[]',
+     metadata JSONB NOT NULL DEFAULT '{}',
+     published_at TIMESTAMPTZ NOT NULL,
+     is_sent BOOLEAN NOT NULL DEFAULT FALSE,
+     sent_at TIMESTAMPTZ,
+     failed_count INT NOT NULL DEFAULT 0,
+     updated_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS items_ready_to_send_idx ON items (published_at) WHERE NOT is_sent;
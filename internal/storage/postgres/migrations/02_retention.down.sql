@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS items_sent_at_idx;
+DROP INDEX IF EXISTS items_feed_url_published_at_idx;
+ALTER TABLE items DROP COLUMN IF EXISTS feed_url;
@@ -0,0 +1,3 @@
+ALTER TABLE items ADD COLUMN IF NOT EXISTS feed_url TEXT NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS items_feed_url_published_at_idx ON items (feed_url, published_at);
+CREATE INDEX IF NOT EXISTS items_sent_at_idx ON items (sent_at) WHERE is_sent;
@@ -0,0 +1,7 @@
+ALTER TABLE items ADD COLUMN IF NOT EXISTS search tsvector GENERATED ALWAYS AS (
+     setweight(to_tsvector('simple', title), 'A') ||
+     setweight(to_tsvector('simple', description), 'B') ||
+     setweight(to_tsvector('simple', tags::text), 'C')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS items_search_idx ON items USING GIN (search);
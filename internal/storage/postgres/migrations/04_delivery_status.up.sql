@@ -0,0 +1,2 @@
+ALTER TABLE items ADD COLUMN IF NOT EXISTS delivery_status TEXT NOT NULL DEFAULT '';
+UPDATE items SET delivery_status = 'sent' WHERE is_sent;
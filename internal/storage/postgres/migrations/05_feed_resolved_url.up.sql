@@ -0,0 +1 @@
+ALTER TABLE feeds ADD COLUMN IF NOT EXISTS resolved_url TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+ALTER TABLE items DROP COLUMN IF EXISTS sent_outputs;
+ALTER TABLE items DROP COLUMN IF EXISTS silent;
+ALTER TABLE items DROP COLUMN IF EXISTS outputs;
@@ -0,0 +1,3 @@
+ALTER TABLE items ADD COLUMN IF NOT EXISTS outputs JSONB NOT NULL DEFAULT '[]';
+ALTER TABLE items ADD COLUMN IF NOT EXISTS silent BOOLEAN;
+ALTER TABLE items ADD COLUMN IF NOT EXISTS sent_outputs JSONB NOT NULL DEFAULT '[]';
@@ -0,0 +1,3 @@
+ALTER TABLE items ADD COLUMN IF NOT EXISTS normalized_link TEXT NOT NULL DEFAULT '';
+ALTER TABLE items ADD COLUMN IF NOT EXISTS extra_sources JSONB NOT NULL DEFAULT '[]';
+CREATE INDEX IF NOT EXISTS items_normalized_link_idx ON items (normalized_link);
@@ -0,0 +1 @@
+ALTER TABLE items ADD COLUMN IF NOT EXISTS fingerprint BIGINT NOT NULL DEFAULT 0;
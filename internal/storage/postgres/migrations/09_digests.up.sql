@@ -0,0 +1,4 @@
+CREATE TABLE IF NOT EXISTS digests (
+     key TEXT NOT NULL PRIMARY KEY,
+     sent_at TIMESTAMPTZ NOT NULL
+);
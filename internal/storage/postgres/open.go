@@ -0,0 +1,36 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"rssgram/internal/storage"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+// Open подключается к PostgreSQL с ограничениями пула из конфига.
+func Open(ctx context.Context, conf storage.Config) (*sql.DB, error) {
+	conf = conf.WithDefaults()
+
+	if conf.DSN == "" {
+		return nil, fmt.Errorf("database dsn is required for driver %s", conf.Driver)
+	}
+
+	db, err := sql.Open("pgx", conf.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open PostgreSQL database: %w", err)
+	}
+
+	db.SetMaxOpenConns(conf.MaxOpenConns)
+	db.SetMaxIdleConns(conf.MaxIdleConns)
+	db.SetConnMaxLifetime(conf.ConnMaxLifetime)
+
+	if err = db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to PostgreSQL database: %w", err)
+	}
+
+	return db, nil
+}
@@ -0,0 +1,73 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"rssgram/internal/storage"
+)
+
+func (s *Storage) PruneItems(ctx context.Context, policy storage.PrunePolicy) (storage.PruneResult, error) {
+	var result storage.PruneResult
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return result, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// у старых записей feed_url пустой, их фиды различаются только по feed_title
+	stmt := `
+	DELETE FROM items WHERE id IN (
+		SELECT id FROM (
+			SELECT id, is_sent, sent_at, ROW_NUMBER() OVER (PARTITION BY feed_url, CASE WHEN feed_url = '' THEN feed_title ELSE '' END ORDER BY published_at DESC) AS rn
+			FROM items
+		) ranked WHERE is_sent AND sent_at < $1 AND rn > $2
+	)
+`
+	res, err := tx.ExecContext(ctx, stmt, policy.SentBefore.UTC(), policy.KeepPerFeed)
+	if err != nil {
+		return result, fmt.Errorf("failed to prune expired items: %w", err)
+	}
+	result.ExpiredItems, _ = res.RowsAffected()
+
+	if len(policy.ActiveFeeds) > 0 {
+		// у старых записей feed_url пустой - их удаляет только срок хранения
+		res, err = tx.ExecContext(ctx, "DELETE FROM items WHERE feed_url <> '' AND NOT (feed_url = ANY($1))", policy.ActiveFeeds)
+		if err != nil {
+			return result, fmt.Errorf("failed to prune items of removed feeds: %w", err)
+		}
+		result.RemovedFeedItems, _ = res.RowsAffected()
+
+		res, err = tx.ExecContext(ctx, "DELETE FROM feeds WHERE NOT (url = ANY($1))", policy.ActiveFeeds)
+		if err != nil {
+			return result, fmt.Errorf("failed to prune removed feeds: %w", err)
+		}
+		result.RemovedFeeds, _ = res.RowsAffected()
+	}
+
+	if err = tx.Commit(); err != nil {
+		return result, fmt.Errorf("failed to commit prune: %w", err)
+	}
+
+	return result, nil
+}
+
+// Vacuum дополняет autovacuum после массового удаления.
+func (s *Storage) Vacuum(ctx context.Context) error {
+	for _, table := range []string{"items", "feeds"} {
+		if _, err := s.db.ExecContext(ctx, "VACUUM (ANALYZE) "+table); err != nil {
+			return fmt.Errorf("failed to vacuum %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+func (s *Storage) Size(ctx context.Context) (int64, error) {
+	var size int64
+	err := s.db.QueryRowContext(ctx, "SELECT pg_database_size(current_database())").Scan(&size)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get database size: %w", err)
+	}
+	return size, nil
+}
@@ -0,0 +1,99 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"rssgram/internal/storage"
+)
+
+// tsQueryExpression превращает пользовательский запрос в выражение to_tsquery:
+// слова объединяются через AND, "слово*" - поиск по префиксу.
+func tsQueryExpression(query string) string {
+	var terms []string
+
+	for _, word := range strings.Fields(query) {
+		prefix := strings.HasSuffix(word, "*")
+		word = strings.Trim(word, `*'\`)
+		if word == "" {
+			continue
+		}
+
+		term := "'" + strings.NewReplacer(`'`, `''`, `\`, `\\`).Replace(word) + "'"
+		if prefix {
+			term += ":*"
+		}
+		terms = append(terms, term)
+	}
+
+	return strings.Join(terms, " & ")
+}
+
+func (s *Storage) Search(ctx context.Context, query storage.SearchQuery) ([]storage.SearchResult, error) {
+	query = query.WithDefaults()
+
+	tsQuery := tsQueryExpression(query.Query)
+	if tsQuery == "" {
+		return nil, fmt.Errorf("search query is empty")
+	}
+
+	headlineOptions := func(maxWords int) string {
+		return fmt.Sprintf(`StartSel="%s", StopSel="%s", MaxWords=%d, MinWords=%d`,
+			storage.SearchMarkStart, storage.SearchMarkEnd, maxWords, maxWords/2,
+		)
+	}
+
+	stmt := `
+	SELECT id, feed_url, feed_title, title, link, published_at, is_sent,
+		ts_rank(search, q.query),
+		ts_headline('simple', title, q.query, $2),
+		ts_headline('simple', description, q.query, $3)
+	FROM items, to_tsquery('simple', $1) AS q(query)
+	WHERE search @@ q.query`
+
+	args := []any{tsQuery, headlineOptions(16), headlineOptions(32)}
+
+	if query.Feed != "" {
+		args = append(args, query.Feed)
+		stmt += fmt.Sprintf(" AND (feed_url = $%d OR feed_title = $%d)", len(args), len(args))
+	}
+	if !query.From.IsZero() {
+		args = append(args, query.From.UTC())
+		stmt += fmt.Sprintf(" AND published_at >= $%d", len(args))
+	}
+	if !query.To.IsZero() {
+		args = append(args, query.To.UTC())
+		stmt += fmt.Sprintf(" AND published_at < $%d", len(args))
+	}
+	if query.IsSent != nil {
+		args = append(args, *query.IsSent)
+		stmt += fmt.Sprintf(" AND is_sent = $%d", len(args))
+	}
+
+	args = append(args, query.Limit)
+	stmt += fmt.Sprintf(" ORDER BY 8 DESC, published_at DESC LIMIT $%d", len(args))
+
+	rows, err := s.db.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search items: %w", err)
+	}
+	defer rows.Close()
+
+	var results []storage.SearchResult
+	for rows.Next() {
+		var r storage.SearchResult
+
+		err = rows.Scan(&r.ItemID, &r.FeedURL, &r.FeedTitle, &r.Title, &r.Link, &r.PublishedAt, &r.IsSent, &r.Score, &r.TitleSnippet, &r.DescriptionSnippet)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch search results: %w", err)
+		}
+		r.PublishedAt = r.PublishedAt.UTC()
+		r.TitleSnippet = query.Highlight(r.TitleSnippet)
+		r.DescriptionSnippet = query.Highlight(r.DescriptionSnippet)
+
+		results = append(results, r)
+	}
+
+	return results, rows.Err()
+}
@@ -0,0 +1,277 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"rssgram/internal/feed"
+	"rssgram/internal/storage"
+)
+
+type Storage struct {
+	db *sql.DB
+}
+
+func (s *Storage) DeleteFeed(ctx context.Context, url string) error {
+	stmt := "DELETE FROM feeds WHERE url=$1"
+	_, err := s.db.ExecContext(ctx, stmt, url)
+	return err
+}
+
+func (s *Storage) UpsertFeed(ctx context.Context, url string, lastChecked, lastPost time.Time) error {
+	stmt := "INSERT INTO feeds (url, last_checked, last_post) VALUES ($1, $2, $3) ON CONFLICT(url) DO UPDATE SET last_checked=excluded.last_checked, last_post=excluded.last_post"
+	_, err := s.db.ExecContext(ctx, stmt, url, lastChecked.UTC(), lastPost.UTC())
+	return err
+}
+
+// SetFeedResolvedURL запоминает адрес фида, найденный на странице из конфига.
+func (s *Storage) SetFeedResolvedURL(ctx context.Context, url, resolvedURL string) error {
+	_, err := s.db.ExecContext(ctx, "UPDATE feeds SET resolved_url=$1 WHERE url=$2", resolvedURL, url)
+	if err != nil {
+		return fmt.Errorf("failed to set resolved url: %w", err)
+	}
+	return nil
+}
+
+func (s *Storage) GetFeedByURL(ctx context.Context, url string) (*storage.StoredFeed, error) {
+	stmt := "SELECT last_checked, last_post, resolved_url FROM feeds WHERE url=$1"
+
+	var lastChecked, lastPosted time.Time
+	var resolvedURL string
+
+	err := s.db.QueryRowContext(ctx, stmt, url).Scan(&lastChecked, &lastPosted, &resolvedURL)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to fetch feed: %w", err)
+	}
+
+	return &storage.StoredFeed{
+		URL:         url,
+		LastChecked: lastChecked.UTC(),
+		LastPosted:  lastPosted.UTC(),
+		ResolvedURL: resolvedURL,
+	}, nil
+}
+
+func (s *Storage) InsertItem(ctx context.Context, item *feed.FeedItem) error {
+	itemMetaJSON, err := item.GetMetadataJson()
+	if err != nil {
+		return fmt.Errorf("failed to marshal item metadata: %w", err)
+	}
+
+	itemTagsJSON, err := item.GetTagsJson()
+	if err != nil {
+		return fmt.Errorf("failed to marshal item tags: %w", err)
+	}
+
+	itemOutputsJSON, err := item.GetOutputsJson()
+	if err != nil {
+		return fmt.Errorf("failed to marshal item outputs: %w", err)
+	}
+
+	now := time.Now().UTC()
+
+	stmt := `
+	INSERT INTO items (id, feed_url, feed_title, title, link, description, image_url, tags, metadata, published_at, updated_at, outputs, silent, normalized_link, fingerprint)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+	ON CONFLICT(id) DO NOTHING
+`
+	_, err = s.db.ExecContext(ctx, stmt,
+		item.ID,
+		item.FeedURL,
+		item.FeedTitle,
+		item.Title,
+		item.Link,
+		item.Description,
+		item.ImageURL,
+		itemTagsJSON,
+		itemMetaJSON,
+		item.GetPublishedAt(now),
+		now,
+		itemOutputsJSON,
+		item.Silent,
+		item.NormalizedLink,
+		// SimHash хранится как знаковое число того же размера
+		int64(item.Fingerprint),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert item: %w", err)
+	}
+	return nil
+}
+
+func (s *Storage) GetItemsReadyToSend(ctx context.Context, limit int) ([]feed.FeedItem, error) {
+	stmt := "SELECT id, feed_url, feed_title, title, link, COALESCE(image_url, ''), description, published_at, tags, metadata, outputs, silent, sent_outputs, extra_sources FROM items WHERE NOT is_sent ORDER BY published_at"
+
+	if limit > 0 {
+		stmt += fmt.Sprintf(" LIMIT %d", limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, stmt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch ready items: %w", err)
+	}
+	defer rows.Close()
+
+	var items []feed.FeedItem
+	for rows.Next() {
+		item := feed.FeedItem{}
+
+		var publishedAt time.Time
+		var tmpTags, tmpMeta, tmpOutputs, tmpSentOutputs, tmpExtraSources []byte
+		var silent sql.NullBool
+
+		err = rows.Scan(&item.ID, &item.FeedURL, &item.FeedTitle, &item.Title, &item.Link, &item.ImageURL, &item.Description, &publishedAt, &tmpTags, &tmpMeta, &tmpOutputs, &silent, &tmpSentOutputs, &tmpExtraSources)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch ready items: %w", err)
+		}
+
+		publishedAt = publishedAt.UTC()
+		item.PublishedAt = &publishedAt
+
+		err = json.Unmarshal(tmpTags, &item.Tags)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal tags: %w", err)
+		}
+
+		if err = unmarshalMetadata(&item, tmpMeta); err != nil {
+			return nil, err
+		}
+
+		if err = unmarshalRouting(&item, tmpOutputs, silent, tmpSentOutputs); err != nil {
+			return nil, err
+		}
+
+		err = json.Unmarshal(tmpExtraSources, &item.ExtraSources)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal extra sources: %w", err)
+		}
+
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+// CountSentItems считает по фидам новости, отправленные после sentAfter.
+func (s *Storage) CountSentItems(ctx context.Context, sentAfter time.Time) (map[string]int, error) {
+	stmt := `SELECT feed_url, COUNT(*) FROM items WHERE is_sent AND sent_at >= $1 GROUP BY feed_url`
+
+	rows, err := s.db.QueryContext(ctx, stmt, sentAfter.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to count sent items: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var feedURL string
+		var count int
+		if err = rows.Scan(&feedURL, &count); err != nil {
+			return nil, fmt.Errorf("failed to fetch sent items count: %w", err)
+		}
+		counts[feedURL] = count
+	}
+
+	return counts, rows.Err()
+}
+
+func (s *Storage) GetCountItemsSendFailed(ctx context.Context) (int, error) {
+	var count int
+
+	stmt := "SELECT count(id) FROM items WHERE NOT is_sent AND failed_count > 0"
+	err := s.db.QueryRowContext(ctx, stmt).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch count items: %w", err)
+	}
+
+	return count, nil
+}
+
+func (s *Storage) GetCountItemsReadyToSend(ctx context.Context) (int, error) {
+	var count int
+
+	stmt := "SELECT count(id) FROM items WHERE NOT is_sent"
+	err := s.db.QueryRowContext(ctx, stmt).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch count ready items: %w", err)
+	}
+
+	return count, nil
+}
+
+func (s *Storage) SetItemIsSent(ctx context.Context, itemID string, status storage.DeliveryStatus) error {
+	stmt := `UPDATE items SET is_sent = TRUE, delivery_status = $1, sent_at = $2, updated_at = $2 WHERE id=$3`
+	_, err := s.db.ExecContext(ctx, stmt, status, time.Now().UTC(), itemID)
+	if err != nil {
+		return fmt.Errorf("failed to update item: %w", err)
+	}
+	return nil
+}
+
+// SetItemOutputSent отмечает отправку новости в один из её выходов, чтобы при повторной
+// попытке после ошибки в другом выходе сообщение не ушло туда ещё раз.
+func (s *Storage) SetItemOutputSent(ctx context.Context, itemID, output string) error {
+	stmt := `UPDATE items SET sent_outputs = sent_outputs || to_jsonb($1::text), updated_at = $2 WHERE id=$3`
+	_, err := s.db.ExecContext(ctx, stmt, output, time.Now().UTC(), itemID)
+	if err != nil {
+		return fmt.Errorf("failed to update item sent outputs: %w", err)
+	}
+	return nil
+}
+
+func (s *Storage) IncrementItemFailedCounter(ctx context.Context, itemID string) error {
+	stmt := `UPDATE items SET failed_count = failed_count + 1, updated_at = $1 WHERE id=$2`
+	_, err := s.db.ExecContext(ctx, stmt, time.Now().UTC(), itemID)
+	if err != nil {
+		return fmt.Errorf("failed to update item failed counter: %w", err)
+	}
+	return nil
+}
+
+func (s *Storage) Close() error {
+	return s.db.Close()
+}
+
+func NewStorage(db *sql.DB) *Storage {
+	return &Storage{db: db}
+}
+
+// New подключается к PostgreSQL и создаёт поверх соединения Storage.
+func New(ctx context.Context, conf storage.Config) (*Storage, error) {
+	db, err := Open(ctx, conf)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewStorage(db), nil
+}
+
+// unmarshalRouting заполняет выходы новости и тихий режим, выбранные правилами маршрутизации.
+func unmarshalRouting(item *feed.FeedItem, outputs []byte, silent sql.NullBool, sentOutputs []byte) error {
+	if err := json.Unmarshal(outputs, &item.Outputs); err != nil {
+		return fmt.Errorf("failed to unmarshal outputs: %w", err)
+	}
+	if err := json.Unmarshal(sentOutputs, &item.SentOutputs); err != nil {
+		return fmt.Errorf("failed to unmarshal sent outputs: %w", err)
+	}
+	if silent.Valid {
+		item.Silent = &silent.Bool
+	}
+	return nil
+}
+
+// unmarshalMetadata заполняет метаданные новости; пустой объект оставляет Metadata nil, как у новой новости.
+func unmarshalMetadata(item *feed.FeedItem, metadata []byte) error {
+	if err := json.Unmarshal(metadata, &item.Metadata); err != nil {
+		return fmt.Errorf("failed to unmarshal metadata: %w", err)
+	}
+	if len(item.Metadata) == 0 {
+		item.Metadata = nil
+	}
+	return nil
+}
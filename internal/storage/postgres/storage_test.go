@@ -0,0 +1,41 @@
+//go:build integration
+// +build integration
+
+package postgres_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"rssgram/internal/storage"
+	"rssgram/internal/storage/backend"
+	"rssgram/internal/storage/postgres"
+	"rssgram/internal/storage/storagetest"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Тесты запускаются против локального PostgreSQL, см. `make test-postgres`.
+func TestStorage_Backend(t *testing.T) {
+	dsn := os.Getenv("RSSGRAM_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("RSSGRAM_TEST_POSTGRES_DSN is not set")
+	}
+
+	storagetest.Run(t, func(t *testing.T) backend.Storage {
+		ctx := context.Background()
+
+		db, err := postgres.Open(ctx, storage.Config{Driver: storage.DriverPostgres, DSN: dsn})
+		require.NoError(t, err)
+
+		s := postgres.NewStorage(db)
+		t.Cleanup(func() { s.Close() })
+
+		require.NoError(t, s.Migrate(ctx))
+
+		_, err = db.ExecContext(ctx, "TRUNCATE feeds, items, digests, enrich_cache")
+		require.NoError(t, err)
+		return s
+	})
+}
@@ -0,0 +1,27 @@
+package sqlite_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"rssgram/internal/storage"
+	"rssgram/internal/storage/backend"
+	"rssgram/internal/storage/sqlite"
+	"rssgram/internal/storage/storagetest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStorage_Backend(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) backend.Storage {
+		conf := storage.Config{DSN: "file:" + filepath.Join(t.TempDir(), "data.db")}
+
+		s, err := sqlite.New(context.Background(), conf)
+		require.NoError(t, err)
+		t.Cleanup(func() { s.Close() })
+
+		require.NoError(t, s.Migrate(context.Background()))
+		return s
+	})
+}
@@ -0,0 +1,66 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"rssgram/internal/storage"
+)
+
+// GetDigestSentAt возвращает время отправки последнего дайджеста key; нулевое, если дайджестов ещё не было.
+func (s *Storage) GetDigestSentAt(ctx context.Context, key string) (time.Time, error) {
+	var sentAt string
+	err := s.db.QueryRowContext(ctx, "SELECT sent_at FROM digests WHERE key = ?", key).Scan(&sentAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get digest %s: %w", key, err)
+	}
+
+	return parseTime(sentAt)
+}
+
+// SetDigestSent запоминает отправку дайджеста и отмечает его новости. Без новостей
+// только запоминает время - так начинается расписание нового дайджеста.
+func (s *Storage) SetDigestSent(ctx context.Context, digest storage.SentDigest) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	sentAt := formatTime(digest.SentAt)
+	now := formatTime(time.Now())
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO digests (key, sent_at) VALUES (?, ?)
+	ON CONFLICT (key) DO UPDATE SET sent_at = excluded.sent_at`, digest.Key, sentAt)
+	if err != nil {
+		return fmt.Errorf("failed to update digest %s: %w", digest.Key, err)
+	}
+
+	for _, id := range digest.ItemIDs {
+		_, err = tx.ExecContext(ctx, `UPDATE items SET is_sent = 1, delivery_status = ?, sent_at = ?, updated_at = ? WHERE id = ?`,
+			digest.Status, sentAt, now, id)
+		if err != nil {
+			return fmt.Errorf("failed to update item %s: %w", id, err)
+		}
+	}
+
+	for _, id := range digest.PartialItemIDs {
+		_, err = tx.ExecContext(ctx, `UPDATE items SET sent_outputs = json_insert(sent_outputs, '$[#]', ?), updated_at = ? WHERE id = ?`,
+			digest.Output, now, id)
+		if err != nil {
+			return fmt.Errorf("failed to update item %s sent outputs: %w", id, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit digest: %w", err)
+	}
+
+	return nil
+}
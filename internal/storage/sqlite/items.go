@@ -0,0 +1,253 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"rssgram/internal/storage"
+)
+
+// itemFilterWhere собирает условие WHERE по фильтру; пустой фильтр подходит под все новости.
+func itemFilterWhere(filter storage.ItemFilter) (string, []any, error) {
+	conds := []string{"1 = 1"}
+	var args []any
+
+	if len(filter.IDs) > 0 {
+		conds = append(conds, "id IN ("+strings.TrimSuffix(strings.Repeat("?,", len(filter.IDs)), ",")+")")
+		for _, id := range filter.IDs {
+			args = append(args, id)
+		}
+	}
+
+	if filter.Feed != "" {
+		conds = append(conds, "(feed_url = ? OR feed_title = ?)")
+		args = append(args, filter.Feed, filter.Feed)
+	}
+
+	switch filter.Status {
+	case "":
+	case storage.ItemStatusPending:
+		conds = append(conds, "is_sent = 0 AND failed_count = 0")
+	case storage.ItemStatusFailed:
+		conds = append(conds, "is_sent = 0 AND failed_count > 0")
+	case storage.ItemStatusSent:
+		conds = append(conds, "is_sent = 1 AND delivery_status != 'dry_run'")
+	case storage.ItemStatusDryRun:
+		conds = append(conds, "is_sent = 1 AND delivery_status = 'dry_run'")
+	default:
+		return "", nil, fmt.Errorf("unknown item status %q", filter.Status)
+	}
+
+	return strings.Join(conds, " AND "), args, nil
+}
+
+func (s *Storage) ListItems(ctx context.Context, filter storage.ItemFilter) ([]storage.StoredItem, error) {
+	filter = filter.WithDefaults()
+
+	where, args, err := itemFilterWhere(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.queryItems(ctx, where, args, filter.Limit)
+}
+
+// FindDuplicateItem ищет новость с той же нормализованной ссылкой: ещё не отправленную
+// или отправленную после sentAfter.
+func (s *Storage) FindDuplicateItem(ctx context.Context, normalizedLink string, sentAfter time.Time) (*storage.StoredItem, error) {
+	items, err := s.queryItems(ctx, "normalized_link = ? AND (is_sent = 0 OR sent_at >= ?)", []any{normalizedLink, formatTime(sentAfter)}, 1)
+	if err != nil || len(items) == 0 {
+		return nil, err
+	}
+	return &items[0], nil
+}
+
+// AddItemSource дописывает к новости название ещё одного фида, где она встретилась.
+func (s *Storage) AddItemSource(ctx context.Context, itemID, source string) error {
+	stmt := `UPDATE items SET extra_sources = json_insert(extra_sources, '$[#]', ?), updated_at = ?
+	WHERE id = ? AND NOT EXISTS (SELECT 1 FROM json_each(items.extra_sources) WHERE value = ?)`
+	_, err := s.db.ExecContext(ctx, stmt, source, formatTime(time.Now()), itemID, source)
+	if err != nil {
+		return fmt.Errorf("failed to add item source: %w", err)
+	}
+	return nil
+}
+
+// ListFingerprints возвращает отпечатки неотправленных новостей и отправленных после sentAfter.
+func (s *Storage) ListFingerprints(ctx context.Context, sentAfter time.Time) ([]storage.ItemFingerprint, error) {
+	stmt := `SELECT id, feed_url, fingerprint, is_sent, delivery_status, failed_count
+	FROM items WHERE fingerprint != 0 AND (is_sent = 0 OR sent_at >= ?)`
+
+	rows, err := s.db.QueryContext(ctx, stmt, formatTime(sentAfter))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list fingerprints: %w", err)
+	}
+	defer rows.Close()
+
+	var result []storage.ItemFingerprint
+	for rows.Next() {
+		var f storage.ItemFingerprint
+		var fingerprint int64
+		var isSent bool
+		var deliveryStatus string
+		var failedCount int
+
+		if err = rows.Scan(&f.ItemID, &f.FeedURL, &fingerprint, &isSent, &deliveryStatus, &failedCount); err != nil {
+			return nil, fmt.Errorf("failed to fetch fingerprints: %w", err)
+		}
+
+		f.Fingerprint = uint64(fingerprint)
+		f.Status = itemStatus(isSent, storage.DeliveryStatus(deliveryStatus), failedCount)
+		result = append(result, f)
+	}
+
+	return result, rows.Err()
+}
+
+func (s *Storage) queryItems(ctx context.Context, where string, args []any, limit int) ([]storage.StoredItem, error) {
+	stmt := `SELECT id, feed_url, feed_title, title, link, published_at, is_sent, delivery_status, sent_at, failed_count
+	FROM items WHERE ` + where + ` ORDER BY published_at DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := s.db.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list items: %w", err)
+	}
+	defer rows.Close()
+
+	var items []storage.StoredItem
+	for rows.Next() {
+		var item storage.StoredItem
+		var publishedAt string
+		var sentAt sql.NullString
+		var isSent bool
+		var deliveryStatus string
+
+		err = rows.Scan(&item.ID, &item.FeedURL, &item.FeedTitle, &item.Title, &item.Link, &publishedAt, &isSent, &deliveryStatus, &sentAt, &item.FailedCount)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch items: %w", err)
+		}
+
+		item.PublishedAt, err = parseTime(publishedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert published_at (%s): %w", item.ID, err)
+		}
+
+		if sentAt.Valid {
+			t, err := parseTime(sentAt.String)
+			if err != nil {
+				return nil, fmt.Errorf("failed to convert sent_at (%s): %w", item.ID, err)
+			}
+			item.SentAt = &t
+		}
+
+		item.Status = itemStatus(isSent, storage.DeliveryStatus(deliveryStatus), item.FailedCount)
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+func itemStatus(isSent bool, deliveryStatus storage.DeliveryStatus, failedCount int) storage.ItemStatus {
+	switch {
+	case isSent && deliveryStatus == storage.DeliveryStatusDryRun:
+		return storage.ItemStatusDryRun
+	case isSent:
+		return storage.ItemStatusSent
+	case failedCount > 0:
+		return storage.ItemStatusFailed
+	default:
+		return storage.ItemStatusPending
+	}
+}
+
+// RequeueItems возвращает новости в очередь отправки: сбрасывает is_sent и счётчик ошибок.
+// Limit фильтра не учитывается.
+func (s *Storage) RequeueItems(ctx context.Context, filter storage.ItemFilter) (int64, error) {
+	where, args, err := itemFilterWhere(filter)
+	if err != nil {
+		return 0, err
+	}
+
+	stmt := `UPDATE items SET is_sent = 0, delivery_status = '', sent_at = NULL, sent_outputs = '[]', failed_count = 0, updated_at = ? WHERE ` + where
+	args = append([]any{formatTime(time.Now())}, args...)
+
+	res, err := s.db.ExecContext(ctx, stmt, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to requeue items: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+func (s *Storage) ListFeedStats(ctx context.Context) ([]storage.FeedStats, error) {
+	stats := make(map[string]*storage.FeedStats)
+
+	rows, err := s.db.QueryContext(ctx, "SELECT url, last_checked, last_post, resolved_url FROM feeds")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch feeds: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var url, lastChecked, lastPosted, resolvedURL string
+		if err = rows.Scan(&url, &lastChecked, &lastPosted, &resolvedURL); err != nil {
+			return nil, fmt.Errorf("failed to fetch feeds: %w", err)
+		}
+
+		st := &storage.FeedStats{URL: url, ResolvedURL: resolvedURL}
+		if st.LastChecked, err = parseTime(lastChecked); err != nil {
+			return nil, fmt.Errorf("failed to convert last_checked (%s): %w", url, err)
+		}
+		if st.LastPosted, err = parseTime(lastPosted); err != nil {
+			return nil, fmt.Errorf("failed to convert last_post (%s): %w", url, err)
+		}
+		stats[url] = st
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	stmt := `
+	SELECT feed_url,
+		SUM(CASE WHEN is_sent = 0 AND failed_count = 0 THEN 1 ELSE 0 END),
+		SUM(CASE WHEN is_sent = 0 AND failed_count > 0 THEN 1 ELSE 0 END),
+		SUM(CASE WHEN is_sent = 1 AND delivery_status != 'dry_run' THEN 1 ELSE 0 END),
+		SUM(CASE WHEN is_sent = 1 AND delivery_status = 'dry_run' THEN 1 ELSE 0 END)
+	FROM items GROUP BY feed_url`
+
+	rows, err = s.db.QueryContext(ctx, stmt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count feed items: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var url string
+		var pending, failed, sent, dryRun int
+		if err = rows.Scan(&url, &pending, &failed, &sent, &dryRun); err != nil {
+			return nil, fmt.Errorf("failed to count feed items: %w", err)
+		}
+
+		st, ok := stats[url]
+		if !ok {
+			st = &storage.FeedStats{URL: url}
+			stats[url] = st
+		}
+		st.Pending, st.Failed, st.Sent, st.DryRun = pending, failed, sent, dryRun
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result := make([]storage.FeedStats, 0, len(stats))
+	for _, st := range stats {
+		result = append(result, *st)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].URL < result[j].URL })
+
+	return result, nil
+}
@@ -0,0 +1,84 @@
+package sqlite
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+
+	"rssgram/internal/storage"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/sqlite3"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+func (s *Storage) newMigrate() (*migrate.Migrate, error) {
+	instance, err := sqlite3.WithInstance(s.db, &sqlite3.Config{})
+	if err != nil {
+		return nil, fmt.Errorf("failed create sqlite3 instance: %w", err)
+	}
+
+	d, err := iofs.New(migrations, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to init iofs: %w", err)
+	}
+
+	m, err := migrate.NewWithInstance("iofs", d, "sqlite", instance)
+	if err != nil {
+		return nil, fmt.Errorf("failed to init migrate: %w", err)
+	}
+
+	return m, nil
+}
+
+// Migrate применяет все миграции схемы SQLite.
+func (s *Storage) Migrate(ctx context.Context) error {
+	m, err := s.newMigrate()
+	if err != nil {
+		return err
+	}
+
+	err = m.Up()
+	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("failed to migrate: %w", err)
+	}
+	return nil
+}
+
+// MigrateDown откатывает steps последних миграций, steps <= 0 - все.
+func (s *Storage) MigrateDown(ctx context.Context, steps int) error {
+	m, err := s.newMigrate()
+	if err != nil {
+		return err
+	}
+
+	if steps > 0 {
+		err = m.Steps(-steps)
+	} else {
+		err = m.Down()
+	}
+	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("failed to migrate down: %w", err)
+	}
+	return nil
+}
+
+func (s *Storage) MigrationVersion(ctx context.Context) (storage.MigrationVersion, error) {
+	m, err := s.newMigrate()
+	if err != nil {
+		return storage.MigrationVersion{}, err
+	}
+
+	version, dirty, err := m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return storage.MigrationVersion{}, nil
+	}
+	if err != nil {
+		return storage.MigrationVersion{}, fmt.Errorf("failed to get migration version: %w", err)
+	}
+	return storage.MigrationVersion{Version: version, Dirty: dirty}, nil
+}
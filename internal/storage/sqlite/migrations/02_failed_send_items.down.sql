@@ -0,0 +1,2 @@
+ALTER TABLE items DROP COLUMN updated_at;
+ALTER TABLE items DROP COLUMN failed_count;
@@ -0,0 +1,3 @@
+ALTER TABLE items ADD COLUMN feed_url TEXT NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS items_feed_url_published_at_idx ON items (feed_url, published_at);
+CREATE INDEX IF NOT EXISTS items_is_sent_sent_at_idx ON items (is_sent, sent_at);
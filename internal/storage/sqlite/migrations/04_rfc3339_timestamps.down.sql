@@ -0,0 +1,7 @@
+-- 2006-01-02T15:04:05.000000000Z -> 2006-01-02 15:04:05
+UPDATE feeds SET last_checked = substr(last_checked, 1, 10) || ' ' || substr(last_checked, 12, 8) WHERE length(last_checked) > 19;
+UPDATE feeds SET last_post = substr(last_post, 1, 10) || ' ' || substr(last_post, 12, 8) WHERE length(last_post) > 19;
+
+UPDATE items SET published_at = substr(published_at, 1, 10) || ' ' || substr(published_at, 12, 8) WHERE length(published_at) > 19;
+UPDATE items SET sent_at = substr(sent_at, 1, 10) || ' ' || substr(sent_at, 12, 8) WHERE length(sent_at) > 19;
+UPDATE items SET updated_at = substr(updated_at, 1, 10) || ' ' || substr(updated_at, 12, 8) WHERE length(updated_at) > 19;
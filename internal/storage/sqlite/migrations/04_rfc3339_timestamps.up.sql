@@ -0,0 +1,7 @@
+-- 2006-01-02 15:04:05 -> 2006-01-02T15:04:05.000000000Z
+UPDATE feeds SET last_checked = replace(last_checked, ' ', 'T') || '.000000000Z' WHERE length(last_checked) = 19;
+UPDATE feeds SET last_post = replace(last_post, ' ', 'T') || '.000000000Z' WHERE length(last_post) = 19;
+
+UPDATE items SET published_at = replace(published_at, ' ', 'T') || '.000000000Z' WHERE length(published_at) = 19;
+UPDATE items SET sent_at = replace(sent_at, ' ', 'T') || '.000000000Z' WHERE length(sent_at) = 19;
+UPDATE items SET updated_at = replace(updated_at, ' ', 'T') || '.000000000Z' WHERE length(updated_at) = 19;
@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS items_fts_au;
+DROP TRIGGER IF EXISTS items_fts_ad;
+DROP TRIGGER IF EXISTS items_fts_ai;
+DROP TABLE IF EXISTS items_fts;
@@ -0,0 +1,23 @@
+CREATE VIRTUAL TABLE IF NOT EXISTS items_fts USING fts5(
+     title,
+     description,
+     tags,
+     content='items',
+     content_rowid='rowid',
+     tokenize='unicode61 remove_diacritics 2'
+);
+
+CREATE TRIGGER IF NOT EXISTS items_fts_ai AFTER INSERT ON items BEGIN
+     INSERT INTO items_fts(rowid, title, description, tags) VALUES (new.rowid, new.title, new.description, new.tags);
+END;
+
+CREATE TRIGGER IF NOT EXISTS items_fts_ad AFTER DELETE ON items BEGIN
+     INSERT INTO items_fts(items_fts, rowid, title, description, tags) VALUES ('delete', old.rowid, old.title, old.description, old.tags);
+END;
+
+CREATE TRIGGER IF NOT EXISTS items_fts_au AFTER UPDATE OF title, description, tags ON items BEGIN
+     INSERT INTO items_fts(items_fts, rowid, title, description, tags) VALUES ('delete', old.rowid, old.title, old.description, old.tags);
+     INSERT INTO items_fts(rowid, title, description, tags) VALUES (new.rowid, new.title, new.description, new.tags);
+END;
+
+INSERT INTO items_fts(items_fts) VALUES ('rebuild');
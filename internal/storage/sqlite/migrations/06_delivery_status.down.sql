@@ -0,0 +1 @@
+ALTER TABLE items DROP COLUMN delivery_status;
@@ -0,0 +1 @@
+ALTER TABLE feeds ADD COLUMN resolved_url TEXT NOT NULL DEFAULT '';
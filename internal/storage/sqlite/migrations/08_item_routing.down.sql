@@ -0,0 +1,3 @@
+ALTER TABLE items DROP COLUMN sent_outputs;
+ALTER TABLE items DROP COLUMN silent;
+ALTER TABLE items DROP COLUMN outputs;
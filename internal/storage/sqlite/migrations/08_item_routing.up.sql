@@ -0,0 +1,3 @@
+ALTER TABLE items ADD COLUMN outputs TEXT NOT NULL DEFAULT '[]';
+ALTER TABLE items ADD COLUMN silent BOOLEAN;
+ALTER TABLE items ADD COLUMN sent_outputs TEXT NOT NULL DEFAULT '[]';
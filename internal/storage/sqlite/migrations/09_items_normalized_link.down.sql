@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS items_normalized_link_idx;
+ALTER TABLE items DROP COLUMN extra_sources;
+ALTER TABLE items DROP COLUMN normalized_link;
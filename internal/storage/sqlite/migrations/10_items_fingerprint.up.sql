@@ -0,0 +1 @@
+ALTER TABLE items ADD COLUMN fingerprint INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS enrich_cache (
+     key TEXT NOT NULL PRIMARY KEY,
+     data TEXT NOT NULL,
+     fetched_at TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS enrich_cache_fetched_at_idx ON enrich_cache (fetched_at);
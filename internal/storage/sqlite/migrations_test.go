@@ -1,5 +1,5 @@
 // Тест создан с помощью AI
-package main
+package sqlite
 
 import (
 	"database/sql"
@@ -213,3 +213,44 @@ func TestMigrations_EmbeddedFiles(t *testing.T) {
 	}
 	assert.True(t, hasUpFiles, "Should have up migration files")
 }
+
+func TestMigrations_RFC3339Timestamps(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	instance, err := sqlite3.WithInstance(db, &sqlite3.Config{})
+	require.NoError(t, err)
+
+	d, err := iofs.New(migrations, "migrations")
+	require.NoError(t, err)
+
+	m, err := migrate.NewWithInstance("iofs", d, "sqlite", instance)
+	require.NoError(t, err)
+
+	// Схема до перехода на RFC 3339
+	require.NoError(t, m.Migrate(3))
+
+	_, err = db.Exec(`INSERT INTO feeds (url, last_checked, last_post) VALUES ('https://example.com/rss', '2024-05-01 10:00:00', '2024-05-01 09:30:00')`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO items (id, feed_title, title, description, published_at, sent_at) VALUES ('1', 'f', 't', 'd', '2024-05-01 09:00:00', NULL)`)
+	require.NoError(t, err)
+
+	require.NoError(t, m.Migrate(4))
+
+	var lastChecked, lastPost, publishedAt string
+	var sentAt sql.NullString
+	require.NoError(t, db.QueryRow("SELECT last_checked, last_post FROM feeds").Scan(&lastChecked, &lastPost))
+	require.NoError(t, db.QueryRow("SELECT published_at, sent_at FROM items").Scan(&publishedAt, &sentAt))
+
+	assert.Equal(t, "2024-05-01T10:00:00.000000000Z", lastChecked)
+	assert.Equal(t, "2024-05-01T09:30:00.000000000Z", lastPost)
+	assert.Equal(t, "2024-05-01T09:00:00.000000000Z", publishedAt)
+	assert.False(t, sentAt.Valid)
+
+	// Откат возвращает старый формат
+	require.NoError(t, m.Migrate(3))
+	require.NoError(t, db.QueryRow("SELECT published_at FROM items").Scan(&publishedAt))
+	assert.Equal(t, "2024-05-01 09:00:00", publishedAt)
+}
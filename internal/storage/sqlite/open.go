@@ -0,0 +1,62 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"rssgram/internal/storage"
+
+	_ "modernc.org/sqlite"
+)
+
+// buildDSN добавляет к DSN прагмы, если они не заданы явно.
+func buildDSN(conf storage.Config) string {
+	dsn := conf.DSN
+
+	pragmas := []struct {
+		name  string
+		value string
+	}{
+		{"busy_timeout", fmt.Sprintf("%d", conf.BusyTimeout.Milliseconds())},
+		{"journal_mode", "WAL"},
+		{"synchronous", "NORMAL"},
+		{"foreign_keys", "1"},
+	}
+
+	for _, p := range pragmas {
+		if strings.Contains(dsn, "_pragma="+p.name) {
+			continue
+		}
+
+		sep := "&"
+		if !strings.Contains(dsn, "?") {
+			sep = "?"
+		}
+		dsn += fmt.Sprintf("%s_pragma=%s(%s)", sep, p.name, p.value)
+	}
+
+	return dsn
+}
+
+// Open открывает базу данных, общую для миграций и Storage.
+func Open(ctx context.Context, conf storage.Config) (*sql.DB, error) {
+	conf = conf.WithDefaults()
+
+	db, err := sql.Open("sqlite", buildDSN(conf))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SQLite database: %w", err)
+	}
+
+	db.SetMaxOpenConns(conf.MaxOpenConns)
+	db.SetMaxIdleConns(conf.MaxIdleConns)
+	db.SetConnMaxLifetime(conf.ConnMaxLifetime)
+
+	if err = db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to SQLite database %s: %w", conf.DSN, err)
+	}
+
+	return db, nil
+}
@@ -0,0 +1,112 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"rssgram/internal/storage"
+)
+
+func (s *Storage) PruneItems(ctx context.Context, policy storage.PrunePolicy) (storage.PruneResult, error) {
+	var result storage.PruneResult
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return result, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// у старых записей feed_url пустой, их фиды различаются только по feed_title
+	stmt := `
+	DELETE FROM items WHERE id IN (
+		SELECT id FROM (
+			SELECT id, is_sent, sent_at, ROW_NUMBER() OVER (PARTITION BY feed_url, CASE WHEN feed_url = '' THEN feed_title ELSE '' END ORDER BY published_at DESC) AS rn
+			FROM items
+		) WHERE is_sent = 1 AND sent_at < ? AND rn > ?
+	)
+`
+	res, err := tx.ExecContext(ctx, stmt, formatTime(policy.SentBefore), policy.KeepPerFeed)
+	if err != nil {
+		return result, fmt.Errorf("failed to prune expired items: %w", err)
+	}
+	result.ExpiredItems, _ = res.RowsAffected()
+
+	if len(policy.ActiveFeeds) > 0 {
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(policy.ActiveFeeds)), ",")
+		args := make([]any, 0, len(policy.ActiveFeeds))
+		for _, url := range policy.ActiveFeeds {
+			args = append(args, url)
+		}
+
+		// у старых записей feed_url пустой - их удаляет только срок хранения
+		res, err = tx.ExecContext(ctx, "DELETE FROM items WHERE feed_url != '' AND feed_url NOT IN ("+placeholders+")", args...)
+		if err != nil {
+			return result, fmt.Errorf("failed to prune items of removed feeds: %w", err)
+		}
+		result.RemovedFeedItems, _ = res.RowsAffected()
+
+		res, err = tx.ExecContext(ctx, "DELETE FROM feeds WHERE url NOT IN ("+placeholders+")", args...)
+		if err != nil {
+			return result, fmt.Errorf("failed to prune removed feeds: %w", err)
+		}
+		result.RemovedFeeds, _ = res.RowsAffected()
+	}
+
+	if err = tx.Commit(); err != nil {
+		return result, fmt.Errorf("failed to commit prune: %w", err)
+	}
+
+	return result, nil
+}
+
+// Vacuum возвращает освободившиеся страницы файлу базы.
+// При первом запуске база переводится в режим auto_vacuum=INCREMENTAL полным VACUUM,
+// дальше достаточно incremental_vacuum.
+func (s *Storage) Vacuum(ctx context.Context) error {
+	var autoVacuum int
+	err := s.db.QueryRowContext(ctx, "PRAGMA auto_vacuum").Scan(&autoVacuum)
+	if err != nil {
+		return fmt.Errorf("failed to get auto_vacuum mode: %w", err)
+	}
+
+	// 2 - INCREMENTAL
+	if autoVacuum == 2 {
+		_, err = s.db.ExecContext(ctx, "PRAGMA incremental_vacuum")
+		if err != nil {
+			return fmt.Errorf("failed to run incremental vacuum: %w", err)
+		}
+		return nil
+	}
+
+	// PRAGMA auto_vacuum действует на соединение, поэтому обе команды выполняются на одном
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err = conn.ExecContext(ctx, "PRAGMA auto_vacuum = INCREMENTAL"); err != nil {
+		return fmt.Errorf("failed to set auto_vacuum mode: %w", err)
+	}
+	if _, err = conn.ExecContext(ctx, "VACUUM"); err != nil {
+		return fmt.Errorf("failed to vacuum: %w", err)
+	}
+
+	// VACUUM может перенумеровать rowid, на которые ссылается items_fts
+	if _, err = conn.ExecContext(ctx, "INSERT INTO items_fts(items_fts) VALUES ('rebuild')"); err != nil {
+		return fmt.Errorf("failed to rebuild search index: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Storage) Size(ctx context.Context) (int64, error) {
+	var size int64
+	stmt := "SELECT page_count * page_size FROM pragma_page_count(), pragma_page_size()"
+	err := s.db.QueryRowContext(ctx, stmt).Scan(&size)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get database size: %w", err)
+	}
+	return size, nil
+}
@@ -0,0 +1,101 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"rssgram/internal/storage"
+)
+
+// matchExpression превращает пользовательский запрос в выражение FTS5:
+// каждое слово берётся в кавычки (AND между словами), "слово*" - поиск по префиксу.
+func matchExpression(query string) string {
+	var terms []string
+
+	for _, word := range strings.Fields(query) {
+		prefix := strings.HasSuffix(word, "*")
+		word = strings.Trim(word, `*"`)
+		if word == "" {
+			continue
+		}
+
+		term := `"` + strings.ReplaceAll(word, `"`, `""`) + `"`
+		if prefix {
+			term += "*"
+		}
+		terms = append(terms, term)
+	}
+
+	return strings.Join(terms, " ")
+}
+
+func (s *Storage) Search(ctx context.Context, query storage.SearchQuery) ([]storage.SearchResult, error) {
+	query = query.WithDefaults()
+
+	match := matchExpression(query.Query)
+	if match == "" {
+		return nil, fmt.Errorf("search query is empty")
+	}
+
+	// веса bm25: title, description, tags
+	stmt := `
+	SELECT i.id, i.feed_url, i.feed_title, i.title, i.link, i.published_at, i.is_sent,
+		-bm25(items_fts, 10.0, 1.0, 5.0),
+		snippet(items_fts, 0, ?, ?, '…', 16),
+		snippet(items_fts, 1, ?, ?, '…', 32)
+	FROM items_fts
+	JOIN items i ON i.rowid = items_fts.rowid
+	WHERE items_fts MATCH ?`
+
+	args := []any{storage.SearchMarkStart, storage.SearchMarkEnd, storage.SearchMarkStart, storage.SearchMarkEnd, match}
+
+	if query.Feed != "" {
+		stmt += " AND (i.feed_url = ? OR i.feed_title = ?)"
+		args = append(args, query.Feed, query.Feed)
+	}
+	if !query.From.IsZero() {
+		stmt += " AND i.published_at >= ?"
+		args = append(args, formatTime(query.From))
+	}
+	if !query.To.IsZero() {
+		stmt += " AND i.published_at < ?"
+		args = append(args, formatTime(query.To))
+	}
+	if query.IsSent != nil {
+		stmt += " AND i.is_sent = ?"
+		args = append(args, *query.IsSent)
+	}
+
+	stmt += " ORDER BY bm25(items_fts, 10.0, 1.0, 5.0) LIMIT ?"
+	args = append(args, query.Limit)
+
+	rows, err := s.db.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search items: %w", err)
+	}
+	defer rows.Close()
+
+	var results []storage.SearchResult
+	for rows.Next() {
+		var r storage.SearchResult
+		var publishedAt string
+
+		err = rows.Scan(&r.ItemID, &r.FeedURL, &r.FeedTitle, &r.Title, &r.Link, &publishedAt, &r.IsSent, &r.Score, &r.TitleSnippet, &r.DescriptionSnippet)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch search results: %w", err)
+		}
+
+		r.TitleSnippet = query.Highlight(r.TitleSnippet)
+		r.DescriptionSnippet = query.Highlight(r.DescriptionSnippet)
+
+		r.PublishedAt, err = parseTime(publishedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert published_at (%s): %w", r.ItemID, err)
+		}
+
+		results = append(results, r)
+	}
+
+	return results, rows.Err()
+}
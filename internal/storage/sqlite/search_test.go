@@ -0,0 +1,27 @@
+package sqlite
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchExpression(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		expected string
+	}{
+		{name: "empty", query: "  ", expected: ""},
+		{name: "words", query: "linux kernel", expected: `"linux" "kernel"`},
+		{name: "prefix", query: "компил*", expected: `"компил"*`},
+		{name: "fts syntax is quoted", query: `title:go OR "rust`, expected: `"title:go" "OR" "rust"`},
+		{name: "quotes inside word", query: `a"b`, expected: `"a""b"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, matchExpression(tt.query))
+		})
+	}
+}
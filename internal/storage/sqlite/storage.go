@@ -9,8 +9,6 @@ import (
 
 	"rssgram/internal/feed"
 	"rssgram/internal/storage"
-
-	_ "modernc.org/sqlite"
 )
 
 type Storage struct {
@@ -19,38 +17,47 @@ type Storage struct {
 
 func (s *Storage) DeleteFeed(ctx context.Context, url string) error {
 	stmt := "DELETE FROM feeds WHERE url=?"
-	_, err := s.db.Exec(stmt, url)
+	_, err := s.db.ExecContext(ctx, stmt, url)
 	return err
 }
 
 func (s *Storage) UpsertFeed(ctx context.Context, url string, lastChecked, lastPost time.Time) error {
 	stmt := "INSERT INTO feeds (url, last_checked, last_post) VALUES (?, ?, ?) ON CONFLICT(url) DO UPDATE SET last_checked=excluded.last_checked, last_post=excluded.last_post"
-	_, err := s.db.Exec(stmt, url, lastChecked.UTC().Format(time.DateTime), lastPost.UTC().Format(time.DateTime))
+	_, err := s.db.ExecContext(ctx, stmt, url, formatTime(lastChecked), formatTime(lastPost))
 	return err
 }
 
+// SetFeedResolvedURL запоминает адрес фида, найденный на странице из конфига.
+func (s *Storage) SetFeedResolvedURL(ctx context.Context, url, resolvedURL string) error {
+	_, err := s.db.ExecContext(ctx, "UPDATE feeds SET resolved_url=? WHERE url=?", resolvedURL, url)
+	if err != nil {
+		return fmt.Errorf("failed to set resolved url: %w", err)
+	}
+	return nil
+}
+
 func (s *Storage) GetFeedByURL(ctx context.Context, url string) (*storage.StoredFeed, error) {
-	stmt := "SELECT last_checked, last_post FROM feeds WHERE url=?"
-	rows, err := s.db.Query(stmt, url)
+	stmt := "SELECT last_checked, last_post, resolved_url FROM feeds WHERE url=?"
+	rows, err := s.db.QueryContext(ctx, stmt, url)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch feed: %w", err)
 	}
 	defer rows.Close()
 
 	for rows.Next() {
-		var lastChecked, lastPosted string
+		var lastChecked, lastPosted, resolvedURL string
 
-		err = rows.Scan(&lastChecked, &lastPosted)
+		err = rows.Scan(&lastChecked, &lastPosted, &resolvedURL)
 		if err != nil {
 			return nil, fmt.Errorf("failed to fetch all feeds: %w", err)
 		}
 
-		parsedLastChecked, err := time.Parse(time.DateTime, lastChecked)
+		parsedLastChecked, err := parseTime(lastChecked)
 		if err != nil {
 			return nil, fmt.Errorf("failed to convert last_cheked (%s): %w", url, err)
 		}
 
-		parsedLastPosted, err := time.Parse(time.DateTime, lastPosted)
+		parsedLastPosted, err := parseTime(lastPosted)
 		if err != nil {
 			return nil, fmt.Errorf("failed to convert last_posted (%s): %w", url, err)
 		}
@@ -59,6 +66,7 @@ func (s *Storage) GetFeedByURL(ctx context.Context, url string) (*storage.Stored
 			URL:         url,
 			LastChecked: parsedLastChecked,
 			LastPosted:  parsedLastPosted,
+			ResolvedURL: resolvedURL,
 		}
 
 		return &_feed, nil
@@ -78,13 +86,21 @@ func (s *Storage) InsertItem(ctx context.Context, item *feed.FeedItem) error {
 		return fmt.Errorf("failed to marshal item tags: %w", err)
 	}
 
+	itemOutputsJSON, err := item.GetOutputsJson()
+	if err != nil {
+		return fmt.Errorf("failed to marshal item outputs: %w", err)
+	}
+
+	now := time.Now()
+
 	stmt := `
-	INSERT INTO items (id, feed_title, title, link, description, image_url, tags, metadata, published_at, updated_at)
-	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	INSERT INTO items (id, feed_url, feed_title, title, link, description, image_url, tags, metadata, published_at, updated_at, outputs, silent, normalized_link, fingerprint)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	ON CONFLICT(id) DO NOTHING
 `
-	_, err = s.db.Exec(stmt,
+	_, err = s.db.ExecContext(ctx, stmt,
 		item.ID,
+		item.FeedURL,
 		item.FeedTitle,
 		item.Title,
 		item.Link,
@@ -92,8 +108,13 @@ func (s *Storage) InsertItem(ctx context.Context, item *feed.FeedItem) error {
 		item.ImageURL,
 		itemTagsJSON,
 		itemMetaJSON,
-		item.PublishedAt.Format(time.DateTime),
-		time.Now().UTC().Format(time.DateTime),
+		formatTime(item.GetPublishedAt(now)),
+		formatTime(now),
+		itemOutputsJSON,
+		item.Silent,
+		item.NormalizedLink,
+		// SimHash хранится как знаковое число того же размера
+		int64(item.Fingerprint),
 	)
 	if err != nil {
 		return fmt.Errorf("failed to insert item: %w", err)
@@ -102,13 +123,13 @@ func (s *Storage) InsertItem(ctx context.Context, item *feed.FeedItem) error {
 }
 
 func (s *Storage) GetItemsReadyToSend(ctx context.Context, limit int) ([]feed.FeedItem, error) {
-	stmt := "SELECT id, title, feed_title, title, link, image_url, description, published_at, tags, metadata  FROM items where is_sent = 0 order by published_at"
+	stmt := "SELECT id, feed_url, feed_title, title, link, image_url, description, published_at, tags, metadata, outputs, silent, sent_outputs, extra_sources FROM items where is_sent = 0 order by published_at"
 
 	if limit > 0 {
 		stmt += fmt.Sprintf(" LIMIT %d", limit)
 	}
 
-	rows, err := s.db.Query(stmt)
+	rows, err := s.db.QueryContext(ctx, stmt)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch ready items: %w", err)
 	}
@@ -117,16 +138,17 @@ func (s *Storage) GetItemsReadyToSend(ctx context.Context, limit int) ([]feed.Fe
 	for rows.Next() {
 		item := feed.FeedItem{}
 
-		var publishedAt, tmpTags, tmpMeta string
+		var publishedAt, tmpTags, tmpMeta, tmpOutputs, tmpSentOutputs, tmpExtraSources string
+		var silent sql.NullBool
 
-		err = rows.Scan(&item.ID, &item.Title, &item.FeedTitle, &item.Title, &item.Link, &item.ImageURL, &item.Description, &publishedAt, &tmpTags, &tmpMeta)
+		err = rows.Scan(&item.ID, &item.FeedURL, &item.FeedTitle, &item.Title, &item.Link, &item.ImageURL, &item.Description, &publishedAt, &tmpTags, &tmpMeta, &tmpOutputs, &silent, &tmpSentOutputs, &tmpExtraSources)
 		if err != nil {
 			return nil, fmt.Errorf("failed to fetch all feeds: %w", err)
 		}
 
-		parsedPublishedAt, err := time.Parse(time.DateTime, publishedAt)
+		parsedPublishedAt, err := parseTime(publishedAt)
 		if err != nil {
-			return nil, fmt.Errorf("failed to convert published_at (%s): %w", item, err)
+			return nil, fmt.Errorf("failed to convert published_at (%s): %w", item.ID, err)
 		}
 		item.PublishedAt = &parsedPublishedAt
 
@@ -135,17 +157,53 @@ func (s *Storage) GetItemsReadyToSend(ctx context.Context, limit int) ([]feed.Fe
 			return nil, fmt.Errorf("failed to unmarshal tags: %w", err)
 		}
 
+		if err = unmarshalMetadata(&item, []byte(tmpMeta)); err != nil {
+			return nil, err
+		}
+
+		if err = unmarshalRouting(&item, []byte(tmpOutputs), silent, []byte(tmpSentOutputs)); err != nil {
+			return nil, err
+		}
+
+		err = json.Unmarshal([]byte(tmpExtraSources), &item.ExtraSources)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal extra sources: %w", err)
+		}
+
 		items = append(items, item)
 	}
 
 	return items, nil
 }
 
+// CountSentItems считает по фидам новости, отправленные после sentAfter.
+func (s *Storage) CountSentItems(ctx context.Context, sentAfter time.Time) (map[string]int, error) {
+	stmt := `SELECT feed_url, COUNT(*) FROM items WHERE is_sent = 1 AND sent_at >= ? GROUP BY feed_url`
+
+	rows, err := s.db.QueryContext(ctx, stmt, formatTime(sentAfter))
+	if err != nil {
+		return nil, fmt.Errorf("failed to count sent items: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var feedURL string
+		var count int
+		if err = rows.Scan(&feedURL, &count); err != nil {
+			return nil, fmt.Errorf("failed to fetch sent items count: %w", err)
+		}
+		counts[feedURL] = count
+	}
+
+	return counts, rows.Err()
+}
+
 func (s *Storage) GetCountItemsSendFailed(ctx context.Context) (int, error) {
 	count := 0
 
 	stmt := "SELECT count(id) FROM items where is_sent = 0 and failed_count > 0"
-	rows, err := s.db.Query(stmt)
+	rows, err := s.db.QueryContext(ctx, stmt)
 	if err != nil {
 		return 0, fmt.Errorf("failed to fetch count items: %w", err)
 	}
@@ -169,7 +227,7 @@ func (s *Storage) GetCountItemsReadyToSend(ctx context.Context) (int, error) {
 	count := 0
 
 	stmt := "SELECT count(id) FROM items where is_sent = 0"
-	rows, err := s.db.Query(stmt)
+	rows, err := s.db.QueryContext(ctx, stmt)
 	if err != nil {
 		return count, fmt.Errorf("failed to fetch count ready items: %w", err)
 	}
@@ -189,30 +247,75 @@ func (s *Storage) GetCountItemsReadyToSend(ctx context.Context) (int, error) {
 	return count, nil
 }
 
-func (s *Storage) SetItemIsSent(ctx context.Context, itemID string) error {
-	stmt := `UPDATE items SET is_sent = 1, sent_at = ?, updated_at = ? WHERE id=?`
-	nowStr := time.Now().UTC().Format(time.DateTime)
-	_, err := s.db.Exec(stmt, nowStr, nowStr, itemID)
+func (s *Storage) SetItemIsSent(ctx context.Context, itemID string, status storage.DeliveryStatus) error {
+	stmt := `UPDATE items SET is_sent = 1, delivery_status = ?, sent_at = ?, updated_at = ? WHERE id=?`
+	nowStr := formatTime(time.Now())
+	_, err := s.db.ExecContext(ctx, stmt, status, nowStr, nowStr, itemID)
 	if err != nil {
 		return fmt.Errorf("failed to update item: %w", err)
 	}
 	return nil
 }
 
+// SetItemOutputSent отмечает отправку новости в один из её выходов, чтобы при повторной
+// попытке после ошибки в другом выходе сообщение не ушло туда ещё раз.
+func (s *Storage) SetItemOutputSent(ctx context.Context, itemID, output string) error {
+	stmt := `UPDATE items SET sent_outputs = json_insert(sent_outputs, '$[#]', ?), updated_at = ? WHERE id=?`
+	_, err := s.db.ExecContext(ctx, stmt, output, formatTime(time.Now()), itemID)
+	if err != nil {
+		return fmt.Errorf("failed to update item sent outputs: %w", err)
+	}
+	return nil
+}
+
 func (s *Storage) IncrementItemFailedCounter(ctx context.Context, itemID string) error {
 	stmt := `UPDATE items SET failed_count = failed_count + 1, updated_at = ? where id=?`
-	_, err := s.db.Exec(stmt, time.Now().UTC().Format(time.DateTime), itemID)
+	_, err := s.db.ExecContext(ctx, stmt, formatTime(time.Now()), itemID)
 	if err != nil {
 		return fmt.Errorf("failed to update item failed counter: %w", err)
 	}
 	return nil
 }
 
-func NewStorage() (*Storage, error) {
-	db, err := sql.Open("sqlite", "file:data.db?cache=shared")
+func (s *Storage) Close() error {
+	return s.db.Close()
+}
+
+func NewStorage(db *sql.DB) *Storage {
+	return &Storage{db: db}
+}
+
+// New открывает базу данных SQLite и создаёт поверх неё Storage.
+func New(ctx context.Context, conf storage.Config) (*Storage, error) {
+	db, err := Open(ctx, conf)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open SQLite storage: %w", err)
+		return nil, err
+	}
+
+	return NewStorage(db), nil
+}
+
+// unmarshalRouting заполняет выходы новости и тихий режим, выбранные правилами маршрутизации.
+func unmarshalRouting(item *feed.FeedItem, outputs []byte, silent sql.NullBool, sentOutputs []byte) error {
+	if err := json.Unmarshal(outputs, &item.Outputs); err != nil {
+		return fmt.Errorf("failed to unmarshal outputs: %w", err)
+	}
+	if err := json.Unmarshal(sentOutputs, &item.SentOutputs); err != nil {
+		return fmt.Errorf("failed to unmarshal sent outputs: %w", err)
 	}
+	if silent.Valid {
+		item.Silent = &silent.Bool
+	}
+	return nil
+}
 
-	return &Storage{db: db}, nil
+// unmarshalMetadata заполняет метаданные новости; пустой объект оставляет Metadata nil, как у новой новости.
+func unmarshalMetadata(item *feed.FeedItem, metadata []byte) error {
+	if err := json.Unmarshal(metadata, &item.Metadata); err != nil {
+		return fmt.Errorf("failed to unmarshal metadata: %w", err)
+	}
+	if len(item.Metadata) == 0 {
+		item.Metadata = nil
+	}
+	return nil
 }
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"rssgram/internal/feed"
+	"rssgram/internal/storage"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -34,37 +35,10 @@ func TestMain(m *testing.M) {
 }
 
 func runTestMigrations(db *sql.DB) error {
-	queries := []string{
-		`CREATE TABLE IF NOT EXISTS feeds (
-			url TEXT NOT NULL PRIMARY KEY,
-			last_checked TEXT NOT NULL,
-			last_post TEXT NOT NULL
-		)`,
-		`CREATE TABLE IF NOT EXISTS items (
-			id TEXT NOT NULL PRIMARY KEY,
-			feed_title TEXT NOT NULL,
-			title TEXT NOT NULL,
-			link TEXT NOT NULL DEFAULT '',
-			description TEXT NOT NULL,
-			image_url TEXT,
-			tags TEXT NOT NULL DEFAULT '',
-			metadata TEXT NOT NULL DEFAULT '{}',
-			published_at TEXT NOT NULL,
-			is_sent BOOLEAN NOT NULL DEFAULT '0',
-			sent_at TEXT,
-			failed_count INT NOT NULL DEFAULT 0,
-			updated_at TEXT
-		)`,
-	}
-
-	for _, query := range queries {
-		_, err := db.Exec(query)
-		if err != nil {
-			return err
-		}
-	}
+	// у каждого соединения своя база :memory:
+	db.SetMaxOpenConns(1)
 
-	return nil
+	return NewStorage(db).Migrate(context.Background())
 }
 
 func TestNewStorage(t *testing.T) {
@@ -75,10 +49,48 @@ func TestNewStorage(t *testing.T) {
 	tmpFile.Close()
 
 	// Test creating storage
-	storage, err := NewStorage()
+	db, err := Open(context.Background(), storage.Config{DSN: "file:" + tmpFile.Name()})
+	require.NoError(t, err)
+	defer db.Close()
+
+	s := NewStorage(db)
 	// Expect successful storage creation
-	assert.NoError(t, err)
-	assert.NotNil(t, storage)
+	assert.NotNil(t, s)
+}
+
+func TestOpen_Pragmas(t *testing.T) {
+	tmpFile, err := os.CreateTemp("", "test_*.db")
+	require.NoError(t, err)
+	defer os.Remove(tmpFile.Name())
+	tmpFile.Close()
+
+	db, err := Open(context.Background(), storage.Config{DSN: "file:" + tmpFile.Name(), MaxOpenConns: 2})
+	require.NoError(t, err)
+	defer db.Close()
+
+	var journalMode string
+	require.NoError(t, db.QueryRow("PRAGMA journal_mode").Scan(&journalMode))
+	assert.Equal(t, "wal", journalMode)
+
+	var busyTimeout int
+	require.NoError(t, db.QueryRow("PRAGMA busy_timeout").Scan(&busyTimeout))
+	assert.Equal(t, 5000, busyTimeout)
+
+	var foreignKeys int
+	require.NoError(t, db.QueryRow("PRAGMA foreign_keys").Scan(&foreignKeys))
+	assert.Equal(t, 1, foreignKeys)
+
+	assert.Equal(t, 2, db.Stats().MaxOpenConnections)
+}
+
+func TestBuildDSN(t *testing.T) {
+	conf := storage.Config{DSN: "file:data.db?_pragma=journal_mode(DELETE)"}.WithDefaults()
+	dsn := buildDSN(conf)
+
+	assert.Contains(t, dsn, "_pragma=journal_mode(DELETE)")
+	assert.NotContains(t, dsn, "journal_mode(WAL)")
+	assert.Contains(t, dsn, "&_pragma=busy_timeout(5000)")
+	assert.Contains(t, dsn, "&_pragma=foreign_keys(1)")
 }
 
 func TestStorage_FeedsOperations(t *testing.T) {
@@ -190,7 +202,7 @@ func TestStorage_ItemsOperations(t *testing.T) {
 		assert.Len(t, items, 2) // Previous + new
 
 		// Mark item as sent
-		err = storage.SetItemIsSent(ctx, itemID)
+		err = storage.SetItemIsSent(ctx, itemID, "sent")
 		assert.NoError(t, err)
 
 		// Check if item is no longer ready to send
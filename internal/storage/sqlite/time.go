@@ -0,0 +1,21 @@
+package sqlite
+
+import (
+	"time"
+)
+
+// timeLayout - RFC 3339 в UTC с наносекундами фиксированной длины,
+// чтобы строковое сравнение в SQL совпадало с порядком времени.
+const timeLayout = "2006-01-02T15:04:05.000000000Z"
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timeLayout)
+}
+
+func parseTime(s string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.UTC(), nil
+}
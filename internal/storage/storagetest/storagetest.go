@@ -0,0 +1,688 @@
+// Package storagetest содержит общий набор тестов для всех реализаций backend.Storage.
+package storagetest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"rssgram/internal/feed"
+	"rssgram/internal/storage"
+	"rssgram/internal/storage/backend"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Factory возвращает пустое хранилище с применёнными миграциями.
+type Factory func(t *testing.T) backend.Storage
+
+func Run(t *testing.T, newStorage Factory) {
+	t.Run("Feeds", func(t *testing.T) { testFeeds(t, newStorage(t)) })
+	t.Run("Items", func(t *testing.T) { testItems(t, newStorage(t)) })
+	t.Run("DeliveryState", func(t *testing.T) { testDeliveryState(t, newStorage(t)) })
+	t.Run("Routing", func(t *testing.T) { testRouting(t, newStorage(t)) })
+	t.Run("Duplicates", func(t *testing.T) { testDuplicates(t, newStorage(t)) })
+	t.Run("Fingerprints", func(t *testing.T) { testFingerprints(t, newStorage(t)) })
+	t.Run("Digests", func(t *testing.T) { testDigests(t, newStorage(t)) })
+	t.Run("SentCounts", func(t *testing.T) { testSentCounts(t, newStorage(t)) })
+	t.Run("EnrichCache", func(t *testing.T) { testEnrichCache(t, newStorage(t)) })
+	t.Run("Retention", func(t *testing.T) { testRetention(t, newStorage(t)) })
+	t.Run("Timestamps", func(t *testing.T) { testTimestamps(t, newStorage(t)) })
+	t.Run("Search", func(t *testing.T) { testSearch(t, newStorage(t)) })
+	t.Run("ItemsAdmin", func(t *testing.T) { testItemsAdmin(t, newStorage(t)) })
+	t.Run("Migrations", func(t *testing.T) { testMigrations(t, newStorage(t)) })
+}
+
+func newItem(id string, publishedAt time.Time) *feed.FeedItem {
+	return &feed.FeedItem{
+		ID:          id,
+		FeedURL:     "https://example.com/rss",
+		FeedTitle:   "Test Feed",
+		Title:       "Title " + id,
+		Link:        "https://example.com/" + id,
+		Description: "Description " + id,
+		PublishedAt: &publishedAt,
+		Tags:        []string{"test"},
+	}
+}
+
+func testFeeds(t *testing.T, s backend.Storage) {
+	ctx := context.Background()
+	url := "https://example.com/rss"
+
+	stored, err := s.GetFeedByURL(ctx, url)
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+
+	lastChecked := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	lastPost := time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC)
+	require.NoError(t, s.UpsertFeed(ctx, url, lastChecked, lastPost))
+
+	stored, err = s.GetFeedByURL(ctx, url)
+	require.NoError(t, err)
+	require.NotNil(t, stored)
+	assert.Equal(t, url, stored.URL)
+	assert.True(t, lastChecked.Equal(stored.LastChecked))
+	assert.True(t, lastPost.Equal(stored.LastPosted))
+
+	// повторный upsert обновляет время
+	lastPost = lastPost.Add(time.Hour)
+	require.NoError(t, s.UpsertFeed(ctx, url, lastChecked, lastPost))
+	stored, err = s.GetFeedByURL(ctx, url)
+	require.NoError(t, err)
+	assert.True(t, lastPost.Equal(stored.LastPosted))
+	assert.Empty(t, stored.ResolvedURL)
+
+	// найденный адрес фида сохраняется и не сбрасывается следующим upsert
+	require.NoError(t, s.SetFeedResolvedURL(ctx, url, "https://example.com/feed.xml"))
+	require.NoError(t, s.UpsertFeed(ctx, url, lastChecked, lastPost))
+	stored, err = s.GetFeedByURL(ctx, url)
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/feed.xml", stored.ResolvedURL)
+
+	stats, err := s.ListFeedStats(ctx)
+	require.NoError(t, err)
+	require.Len(t, stats, 1)
+	assert.Equal(t, "https://example.com/feed.xml", stats[0].ResolvedURL)
+
+	require.NoError(t, s.DeleteFeed(ctx, url))
+	stored, err = s.GetFeedByURL(ctx, url)
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+}
+
+func testItems(t *testing.T, s backend.Storage) {
+	ctx := context.Background()
+	base := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	for i := 3; i > 0; i-- {
+		item := newItem(fmt.Sprintf("item-%d", i), base.Add(time.Duration(i)*time.Minute))
+		if i == 1 {
+			item.Metadata = map[string]interface{}{"author": "Jane Doe", "tags": []string{"go"}}
+		}
+		require.NoError(t, s.InsertItem(ctx, item))
+	}
+
+	// дубликат игнорируется
+	require.NoError(t, s.InsertItem(ctx, newItem("item-1", base)))
+
+	items, err := s.GetItemsReadyToSend(ctx, 0)
+	require.NoError(t, err)
+	require.Len(t, items, 3)
+	assert.Equal(t, "item-1", items[0].ID)
+	assert.Equal(t, "item-3", items[2].ID)
+	assert.Equal(t, "Title item-1", items[0].Title)
+	assert.Equal(t, "Test Feed", items[0].FeedTitle)
+	assert.Equal(t, []string{"test"}, items[0].Tags)
+	assert.True(t, base.Add(time.Minute).Equal(*items[0].PublishedAt))
+	// метаданные возвращаются после JSON: списки - []any
+	assert.Equal(t, map[string]interface{}{"author": "Jane Doe", "tags": []interface{}{"go"}}, items[0].Metadata)
+	assert.Nil(t, items[1].Metadata)
+
+	items, err = s.GetItemsReadyToSend(ctx, 2)
+	require.NoError(t, err)
+	assert.Len(t, items, 2)
+
+	count, err := s.GetCountItemsReadyToSend(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+}
+
+func testDeliveryState(t *testing.T, s backend.Storage) {
+	ctx := context.Background()
+	base := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	require.NoError(t, s.InsertItem(ctx, newItem("sent", base)))
+	require.NoError(t, s.InsertItem(ctx, newItem("failed", base)))
+
+	require.NoError(t, s.SetItemIsSent(ctx, "sent", storage.DeliveryStatusSent))
+	require.NoError(t, s.IncrementItemFailedCounter(ctx, "failed"))
+	require.NoError(t, s.IncrementItemFailedCounter(ctx, "failed"))
+
+	items, err := s.GetItemsReadyToSend(ctx, 0)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, "failed", items[0].ID)
+
+	failed, err := s.GetCountItemsSendFailed(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, failed)
+}
+
+func testRouting(t *testing.T, s backend.Storage) {
+	ctx := context.Background()
+	base := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	silent := true
+	routed := newItem("routed", base)
+	routed.Outputs = []string{"default", "security"}
+	routed.Silent = &silent
+
+	require.NoError(t, s.InsertItem(ctx, newItem("plain", base)))
+	require.NoError(t, s.InsertItem(ctx, routed))
+	require.NoError(t, s.SetItemOutputSent(ctx, "routed", "default"))
+
+	items, err := s.GetItemsReadyToSend(ctx, 0)
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+
+	byID := map[string]feed.FeedItem{items[0].ID: items[0], items[1].ID: items[1]}
+	assert.Empty(t, byID["plain"].Outputs)
+	assert.Nil(t, byID["plain"].Silent)
+	assert.Empty(t, byID["plain"].SentOutputs)
+
+	assert.Equal(t, []string{"default", "security"}, byID["routed"].Outputs)
+	require.NotNil(t, byID["routed"].Silent)
+	assert.True(t, *byID["routed"].Silent)
+	assert.Equal(t, []string{"default"}, byID["routed"].SentOutputs)
+
+	// повторная отправка начинается заново во все выходы
+	require.NoError(t, s.SetItemIsSent(ctx, "routed", storage.DeliveryStatusSent))
+	_, err = s.RequeueItems(ctx, storage.ItemFilter{IDs: []string{"routed"}})
+	require.NoError(t, err)
+
+	items, err = s.GetItemsReadyToSend(ctx, 0)
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	for _, item := range items {
+		assert.Empty(t, item.SentOutputs, item.ID)
+	}
+}
+
+func testDuplicates(t *testing.T, s backend.Storage) {
+	ctx := context.Background()
+	base := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	pending := newItem("pending", base)
+	pending.NormalizedLink = "example.com/pending"
+	sent := newItem("sent", base)
+	sent.NormalizedLink = "example.com/sent"
+
+	require.NoError(t, s.InsertItem(ctx, pending))
+	require.NoError(t, s.InsertItem(ctx, sent))
+	require.NoError(t, s.SetItemIsSent(ctx, "sent", storage.DeliveryStatusSent))
+
+	dup, err := s.FindDuplicateItem(ctx, "example.com/pending", time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	require.NotNil(t, dup)
+	assert.Equal(t, "pending", dup.ID)
+	assert.Equal(t, storage.ItemStatusPending, dup.Status)
+
+	dup, err = s.FindDuplicateItem(ctx, "example.com/sent", time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	require.NotNil(t, dup)
+	assert.Equal(t, "sent", dup.ID)
+
+	// отправлена раньше окна
+	dup, err = s.FindDuplicateItem(ctx, "example.com/sent", time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Nil(t, dup)
+
+	dup, err = s.FindDuplicateItem(ctx, "example.com/other", time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Nil(t, dup)
+
+	// источник дописывается один раз
+	require.NoError(t, s.AddItemSource(ctx, "pending", "Aggregator"))
+	require.NoError(t, s.AddItemSource(ctx, "pending", "Aggregator"))
+	require.NoError(t, s.AddItemSource(ctx, "pending", "Mirror"))
+
+	items, err := s.GetItemsReadyToSend(ctx, 0)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, []string{"Aggregator", "Mirror"}, items[0].ExtraSources)
+}
+
+func testFingerprints(t *testing.T, s backend.Storage) {
+	ctx := context.Background()
+	base := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	pending := newItem("pending", base)
+	// старший бит проверяет хранение в знаковой колонке
+	pending.Fingerprint = 1<<63 | 42
+	sent := newItem("sent", base)
+	sent.Fingerprint = 7
+	empty := newItem("empty", base)
+
+	require.NoError(t, s.InsertItem(ctx, pending))
+	require.NoError(t, s.InsertItem(ctx, sent))
+	require.NoError(t, s.InsertItem(ctx, empty))
+	require.NoError(t, s.SetItemIsSent(ctx, "sent", storage.DeliveryStatusSent))
+
+	fingerprints, err := s.ListFingerprints(ctx, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []storage.ItemFingerprint{
+		{ItemID: "pending", FeedURL: pending.FeedURL, Fingerprint: 1<<63 | 42, Status: storage.ItemStatusPending},
+		{ItemID: "sent", FeedURL: sent.FeedURL, Fingerprint: 7, Status: storage.ItemStatusSent},
+	}, fingerprints)
+
+	// отправлена раньше окна
+	fingerprints, err = s.ListFingerprints(ctx, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, fingerprints, 1)
+	assert.Equal(t, "pending", fingerprints[0].ItemID)
+}
+
+func testDigests(t *testing.T, s backend.Storage) {
+	ctx := context.Background()
+	base := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	sentAt, err := s.GetDigestSentAt(ctx, "feed:default:https://example.com/rss")
+	require.NoError(t, err)
+	assert.True(t, sentAt.IsZero())
+
+	// начало расписания - без новостей
+	require.NoError(t, s.SetDigestSent(ctx, storage.SentDigest{Key: "feed:default:https://example.com/rss", SentAt: base}))
+	sentAt, err = s.GetDigestSentAt(ctx, "feed:default:https://example.com/rss")
+	require.NoError(t, err)
+	assert.True(t, base.Equal(sentAt), sentAt)
+
+	multi := newItem("multi", base)
+	multi.Outputs = []string{"default", "security"}
+	require.NoError(t, s.InsertItem(ctx, newItem("first", base)))
+	require.NoError(t, s.InsertItem(ctx, newItem("second", base)))
+	require.NoError(t, s.InsertItem(ctx, multi))
+	require.NoError(t, s.InsertItem(ctx, newItem("later", base)))
+
+	next := base.Add(time.Hour)
+	require.NoError(t, s.SetDigestSent(ctx, storage.SentDigest{
+		Key:            "feed:default:https://example.com/rss",
+		SentAt:         next,
+		Status:         storage.DeliveryStatusSent,
+		ItemIDs:        []string{"first", "second"},
+		Output:         "default",
+		PartialItemIDs: []string{"multi"},
+	}))
+
+	sentAt, err = s.GetDigestSentAt(ctx, "feed:default:https://example.com/rss")
+	require.NoError(t, err)
+	assert.True(t, next.Equal(sentAt), sentAt)
+
+	items, err := s.GetItemsReadyToSend(ctx, 0)
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	byID := map[string]feed.FeedItem{items[0].ID: items[0], items[1].ID: items[1]}
+	assert.Equal(t, []string{"default"}, byID["multi"].SentOutputs)
+	assert.Empty(t, byID["later"].SentOutputs)
+
+	stored, err := s.ListItems(ctx, storage.ItemFilter{IDs: []string{"first", "second"}})
+	require.NoError(t, err)
+	require.Len(t, stored, 2)
+	for _, item := range stored {
+		assert.Equal(t, storage.ItemStatusSent, item.Status, item.ID)
+		require.NotNil(t, item.SentAt, item.ID)
+		assert.True(t, next.Equal(*item.SentAt), item.ID)
+	}
+}
+
+func testSentCounts(t *testing.T, s backend.Storage) {
+	ctx := context.Background()
+	base := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	other := newItem("other", base)
+	other.FeedURL = "https://other.example.com/rss"
+	for _, item := range []*feed.FeedItem{newItem("first", base), newItem("second", base), newItem("pending", base), other} {
+		require.NoError(t, s.InsertItem(ctx, item))
+	}
+	require.NoError(t, s.SetItemIsSent(ctx, "first", storage.DeliveryStatusSent))
+	require.NoError(t, s.SetItemIsSent(ctx, "second", storage.DeliveryStatusDryRun))
+	require.NoError(t, s.SetItemIsSent(ctx, "other", storage.DeliveryStatusSent))
+
+	counts, err := s.CountSentItems(ctx, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"https://example.com/rss": 2, "https://other.example.com/rss": 1}, counts)
+
+	counts, err = s.CountSentItems(ctx, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, counts)
+}
+
+func testEnrichCache(t *testing.T, s backend.Storage) {
+	ctx := context.Background()
+	base := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	key := "link:https://example.com/post"
+
+	cached, err := s.GetEnrichCache(ctx, key, base.Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Nil(t, cached)
+
+	description := feed.SiteDescription{
+		Title:       "Post",
+		Description: "Teaser",
+		Canonical:   "https://example.com/post",
+		Page:        feed.PageMetadata{Author: "Jane Doe", Tags: []string{"go"}},
+	}
+	require.NoError(t, s.SetEnrichCache(ctx, key, description, base))
+
+	cached, err = s.GetEnrichCache(ctx, key, base.Add(-time.Hour))
+	require.NoError(t, err)
+	require.NotNil(t, cached)
+	assert.Equal(t, description, *cached)
+
+	// устаревшее обогащение не возвращается
+	cached, err = s.GetEnrichCache(ctx, key, base.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Nil(t, cached)
+
+	// повторное сохранение заменяет данные и время
+	description.Text = "Full text"
+	require.NoError(t, s.SetEnrichCache(ctx, key, description, base.Add(time.Hour)))
+	require.NoError(t, s.SetEnrichCache(ctx, "link:https://example.com/old", feed.SiteDescription{Title: "Old"}, base.Add(-time.Hour)))
+
+	cached, err = s.GetEnrichCache(ctx, key, base.Add(time.Minute))
+	require.NoError(t, err)
+	require.NotNil(t, cached)
+	assert.Equal(t, "Full text", cached.Text)
+
+	deleted, err := s.DeleteEnrichCache(ctx, base)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+
+	cached, err = s.GetEnrichCache(ctx, key, base)
+	require.NoError(t, err)
+	assert.NotNil(t, cached)
+}
+
+func testRetention(t *testing.T, s backend.Storage) {
+	ctx := context.Background()
+	base := time.Now().UTC().Add(-time.Hour)
+
+	for i := 0; i < 4; i++ {
+		item := newItem(fmt.Sprintf("active-%d", i), base.Add(time.Duration(i)*time.Minute))
+		require.NoError(t, s.InsertItem(ctx, item))
+		require.NoError(t, s.SetItemIsSent(ctx, item.ID, storage.DeliveryStatusSent))
+	}
+
+	// старые записи без feed_url: последние новости хранятся для каждого их фида
+	for _, title := range []string{"Legacy A", "Legacy B"} {
+		for i := 0; i < 2; i++ {
+			item := newItem(fmt.Sprintf("%s-%d", title, i), base)
+			item.FeedURL = ""
+			item.FeedTitle = title
+			require.NoError(t, s.InsertItem(ctx, item))
+			require.NoError(t, s.SetItemIsSent(ctx, item.ID, storage.DeliveryStatusSent))
+		}
+	}
+
+	pending := newItem("pending", base)
+	require.NoError(t, s.InsertItem(ctx, pending))
+
+	removed := newItem("removed", base)
+	removed.FeedURL = "https://removed.example.com/rss"
+	require.NoError(t, s.InsertItem(ctx, removed))
+	require.NoError(t, s.UpsertFeed(ctx, removed.FeedURL, base, base))
+	require.NoError(t, s.UpsertFeed(ctx, "https://example.com/rss", base, base))
+
+	// без активных фидов удаляются только просроченные новости, старше последних двух
+	result, err := s.PruneItems(ctx, storage.PrunePolicy{
+		SentBefore:  time.Now().Add(time.Hour),
+		KeepPerFeed: 2,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), result.ExpiredItems)
+	assert.Equal(t, int64(0), result.RemovedFeedItems)
+
+	result, err = s.PruneItems(ctx, storage.PrunePolicy{
+		SentBefore:  time.Now().Add(time.Hour),
+		KeepPerFeed: 2,
+		ActiveFeeds: []string{"https://example.com/rss"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), result.ExpiredItems)
+	assert.Equal(t, int64(1), result.RemovedFeedItems)
+	assert.Equal(t, int64(1), result.RemovedFeeds)
+
+	items, err := s.GetItemsReadyToSend(ctx, 0)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, "pending", items[0].ID)
+
+	stored, err := s.GetFeedByURL(ctx, removed.FeedURL)
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+
+	require.NoError(t, s.Vacuum(ctx))
+	require.NoError(t, s.Vacuum(ctx))
+
+	size, err := s.Size(ctx)
+	require.NoError(t, err)
+	assert.Greater(t, size, int64(0))
+}
+
+func testTimestamps(t *testing.T, s backend.Storage) {
+	ctx := context.Background()
+	msk := time.FixedZone("MSK", 3*60*60)
+
+	// 10:00+03:00 должно сохраниться как 07:00Z с долями секунды
+	lastChecked := time.Date(2024, 5, 1, 10, 0, 0, 123456000, msk)
+	require.NoError(t, s.UpsertFeed(ctx, "https://example.com/rss", lastChecked, lastChecked))
+
+	stored, err := s.GetFeedByURL(ctx, "https://example.com/rss")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 5, 1, 7, 0, 0, 123456000, time.UTC), stored.LastChecked)
+	assert.Equal(t, time.UTC, stored.LastPosted.Location())
+
+	zoned := newItem("zoned", time.Date(2024, 5, 1, 10, 0, 0, 500000000, msk))
+	utc := newItem("utc", time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC))
+	require.NoError(t, s.InsertItem(ctx, zoned))
+	require.NoError(t, s.InsertItem(ctx, utc))
+
+	updatedAt := time.Date(2024, 5, 1, 6, 0, 0, 0, time.UTC)
+	noDate := newItem("no-date", time.Time{})
+	noDate.PublishedAt = nil
+	noDate.UpdatedAt = &updatedAt
+	require.NoError(t, s.InsertItem(ctx, noDate))
+
+	items, err := s.GetItemsReadyToSend(ctx, 0)
+	require.NoError(t, err)
+	require.Len(t, items, 3)
+
+	// сортировка по реальному времени, а не по локальному
+	assert.Equal(t, "no-date", items[0].ID)
+	assert.Equal(t, updatedAt, *items[0].PublishedAt)
+	assert.Equal(t, "zoned", items[1].ID)
+	assert.Equal(t, time.Date(2024, 5, 1, 7, 0, 0, 500000000, time.UTC), *items[1].PublishedAt)
+	assert.Equal(t, "utc", items[2].ID)
+}
+
+func testSearch(t *testing.T, s backend.Storage) {
+	ctx := context.Background()
+	base := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	golang := newItem("golang", base)
+	golang.Title = "Go 1.23 released"
+	golang.Description = "The Go team announces a new release of the compiler & <tools>"
+	golang.Tags = []string{"golang", "release"}
+
+	kernel := newItem("kernel", base.Add(24*time.Hour))
+	kernel.FeedURL = "https://opennet.example.com/rss"
+	kernel.FeedTitle = "Opennet"
+	kernel.Title = "Выпуск ядра Linux"
+	kernel.Description = "Новый выпуск ядра с поддержкой компилятора Rust"
+	kernel.Tags = []string{"linux"}
+
+	mention := newItem("mention", base.Add(48*time.Hour))
+	mention.Title = "Weekly digest"
+	mention.Description = "Links about databases and a note on the Go release"
+
+	for _, item := range []*feed.FeedItem{golang, kernel, mention} {
+		require.NoError(t, s.InsertItem(ctx, item))
+	}
+	require.NoError(t, s.SetItemIsSent(ctx, "mention", storage.DeliveryStatusSent))
+
+	results, err := s.Search(ctx, storage.SearchQuery{Query: "release"})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	// совпадение в тегах важнее совпадения в описании
+	assert.Equal(t, "golang", results[0].ItemID)
+	assert.Equal(t, "mention", results[1].ItemID)
+	assert.Greater(t, results[0].Score, results[1].Score)
+
+	results, err = s.Search(ctx, storage.SearchQuery{Query: "released"})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Contains(t, results[0].TitleSnippet, "<b>released</b>")
+
+	// по умолчанию сниппеты - экранированный HTML
+	results, err = s.Search(ctx, storage.SearchQuery{Query: "tools"})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Contains(t, results[0].DescriptionSnippet, "&amp; &lt;<b>tools</b>&gt;")
+	assert.Equal(t, storage.MaxSearchLimit, storage.SearchQuery{Limit: 100000000}.WithDefaults().Limit)
+
+	results, err = s.Search(ctx, storage.SearchQuery{Query: "ядра", HighlightStart: "[", HighlightEnd: "]"})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "kernel", results[0].ItemID)
+	assert.Equal(t, "Opennet", results[0].FeedTitle)
+	assert.Contains(t, results[0].DescriptionSnippet, "[ядра]")
+
+	results, err = s.Search(ctx, storage.SearchQuery{Query: "компил*"})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "kernel", results[0].ItemID)
+
+	notSent := false
+	results, err = s.Search(ctx, storage.SearchQuery{Query: "release", IsSent: &notSent})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "golang", results[0].ItemID)
+
+	results, err = s.Search(ctx, storage.SearchQuery{Query: "release", From: base.Add(time.Hour)})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "mention", results[0].ItemID)
+
+	results, err = s.Search(ctx, storage.SearchQuery{Query: "выпуск", Feed: "https://example.com/rss"})
+	require.NoError(t, err)
+	assert.Len(t, results, 0)
+
+	// удалённые новости пропадают из индекса
+	_, err = s.PruneItems(ctx, storage.PrunePolicy{
+		SentBefore:  time.Now().Add(time.Hour),
+		ActiveFeeds: []string{"https://example.com/rss"},
+	})
+	require.NoError(t, err)
+	results, err = s.Search(ctx, storage.SearchQuery{Query: "выпуск"})
+	require.NoError(t, err)
+	assert.Len(t, results, 0)
+
+	_, err = s.Search(ctx, storage.SearchQuery{Query: "  "})
+	assert.Error(t, err)
+}
+
+func testItemsAdmin(t *testing.T, s backend.Storage) {
+	ctx := context.Background()
+	base := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	require.NoError(t, s.UpsertFeed(ctx, "https://example.com/rss", base, base))
+	require.NoError(t, s.UpsertFeed(ctx, "https://empty.example.com/rss", base, base))
+
+	for i, id := range []string{"pending", "failed", "sent"} {
+		require.NoError(t, s.InsertItem(ctx, newItem(id, base.Add(time.Duration(i)*time.Minute))))
+	}
+	other := newItem("other", base)
+	other.FeedURL = "https://other.example.com/rss"
+	other.FeedTitle = "Other Feed"
+	require.NoError(t, s.InsertItem(ctx, other))
+
+	require.NoError(t, s.IncrementItemFailedCounter(ctx, "failed"))
+	require.NoError(t, s.SetItemIsSent(ctx, "sent", storage.DeliveryStatusSent))
+
+	dryRun := newItem("dry-run", base.Add(-time.Minute))
+	dryRun.FeedURL = "https://other.example.com/rss"
+	dryRun.FeedTitle = "Other Feed"
+	require.NoError(t, s.InsertItem(ctx, dryRun))
+	require.NoError(t, s.SetItemIsSent(ctx, "dry-run", storage.DeliveryStatusDryRun))
+
+	items, err := s.ListItems(ctx, storage.ItemFilter{Feed: "Other Feed", Status: storage.ItemStatusDryRun})
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, "dry-run", items[0].ID)
+	assert.Equal(t, storage.ItemStatusDryRun, items[0].Status)
+
+	items, err = s.ListItems(ctx, storage.ItemFilter{Feed: "Test Feed"})
+	require.NoError(t, err)
+	require.Len(t, items, 3)
+	// новые сверху
+	assert.Equal(t, "sent", items[0].ID)
+	assert.Equal(t, storage.ItemStatusSent, items[0].Status)
+	require.NotNil(t, items[0].SentAt)
+	assert.Equal(t, storage.ItemStatusFailed, items[1].Status)
+	assert.Equal(t, 1, items[1].FailedCount)
+	assert.Equal(t, storage.ItemStatusPending, items[2].Status)
+	assert.Nil(t, items[2].SentAt)
+	assert.True(t, base.Equal(items[2].PublishedAt))
+
+	items, err = s.ListItems(ctx, storage.ItemFilter{Status: storage.ItemStatusPending})
+	require.NoError(t, err)
+	assert.Len(t, items, 2)
+
+	items, err = s.ListItems(ctx, storage.ItemFilter{Limit: 1})
+	require.NoError(t, err)
+	assert.Len(t, items, 1)
+
+	_, err = s.ListItems(ctx, storage.ItemFilter{Status: "unknown"})
+	assert.Error(t, err)
+
+	stats, err := s.ListFeedStats(ctx)
+	require.NoError(t, err)
+	require.Len(t, stats, 3)
+	assert.Equal(t, storage.FeedStats{URL: "https://empty.example.com/rss", LastChecked: base, LastPosted: base}, stats[0])
+	assert.Equal(t, "https://example.com/rss", stats[1].URL)
+	assert.Equal(t, [3]int{1, 1, 1}, [3]int{stats[1].Pending, stats[1].Failed, stats[1].Sent})
+	// фида нет в feeds - время нулевое
+	assert.Equal(t, "https://other.example.com/rss", stats[2].URL)
+	assert.True(t, stats[2].LastChecked.IsZero())
+	assert.Equal(t, 1, stats[2].Pending)
+	assert.Equal(t, 0, stats[2].Sent)
+	assert.Equal(t, 1, stats[2].DryRun)
+
+	requeued, err := s.RequeueItems(ctx, storage.ItemFilter{Status: storage.ItemStatusFailed})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), requeued)
+
+	requeued, err = s.RequeueItems(ctx, storage.ItemFilter{IDs: []string{"sent", "missing"}})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), requeued)
+
+	requeued, err = s.RequeueItems(ctx, storage.ItemFilter{Status: storage.ItemStatusDryRun})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), requeued)
+
+	items, err = s.ListItems(ctx, storage.ItemFilter{Status: storage.ItemStatusPending})
+	require.NoError(t, err)
+	assert.Len(t, items, 5)
+
+	count, err := s.GetCountItemsSendFailed(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
+
+func testMigrations(t *testing.T, s backend.Storage) {
+	ctx := context.Background()
+
+	version, err := s.MigrationVersion(ctx)
+	require.NoError(t, err)
+	assert.NotZero(t, version.Version)
+	assert.False(t, version.Dirty)
+
+	require.NoError(t, s.MigrateDown(ctx, 1))
+	down, err := s.MigrationVersion(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, version.Version-1, down.Version)
+
+	require.NoError(t, s.MigrateDown(ctx, 0))
+	down, err = s.MigrationVersion(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, down.Version)
+
+	require.NoError(t, s.Migrate(ctx))
+	up, err := s.MigrationVersion(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, version, up)
+
+	require.NoError(t, s.InsertItem(ctx, newItem("after-remigrate", time.Now())))
+}
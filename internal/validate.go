@@ -0,0 +1,383 @@
+package internal
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"rssgram/internal/feed"
+	"rssgram/internal/outputs/telegram"
+	"rssgram/internal/storage"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ValidationError - одна проблема конфига. Path записан в виде feeds[1].url,
+// Line - строка в YAML (0, если конфиг собран не из файла).
+type ValidationError struct {
+	Path    string
+	Line    int
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("line %d: %s: %s", e.Line, e.Path, e.Message)
+	}
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// ValidationErrors - все найденные проблемы сразу, а не только первая.
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+type validator struct {
+	root *yaml.Node
+	errs ValidationErrors
+}
+
+// addf записывает ошибку. Если ключа в файле нет, берётся строка ближайшего родителя.
+func (v *validator) addf(path []any, format string, args ...any) {
+	v.errs = append(v.errs, ValidationError{
+		Path:    formatPath(path),
+		Line:    lineOf(v.root, path),
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func formatPath(path []any) string {
+	var b strings.Builder
+	for _, p := range path {
+		switch p := p.(type) {
+		case int:
+			b.WriteString("[" + strconv.Itoa(p) + "]")
+		case string:
+			if b.Len() > 0 {
+				b.WriteString(".")
+			}
+			b.WriteString(p)
+		}
+	}
+	return b.String()
+}
+
+func lineOf(node *yaml.Node, path []any) int {
+	if node == nil {
+		return 0
+	}
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+
+	line := node.Line
+	for _, p := range path {
+		var next *yaml.Node
+
+		switch p := p.(type) {
+		case string:
+			if node.Kind == yaml.MappingNode {
+				for i := 0; i+1 < len(node.Content); i += 2 {
+					if node.Content[i].Value == p {
+						// для скалярного значения строка ключа и значения совпадает,
+						// для вложенных блоков удобнее показать ключ
+						line = node.Content[i].Line
+						next = node.Content[i+1]
+						break
+					}
+				}
+			}
+		case int:
+			if node.Kind == yaml.SequenceNode && p < len(node.Content) {
+				next = node.Content[p]
+				line = next.Line
+			}
+		}
+
+		if next == nil {
+			break
+		}
+		node = next
+	}
+
+	return line
+}
+
+// Validate проверяет конфиг и возвращает ValidationErrors со всеми найденными проблемами.
+func (c *Config) Validate() error {
+	v := &validator{root: c.source}
+	v.errs = append(v.errs, c.loadErrors...)
+
+	c.validateTelegram(v)
+	c.validateOutputs(v)
+	c.validateFeeds(v)
+	validateFilters(v, []any{"filters"}, c.Filters)
+	validateRules(v, []any{"rules"}, c.Rules, c.OutputNames())
+
+	if c.Dedup.Window < 0 {
+		v.addf([]any{"dedup", "window"}, "must not be negative, got %s", c.Dedup.Window)
+	}
+	validateQuota(v, []any{"quota"}, c.Quota)
+
+	if c.Dedup.Similarity < 0 || c.Dedup.Similarity > 1 {
+		v.addf([]any{"dedup", "similarity"}, "must be between 0 and 1, got %g", c.Dedup.Similarity)
+	}
+
+	if c.Enrich.CacheTTL < 0 {
+		v.addf([]any{"enrich", "cache_ttl"}, "must not be negative, got %s", c.Enrich.CacheTTL)
+	}
+	if c.Enrich.HostDelay < 0 {
+		v.addf([]any{"enrich", "host_delay"}, "must not be negative, got %s", c.Enrich.HostDelay)
+	}
+
+	if c.Metrics.Enabled && (c.Metrics.Port <= 0 || c.Metrics.Port > 65535) {
+		v.addf([]any{"metrics", "port"}, "must be between 1 and 65535, got %d", c.Metrics.Port)
+	}
+
+	if _, err := c.Log.ParseLevel(); err != nil {
+		v.addf([]any{"log", "level"}, "unknown log level %q, expected debug, info, warn or error", c.Log.Level)
+	}
+	switch c.Log.Format {
+	case "", LogFormatJSON, LogFormatConsole:
+	default:
+		v.addf([]any{"log", "format"}, "unknown log format %q, expected %s or %s", c.Log.Format, LogFormatJSON, LogFormatConsole)
+	}
+
+	switch c.Database.Driver {
+	case "", storage.DriverSQLite:
+	case storage.DriverPostgres:
+		if c.Database.DSN == "" {
+			v.addf([]any{"database", "dsn"}, "is required for driver %q", storage.DriverPostgres)
+		}
+	default:
+		v.addf([]any{"database", "driver"}, "unknown driver %q, expected %q or %q", c.Database.Driver, storage.DriverSQLite, storage.DriverPostgres)
+	}
+
+	if len(v.errs) > 0 {
+		return v.errs
+	}
+	return nil
+}
+
+func (c *Config) validateTelegram(v *validator) {
+	tg := c.Telegram.TelegramChannelClientConfig
+
+	// в dry run сообщения не уходят в Telegram, и конфиг проверяется без секретов;
+	// при ошибке чтения bot_token_file проблема уже записана
+	if tg.BotToken == "" && tg.BotTokenFile == "" && !c.DryRun.Enabled {
+		v.addf([]any{"telegram", "bot_token"}, "is required (or bot_token_file)")
+	}
+
+	validateChannel(v, []any{"telegram"}, tg, c.DryRun.Enabled)
+	validateDigest(v, []any{"telegram", "digest"}, c.Telegram.Digest)
+	if c.Telegram.MinInterval < 0 {
+		v.addf([]any{"telegram", "min_interval"}, "must not be negative, got %s", c.Telegram.MinInterval)
+	}
+	validateRelease(v, []any{"telegram"}, tg.SilentMode, c.Telegram.MinInterval)
+}
+
+// validateOutputs проверяет именованные каналы; токен у них необязателен - берётся из telegram.
+func (c *Config) validateOutputs(v *validator) {
+	seen := make(map[string]int, len(c.Outputs))
+
+	for i, o := range c.Outputs {
+		path := []any{"outputs", i}
+
+		switch first, ok := seen[o.Name]; {
+		case o.Name == "":
+			v.addf(append(path, "name"), "is required")
+		case o.Name == feed.DefaultOutput:
+			v.addf(append(path, "name"), "%q is reserved for the telegram section", feed.DefaultOutput)
+		case ok:
+			v.addf(append(path, "name"), "duplicate of outputs[%d].name", first)
+		default:
+			seen[o.Name] = i
+		}
+
+		validateChannel(v, path, o.TelegramChannelClientConfig, c.DryRun.Enabled)
+		validateDigest(v, append(path, "digest"), o.Digest)
+		if o.MinInterval < 0 {
+			v.addf(append(path, "min_interval"), "must not be negative, got %s", o.MinInterval)
+		}
+		// silent_mode может прийти из секции telegram, min_interval - нет
+		validateRelease(v, path, o.WithDefaults(c.Telegram.TelegramChannelClientConfig).SilentMode, o.MinInterval)
+	}
+}
+
+// validateRelease: с release: gradual накопленное за тихие часы уходит с паузами min_interval,
+// без интервала все новости ушли бы разом, как обычная очередь.
+func validateRelease(v *validator, path []any, silent telegram.SilentModeConfig, minInterval time.Duration) {
+	if silent.Defer() && silent.Release != telegram.SilentReleaseDigest && minInterval == 0 {
+		v.addf(append(path[:len(path):len(path)], "min_interval"), "is required with silent_mode mode: defer and release: gradual, or use release: digest")
+	}
+}
+
+func validateQuota(v *validator, path []any, q QuotaConfig) {
+	at := func(keys ...any) []any {
+		return append(path[:len(path):len(path)], keys...)
+	}
+
+	if q.PerHour < 0 {
+		v.addf(at("per_hour"), "must not be negative, got %d", q.PerHour)
+	}
+	if q.PerDay < 0 {
+		v.addf(at("per_day"), "must not be negative, got %d", q.PerDay)
+	}
+	validateDigest(v, at("overflow_digest"), q.OverflowDigest)
+}
+
+func validateDigest(v *validator, path []any, d telegram.DigestConfig) {
+	at := func(keys ...any) []any {
+		return append(path[:len(path):len(path)], keys...)
+	}
+
+	if d.Every < 0 {
+		v.addf(at("every"), "must not be negative, got %s", d.Every)
+	}
+	if d.EveryItems < 0 {
+		v.addf(at("every_items"), "must not be negative, got %d", d.EveryItems)
+	}
+	for i, t := range d.At {
+		if _, err := time.Parse(telegram.DigestTimeLayout, t); err != nil {
+			v.addf(at("at", i), "invalid time %q, expected HH:MM", t)
+		}
+	}
+	if _, err := time.LoadLocation(d.Timezone); err != nil {
+		v.addf(at("timezone"), "unknown timezone %q", d.Timezone)
+	}
+}
+
+// validateChannel проверяет настройки канала; в dry run канал можно не указывать.
+func validateChannel(v *validator, path []any, tg telegram.TelegramChannelClientConfig, dryRun bool) {
+	at := func(keys ...any) []any {
+		return append(path[:len(path):len(path)], keys...)
+	}
+
+	if tg.ChannelName == "" && !dryRun {
+		v.addf(at("channel_name"), "is required")
+	}
+
+	silent := tg.SilentMode
+	if (silent.Start == "") != (silent.Finish == "") {
+		v.addf(at("silent_mode"), "start and finish must be set together")
+	}
+	if silent.Start != "" {
+		if _, err := time.Parse(time.TimeOnly, silent.Start); err != nil {
+			v.addf(at("silent_mode", "start"), "invalid time %q, expected HH:MM:SS", silent.Start)
+		}
+	}
+	if silent.Finish != "" {
+		if _, err := time.Parse(time.TimeOnly, silent.Finish); err != nil {
+			v.addf(at("silent_mode", "finish"), "invalid time %q, expected HH:MM:SS", silent.Finish)
+		}
+	}
+	if _, err := time.LoadLocation(silent.Timezone); err != nil {
+		v.addf(at("silent_mode", "timezone"), "unknown timezone %q", silent.Timezone)
+	}
+	for i, w := range silent.Windows {
+		if err := telegram.ValidateSilentWindow(w); err != nil {
+			v.addf(at("silent_mode", "windows", i), "%v", err)
+		}
+	}
+	switch silent.Mode {
+	case "", telegram.SilentModeMute, telegram.SilentModeDefer:
+	default:
+		v.addf(at("silent_mode", "mode"), "unknown mode %q, expected %s or %s", silent.Mode, telegram.SilentModeMute, telegram.SilentModeDefer)
+	}
+	switch silent.Release {
+	case "", telegram.SilentReleaseGradual, telegram.SilentReleaseDigest:
+	default:
+		v.addf(at("silent_mode", "release"), "unknown release %q, expected %s or %s", silent.Release, telegram.SilentReleaseGradual, telegram.SilentReleaseDigest)
+	}
+	// без времени режим ничего не делает - скорее всего, окна забыли
+	if (silent.Mode != "" || silent.Release != "") && silent.Start == "" && silent.Finish == "" && len(silent.Windows) == 0 {
+		v.addf(at("silent_mode"), "start and finish or windows are required with mode and release")
+	}
+
+	trace := tg.Trace
+	if trace.SampleRate < 0 || trace.SampleRate > 1 {
+		v.addf(at("trace", "sample_rate"), "must be between 0 and 1, got %v", trace.SampleRate)
+	}
+	if trace.Enabled && trace.SampleRate == 0 && len(trace.ItemIDs) == 0 {
+		v.addf(at("trace"), "sample_rate or item_ids is required to limit traced requests")
+	}
+}
+
+func (c *Config) validateFeeds(v *validator) {
+	seen := make(map[string]int, len(c.Feeds))
+
+	for i, f := range c.Feeds {
+		if f.URL == "" {
+			v.addf([]any{"feeds", i, "url"}, "is required")
+		} else if u, err := url.Parse(f.URL); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			v.addf([]any{"feeds", i, "url"}, "invalid url %q, expected absolute http(s) url", f.URL)
+		} else if first, ok := seen[f.URL]; ok {
+			v.addf([]any{"feeds", i, "url"}, "duplicate of feeds[%d].url", first)
+		} else {
+			seen[f.URL] = i
+		}
+
+		switch f.DescriptionType {
+		case "", feed.FeedDescriptionTypeItem, feed.FeedDescriptionTypeLink, feed.FeedDescriptionTypeFulltext, feed.FeedDescriptionTypeNone:
+		default:
+			v.addf([]any{"feeds", i, "description_type"}, "unknown description type %q, expected %s, %s, %s or %s",
+				f.DescriptionType, feed.FeedDescriptionTypeItem, feed.FeedDescriptionTypeLink, feed.FeedDescriptionTypeFulltext, feed.FeedDescriptionTypeNone)
+		}
+
+		if f.Interval != "" {
+			if _, err := time.ParseDuration(f.Interval); err != nil {
+				v.addf([]any{"feeds", i, "interval"}, "invalid duration %q", f.Interval)
+			}
+		}
+
+		validateFilters(v, []any{"feeds", i, "filters"}, f.Filters)
+		validateRules(v, []any{"feeds", i, "rules"}, f.Rules, c.OutputNames())
+		validateDigest(v, []any{"feeds", i, "digest"}, f.Digest)
+		validateQuota(v, []any{"feeds", i, "quota"}, f.Quota)
+	}
+}
+
+// validateRules компилирует выражения правил и проверяет, что их выходы настроены.
+func validateRules(v *validator, path []any, rules []feed.RoutingRule, outputs []string) {
+	for i, rule := range rules {
+		rulePath := append(path[:len(path):len(path)], i)
+
+		if err := feed.ValidateRoutingRule(rule); err != nil {
+			v.addf(rulePath, "%v", err)
+			continue
+		}
+
+		for j, o := range rule.Outputs {
+			if !slices.Contains(outputs, o) {
+				v.addf(append(rulePath, "outputs", j), "unknown output %q, expected one of %s", o, strings.Join(outputs, ", "))
+			}
+		}
+	}
+}
+
+func validateFilters(v *validator, path []any, filters feed.FiltersConfig) {
+	rules := []struct {
+		kind  string
+		rules []feed.FilterRule
+	}{
+		{"include", filters.Include},
+		{"exclude", filters.Exclude},
+	}
+
+	for _, r := range rules {
+		for i, rule := range r.rules {
+			if err := feed.ValidateRule(rule); err != nil {
+				v.addf(append(path[:len(path):len(path)], r.kind, i), "%v", err)
+			}
+		}
+	}
+}